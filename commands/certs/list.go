@@ -5,11 +5,12 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdList(ic ICerts, is services.IServices, downStream string) error {
+func CmdList(ic ICerts, is services.IServices, iout output.IOutput, downStream string) error {
 	service, err := is.RetrieveByLabel(downStream)
 	if err != nil {
 		return err
@@ -18,6 +19,12 @@ func CmdList(ic ICerts, is services.IServices, downStream string) error {
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		if certs == nil {
+			certs = &[]models.Cert{}
+		}
+		return iout.Render(certs)
+	}
 	if certs == nil || len(*certs) == 0 {
 		logrus.Println("No certs found")
 		return nil
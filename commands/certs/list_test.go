@@ -1,12 +1,16 @@
 package certs
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/test"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestCertsList(t *testing.T) {
@@ -27,10 +31,53 @@ func TestCertsList(t *testing.T) {
 	)
 
 	// test
-	err := CmdList(New(settings), services.New(settings), test.DownStream)
+	err := CmdList(New(settings), services.New(settings), output.New(settings), test.DownStream)
 
 	// assert
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
 	}
 }
+
+func TestCertsListStructured(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/certs",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, `[{"name":"cert0","letsEncrypt":0},{"name":"cert1","letsEncrypt":2}]`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s"}]`, test.SvcID, test.DownStream))
+		},
+	)
+
+	for _, format := range []string{output.FormatJSON, output.FormatYAML} {
+		t.Logf("Format: %s", format)
+		buf := &bytes.Buffer{}
+
+		// test
+		err := CmdList(New(settings), services.New(settings), &output.SOutput{Format: format, Writer: buf}, test.DownStream)
+
+		// assert
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		var certs []map[string]interface{}
+		if format == output.FormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &certs)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &certs)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s output: %s", format, err)
+		}
+		if len(certs) != 2 || certs[1]["name"] != "cert1" || fmt.Sprintf("%v", certs[1]["letsEncrypt"]) != "2" {
+			t.Errorf("Unexpected %s output: %s", format, buf.String())
+		}
+	}
+}
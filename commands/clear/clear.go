@@ -11,8 +11,7 @@ func CmdClear(privateKey, session, environments, pods bool, settings *models.Set
 		settings.PrivateKeyPath = ""
 	}
 	if session {
-		settings.SessionToken = ""
-		settings.UsersID = ""
+		config.ClearSession(settings)
 	}
 	if environments {
 		settings.Environments = map[string]models.AssociatedEnvV2{}
@@ -20,7 +19,9 @@ func CmdClear(privateKey, session, environments, pods bool, settings *models.Set
 	if pods {
 		settings.Pods = &[]models.Pod{}
 	}
-	config.SaveSettings(settings)
+	if err := config.SaveSettings(settings); err != nil {
+		return err
+	}
 	if !privateKey && !session && !environments && !pods {
 		logrus.Println("No settings were specified. To see available options, run \"datica clear --help\"")
 	} else {
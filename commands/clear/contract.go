@@ -14,6 +14,7 @@ var Cmd = models.Command{
 	LongHelp: "<code>clear</code> allows you to manage your global settings file in case your CLI becomes misconfigured. " +
 		"The global settings file is stored in your home directory at <code>~/.datica</code>. " +
 		"You can clear out all settings or pick and choose which ones need to be removed. " +
+		"Clearing the session also wipes it from the encrypted credential vault or credential helper if one is used. " +
 		"After running the <code>clear</code> command, any other CLI command will reset the removed settings to their appropriate values. Here are some sample commands\n\n" +
 		"<pre>\ndatica clear --all\n" +
 		"datica clear --environments # removes your locally cached environment(s) configuration data\n" +
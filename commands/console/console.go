@@ -1,7 +1,7 @@
 package console
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +15,8 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/models"
 	"github.com/docker/docker/pkg/term"
 )
@@ -64,11 +66,10 @@ func (c *SConsole) Open(command string, service *models.Service) error {
 	if err != nil {
 		return err
 	}
-	// all because logrus treats print, println, and printf the same
-	logrus.StandardLogger().Out.Write([]byte(fmt.Sprintf("Waiting for the console (job ID = %s) to be ready. This might take a minute.", job.ID)))
+	logrus.Printf("Waiting for the console (job ID = %s) to be ready. This might take a minute.", job.ID)
 
 	validStatuses := []string{"running", "finished", "failed"}
-	status, err := c.Jobs.PollForStatus(validStatuses, job.ID, service.ID)
+	status, err := c.Jobs.Watch(context.Background(), job.ID, service.ID, jobs.WatchOptions{Statuses: validStatuses})
 	if err != nil {
 		return err
 	}
@@ -80,25 +81,12 @@ func (c *SConsole) Open(command string, service *models.Service) error {
 		}
 	}
 	if !found {
-		return fmt.Errorf("\nCould not open a console connection. Entered state '%s'", status)
+		return fmt.Errorf("Could not open a console connection. Entered state '%s'", status)
 	}
 	job.Status = status
 	defer c.Destroy(job.ID, service)
-	creds, err := c.RetrieveTokens(job.ID, service)
-	if err != nil {
-		return err
-	}
-
-	creds.URL = strings.Replace(creds.URL, "http", "ws", 1)
-	logrus.Println("\nConnecting...")
-
-	// BEGIN websocket impl
-	config, _ := websocket.NewConfig(creds.URL, "ws://localhost:9443/")
-	config.TlsConfig = &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-	config.Header["X-Console-Token"] = []string{creds.Token}
-	ws, err := websocket.DialConfig(config)
+	logrus.Println("Connecting...")
+	ws, err := c.connect(job.ID, service)
 	if err != nil {
 		return err
 	}
@@ -121,6 +109,30 @@ func (c *SConsole) Open(command string, service *models.Service) error {
 	return nil
 }
 
+// connect opens the websocket of a running console job.
+func (c *SConsole) connect(jobID string, service *models.Service) (*websocket.Conn, error) {
+	creds, err := c.RetrieveTokens(jobID, service)
+	if err != nil {
+		return nil, err
+	}
+	creds.URL = strings.Replace(creds.URL, "http", "ws", 1)
+	config, err := websocket.NewConfig(creds.URL, "ws://localhost:9443/")
+	if err != nil {
+		return nil, err
+	}
+	config.Header["X-Console-Token"] = []string{creds.Token}
+	conn, err := network.Dial(c.Settings.Network, config.Location)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
 func (c *SConsole) Request(command string, service *models.Service) (*models.Job, error) {
 	console := map[string]string{}
 	if command != "" {
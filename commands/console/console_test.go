@@ -1,12 +1,19 @@
 package console
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
 
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/models"
 	"github.com/daticahealth/cli/test"
 )
 
@@ -43,3 +50,85 @@ func TestConsole(t *testing.T) {
 		}
 	}
 }
+
+// serveExec answers the console API for job1, which is in the given status
+// until the console is connected to and then ends in the end status. It
+// records whether the job was destroyed.
+func serveExec(t *testing.T, mux *http.ServeMux, wsURL, status, end string, destroyed *bool) {
+	connected := false
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/console",
+		func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			test.AssertEquals(t, "map[command:cat]", fmt.Sprint(body))
+			fmt.Fprint(w, `{"id":"job1","status":"scheduled"}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs/job1",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "DELETE" {
+				*destroyed = true
+			}
+			if connected {
+				status = end
+			}
+			fmt.Fprintf(w, `{"id":"job1","status":"%s"}`, status)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs/job1/console-token",
+		func(w http.ResponseWriter, r *http.Request) {
+			connected = true
+			if wsURL == "" {
+				w.WriteHeader(404)
+				fmt.Fprint(w, `{"title":"Not Found","description":"The console has ended","code":404}`)
+				return
+			}
+			fmt.Fprintf(w, `{"url":"%s/","token":"token1"}`, wsURL)
+		},
+	)
+}
+
+func TestExec(t *testing.T) {
+	// the console prints a line and exits
+	wsServer := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		test.AssertEquals(t, "token1", ws.Request().Header.Get("X-Console-Token"))
+		websocket.Message.Send(ws, "hello")
+	}))
+	defer wsServer.Close()
+
+	for _, end := range []string{"finished", "failed"} {
+		mux, server, baseURL := test.Setup()
+		settings := test.GetSettings(baseURL.String())
+		settings.JobPollInterval = 10 * time.Millisecond
+		destroyed := false
+		serveExec(t, mux, wsServer.URL, "running", end, &destroyed)
+
+		var stdout bytes.Buffer
+		err := New(settings, jobs.New(settings)).Exec("cat", &models.Service{ID: test.SvcID}, &stdout)
+		test.Teardown(server)
+		if (err != nil) != (end == "failed") {
+			t.Errorf("Unexpected error for a %s job: %v", end, err)
+		}
+		test.AssertEquals(t, "hello", stdout.String())
+		if !destroyed {
+			t.Errorf("Expected the console job to be destroyed")
+		}
+	}
+}
+
+func TestExecEnded(t *testing.T) {
+	// the command ended before the first poll and the console is gone
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	destroyed := false
+	serveExec(t, mux, "", "failed", "failed", &destroyed)
+
+	err := New(settings, jobs.New(settings)).Exec("cat", &models.Service{ID: test.SvcID}, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected an error for the failed console job")
+	}
+	if !destroyed {
+		t.Errorf("Expected the console job to be destroyed")
+	}
+}
@@ -1,6 +1,8 @@
 package console
 
 import (
+	"io"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/config"
@@ -20,13 +22,19 @@ var Cmd = models.Command{
 		"For example, if you open up a console to a postgres database, you will be given access to a psql prompt. " +
 		"You can also open up a mysql prompt, mongo cli prompt, rails console, django shell, and much more. " +
 		"When accessing a database service, the <code>COMMAND</code> argument is not needed because the appropriate prompt will be given to you. " +
-		"If you are connecting to an application service the <code>COMMAND</code> argument is required. Here are some sample commands\n\n" +
+		"If you are connecting to an application service the <code>COMMAND</code> argument is required. " +
+		"To run a command from a script or CI, where there is no terminal, pass it with <code>--exec</code> instead. " +
+		"Its output is written to stdout and the CLI exits with an error when the console job fails. " +
+		"The exit status of the command itself is not available and local input is not sent to it, so the command must not wait for input. " +
+		"The console is stopped when the command exits or Ctrl-C is pressed. Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" console db01\n" +
-		"datica -E \"<your_env_name>\" console app01 \"bundle exec rails console\"\n</pre>",
+		"datica -E \"<your_env_name>\" console app01 \"bundle exec rails console\"\n" +
+		"datica -E \"<your_env_name>\" console app01 --exec \"bundle exec rake db:migrate\"\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
 			serviceName := cmd.StringArg("SERVICE_NAME", "", "The name of the service to open up a console for")
 			command := cmd.StringArg("COMMAND", "", "An optional command to run when the console becomes available")
+			execCommand := cmd.StringOpt("e exec", "", "Run this command without a terminal and exit with an error if its console job fails")
 			cmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -34,12 +42,19 @@ var Cmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
+				if *execCommand != "" {
+					err := CmdExec(*serviceName, *execCommand, New(settings, jobs.New(settings)), services.New(settings))
+					if err != nil {
+						logrus.Fatal(err.Error())
+					}
+					return
+				}
 				err := CmdConsole(*serviceName, *command, New(settings, jobs.New(settings)), services.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			cmd.Spec = "SERVICE_NAME [COMMAND]"
+			cmd.Spec = "SERVICE_NAME [COMMAND | --exec]"
 		}
 	},
 }
@@ -47,6 +62,7 @@ var Cmd = models.Command{
 // IConsole
 type IConsole interface {
 	Open(command string, service *models.Service) error
+	Exec(command string, service *models.Service, stdout io.Writer) error
 	Request(command string, service *models.Service) (*models.Job, error)
 	RetrieveTokens(jobID string, service *models.Service) (*models.ConsoleCredentials, error)
 	Destroy(jobID string, service *models.Service) error
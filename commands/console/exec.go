@@ -0,0 +1,101 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/models"
+)
+
+// CmdExec runs a command on a service without a terminal and writes its
+// output to stdout.
+func CmdExec(svcName, command string, ic IConsole, is services.IServices) error {
+	service, err := is.RetrieveByLabel(svcName)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.\n", svcName)
+	}
+	// the console has no message for the end of the input, so a command
+	// reading piped input would never exit
+	if fi, err := os.Stdin.Stat(); err == nil && (fi.Mode()&os.ModeNamedPipe != 0 || fi.Mode().IsRegular()) {
+		logrus.Warnln("Input piped to the CLI is not sent to the command")
+	}
+	return ic.Exec(command, service, os.Stdout)
+}
+
+// Exec runs a command in a console job without a terminal and returns an
+// error when the job fails. The console only has a single output stream and
+// does not report the exit status of the command, so the output is written to
+// stdout as it is received and only the failure of the job is known. The
+// console job is always destroyed, also when Ctrl-C is pressed.
+func (c *SConsole) Exec(command string, service *models.Service, stdout io.Writer) error {
+	if command == "" {
+		return fmt.Errorf("A command is required to run a console without a terminal")
+	}
+	// registered before the job exists so Ctrl-C cannot skip Destroy
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	job, err := c.Request(command, service)
+	if err != nil {
+		return err
+	}
+	defer c.Destroy(job.ID, service)
+	logrus.Debugf("Waiting for console job %s to be ready", job.ID)
+
+	// Ctrl-C while waiting for the console stops it without asking
+	opts := jobs.WatchOptions{
+		OnEvent:     func(e jobs.Event) { logrus.Debugf("Console job %s is %s", e.JobID, e.Status) },
+		ConfirmStop: func(string) bool { return true },
+	}
+	opts.Statuses = []string{"running", "finished", "failed"}
+	status, err := c.Jobs.Watch(context.Background(), job.ID, service.ID, opts)
+	if err != nil {
+		return err
+	}
+
+	// a short command may have ended before the first poll, its output is
+	// read if the console still has it
+	ws, err := c.connect(job.ID, service)
+	if err != nil {
+		if status == "running" {
+			return err
+		}
+		logrus.Warnf("Console job %s %s before its output could be read: %s", job.ID, status, err)
+	} else {
+		defer ws.Close()
+		closed := make(chan error, 1)
+		go func() {
+			_, err := io.Copy(stdout, ws)
+			closed <- err
+		}()
+		select {
+		case err = <-closed:
+			if err != nil {
+				return fmt.Errorf("Error reading data from server: %s", err)
+			}
+		case <-interrupts:
+			return fmt.Errorf("Interrupted, stopping console job %s", job.ID)
+		}
+	}
+
+	if status == "running" {
+		opts.Statuses = []string{"finished", "failed"}
+		if status, err = c.Jobs.Watch(context.Background(), job.ID, service.ID, opts); err != nil {
+			return err
+		}
+	}
+	if status == "failed" {
+		return fmt.Errorf("Console job %s failed", job.ID)
+	}
+	return nil
+}
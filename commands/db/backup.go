@@ -24,10 +24,9 @@ func CmdBackup(databaseName string, skipPoll bool, id IDb, is services.IServices
 	logrus.Printf("Backup started (job ID = %s)", job.ID)
 	isSnapshotBackup := job.IsSnapshotBackup != nil && *job.IsSnapshotBackup
 	if !skipPoll {
-		// all because logrus treats print, println, and printf the same
-		logrus.StandardLogger().Out.Write([]byte("Polling until backup finishes."))
+		logrus.Println("Polling until backup finishes.")
 		if isSnapshotBackup {
-			logrus.StandardLogger().Out.Write([]byte(fmt.Sprintf("\nThis is a snapshot backup, it may be a while before this backup shows up in the \"datica db list %s\" command.", databaseName)))
+			logrus.Printf("This is a snapshot backup, it may be a while before this backup shows up in the \"datica db list %s\" command.", databaseName)
 			err = ij.WaitToAppear(job.ID, service.ID)
 			if err != nil {
 				return err
@@ -38,7 +37,7 @@ func CmdBackup(databaseName string, skipPoll bool, id IDb, is services.IServices
 			return err
 		}
 		job.Status = status
-		logrus.Printf("\nEnded in status '%s'", job.Status)
+		logrus.Printf("Ended in status '%s'", job.Status)
 		err = id.DumpLogs("backup", job, service)
 		if err != nil {
 			return err
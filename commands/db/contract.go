@@ -12,6 +12,7 @@ import (
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/crypto"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/lib/transfer"
 	"github.com/daticahealth/cli/models"
@@ -75,13 +76,18 @@ var DownloadSubCmd = models.Command{
 		"The ID of the backup is found by first running the db list command. Here is a sample command\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" db download db01 cd2b4bce-2727-42d1-89e0-027bf3f1a203 ./db.sql\n</pre>\n\n" +
 		"This assumes you are downloading a MySQL or PostgreSQL backup which takes the <code>.sql</code> file format. If you are downloading a mongo backup, the command might look like this\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" db download db01 cd2b4bce-2727-42d1-89e0-027bf3f1a203 ./db.tar.gz\n</pre>",
+		"<pre>\ndatica -E \"<your_env_name>\" db download db01 cd2b4bce-2727-42d1-89e0-027bf3f1a203 ./db.tar.gz\n</pre>\n\n" +
+		"The backup is downloaded in several segments at the same time, use <code>--parallel</code> to change how many. " +
+		"If a download is interrupted, running the same command again resumes the download of the remaining segments. " +
+		"Once downloaded, the backup is verified while it is decrypted and the SHA-256 checksum of the decrypted file is printed. " +
+		"A backup that fails verification is removed rather than left incomplete.",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			databaseName := subCmd.StringArg("DATABASE_NAME", "", "The name of the database service which was backed up (e.g. 'db01')")
 			backupID := subCmd.StringArg("BACKUP_ID", "", "The ID of the backup to download (found from \"datica backup list\")")
 			filePath := subCmd.StringArg("FILEPATH", "", "The location to save the downloaded backup to. This location must NOT already exist unless -f is specified")
 			force := subCmd.BoolOpt("f force", false, "If a file previously exists at \"filepath\", overwrite it and download the backup")
+			parallel := subCmd.IntOpt("p parallel", defaultDownloadParallel, "The number of segments of the backup to download at the same time")
 			subCmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -89,12 +95,12 @@ var DownloadSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdDownload(*databaseName, *backupID, *filePath, *force, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings))
+				err := CmdDownload(*databaseName, *backupID, *filePath, *force, *parallel, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "DATABASE_NAME BACKUP_ID FILEPATH [-f]"
+			subCmd.Spec = "DATABASE_NAME BACKUP_ID FILEPATH [-f] [-p]"
 		}
 	},
 }
@@ -108,12 +114,14 @@ var ExportSubCmd = models.Command{
 		"If an error occurs and the logs are not printed, you can use the db logs command to print out historical backup job logs. Here is a sample command\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" db export db01 ./dbexport.sql\n</pre>\n\n" +
 		"This assumes you are exporting a MySQL or PostgreSQL database which takes the <code>.sql</code> file format. If you are exporting a mongo database, the command might look like this\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" db export db01 ./dbexport.tar.gz\n</pre>",
+		"<pre>\ndatica -E \"<your_env_name>\" db export db01 ./dbexport.tar.gz\n</pre>\n\n" +
+		"If the download of the backup is interrupted, it can be resumed with the <code>db download</code> command using the same file path.",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			databaseName := subCmd.StringArg("DATABASE_NAME", "", "The name of the database to export data from (e.g. 'db01')")
 			filePath := subCmd.StringArg("FILEPATH", "", "The location to save the exported data. This location must NOT already exist unless -f is specified")
 			force := subCmd.BoolOpt("f force", false, "If a file previously exists at <code>filepath</code>, overwrite it and export data")
+			parallel := subCmd.IntOpt("p parallel", defaultDownloadParallel, "The number of segments of the backup to download at the same time")
 			subCmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -121,12 +129,12 @@ var ExportSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdExport(*databaseName, *filePath, *force, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings), jobs.New(settings))
+				err := CmdExport(*databaseName, *filePath, *force, *parallel, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings), jobs.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "DATABASE_NAME FILEPATH [-f]"
+			subCmd.Spec = "DATABASE_NAME FILEPATH [-f] [-p]"
 		}
 	},
 }
@@ -143,8 +151,14 @@ var ImportSubCmd = models.Command{
 		"and stored it at <code>./db.sql</code> you could import this into your database service. " +
 		"When importing data into mongo, you may specify the database and collection to import into using the <code>-d</code> and <code>-c</code> flags respectively. " +
 		"Regardless of a successful import or not, the logs for the import will be printed to the console when the import is finished. " +
-		"Before an import takes place, your database is backed up automatically in case any issues arise. Here is a sample command\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" db import db01 ./db.sql\n</pre>",
+		"Before an import takes place, your database is backed up automatically in case any issues arise. " +
+		"Files larger than 5 GB once encrypted, or any file when the <code>--multipart</code> flag is given, are encrypted and uploaded in parts that are retried independently. " +
+		"The progress of a multipart import is recorded in a <code>.datica-import</code> file next to the imported file. " +
+		"If the import is interrupted, run the same command with <code>--resume</code> to continue uploading where it left off with the same encryption key and IV. " +
+		"The progress file contains the encryption key and is removed once the upload finishes. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" db import db01 ./db.sql\n" +
+		"datica -E \"<your_env_name>\" db import db01 ./large-db.sql --multipart --part-size 250\n" +
+		"datica -E \"<your_env_name>\" db import db01 ./large-db.sql --resume\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			databaseName := subCmd.StringArg("DATABASE_NAME", "", "The name of the database to import data to (e.g. 'db01')")
@@ -152,6 +166,9 @@ var ImportSubCmd = models.Command{
 			mongoCollection := subCmd.StringOpt("c mongo-collection", "", "If importing into a mongo service, the name of the collection to import into")
 			mongoDatabase := subCmd.StringOpt("d mongo-database", "", "If importing into a mongo service, the name of the database to import into")
 			skipBackup := subCmd.BoolOpt("s skip-backup", false, "Skip backing up database. Useful for large databases, which can have long backup times.")
+			multipart := subCmd.BoolOpt("multipart", false, "Encrypt and upload the file in independently retried parts. This is always used for files larger than 5 GB.")
+			partSize := subCmd.IntOpt("part-size", 100, "The size in MB of each part of a multipart upload")
+			resume := subCmd.BoolOpt("resume", false, "Resume an interrupted multipart import of the given file using the same encryption key and IV")
 			subCmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -159,12 +176,12 @@ var ImportSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdImport(*databaseName, *filePath, *mongoCollection, *mongoDatabase, *skipBackup, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings), jobs.New(settings))
+				err := CmdImport(*databaseName, *filePath, *mongoCollection, *mongoDatabase, *skipBackup, *multipart, *resume, *partSize, New(settings, crypto.New(), jobs.New(settings)), prompts.New(), services.New(settings), jobs.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "DATABASE_NAME FILEPATH [-s][-d [-c]]"
+			subCmd.Spec = "DATABASE_NAME FILEPATH [-s][-d [-c]] [--multipart [--part-size] | --resume]"
 		}
 	},
 }
@@ -187,7 +204,7 @@ var ListSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdList(*databaseName, *page, *pageSize, New(settings, crypto.New(), jobs.New(settings)), services.New(settings))
+				err := CmdList(*databaseName, *page, *pageSize, New(settings, crypto.New(), jobs.New(settings)), services.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -227,9 +244,10 @@ var LogsSubCmd = models.Command{
 // IDb
 type IDb interface {
 	Backup(service *models.Service) (*models.Job, error)
-	Download(backupID, filePath string, service *models.Service) error
-	Export(filePath string, job *models.Job, service *models.Service) error
+	Download(backupID, filePath string, parallel int, service *models.Service) error
+	Export(filePath string, parallel int, job *models.Job, service *models.Service) error
 	Import(rt *transfer.ReaderTransfer, key, iv []byte, mongoCollection, mongoDatabase string, service *models.Service) (*models.Job, error)
+	ImportMultipart(state *ImportState, mongoCollection, mongoDatabase string, service *models.Service) (*models.Job, error)
 	List(page, pageSize int, service *models.Service) (*[]models.Job, error)
 	TempDownloadURL(jobID string, service *models.Service) (*models.TempURL, error)
 	TempLogsURL(jobID string, serviceID string) (*models.TempURL, error)
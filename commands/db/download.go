@@ -11,7 +11,7 @@ import (
 	"github.com/daticahealth/cli/models"
 )
 
-func CmdDownload(databaseName, backupID, filePath string, force bool, id IDb, ip prompts.IPrompts, is services.IServices) error {
+func CmdDownload(databaseName, backupID, filePath string, force bool, parallel int, id IDb, ip prompts.IPrompts, is services.IServices) error {
 	err := ip.PHI()
 	if err != nil {
 		return err
@@ -30,7 +30,7 @@ func CmdDownload(databaseName, backupID, filePath string, force bool, id IDb, ip
 	if service == nil {
 		return fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.", databaseName)
 	}
-	err = id.Download(backupID, filePath, service)
+	err = id.Download(backupID, filePath, parallel, service)
 	if err != nil {
 		return err
 	}
@@ -41,7 +41,7 @@ func CmdDownload(databaseName, backupID, filePath string, force bool, id IDb, ip
 
 // Download an existing backup to the local machine. The backup is encrypted
 // throughout the entire journey and then decrypted once it is stored locally.
-func (d *SDb) Download(backupID, filePath string, service *models.Service) error {
+func (d *SDb) Download(backupID, filePath string, parallel int, service *models.Service) error {
 	job, err := d.Jobs.Retrieve(backupID, service.ID, false)
 	if err != nil {
 		return err
@@ -49,7 +49,7 @@ func (d *SDb) Download(backupID, filePath string, service *models.Service) error
 	if job.Type != "backup" || (job.Status != "finished" && job.Status != "disappeared") {
 		return errors.New("Only 'finished' 'backup' jobs may be downloaded")
 	}
-	return d.Export(filePath, job, service)
+	return d.Export(filePath, parallel, job, service)
 }
 
 func (d *SDb) TempDownloadURL(jobID string, service *models.Service) (*models.TempURL, error) {
@@ -1,16 +1,22 @@
 package db
 
 import (
+	"bytes"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/lib/crypto"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/models"
 	"github.com/daticahealth/cli/test"
 )
 
@@ -64,7 +70,7 @@ func TestDbDownload(t *testing.T) {
 		t.Logf("Data: %+v", data)
 
 		// test
-		err := CmdDownload(data.databaseName, data.backupID, data.filePath, data.force, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings))
+		err := CmdDownload(data.databaseName, data.backupID, data.filePath, data.force, defaultDownloadParallel, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings))
 
 		// assert
 		if err != nil {
@@ -81,3 +87,74 @@ func TestDbDownload(t *testing.T) {
 	}
 	os.Remove(downloadFilePath)
 }
+
+func TestDbDownloadSegments(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+
+	// 20 chunks split into segments of 8, 8, and 4 chunks
+	plaintext := make([]byte, crypto.ChunkSize*20)
+	rand.Read(plaintext)
+	encryptReader, _ := crypto.New().NewEncryptReader(bytes.NewReader(plaintext), make([]byte, crypto.KeySize), make([]byte, crypto.IVSize))
+	encrypted, _ := ioutil.ReadAll(io.LimitReader(encryptReader, int64(encryptReader.CalculateTotalSize(len(plaintext)))))
+	segmentSize := downloadSegmentChunks * (crypto.ChunkSize + crypto.TagSize)
+
+	var lock sync.Mutex
+	ranges := []string{}
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+dbID+"/backup-url/"+dbJobID,
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`{"url":"%s/backup"}`, baseURL.String()))
+		},
+	)
+	mux.HandleFunc("/backup",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			lock.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			lock.Unlock()
+			http.ServeContent(w, r, "backup", time.Time{}, bytes.NewReader(encrypted))
+		},
+	)
+	job := &models.Job{ID: dbJobID, Backup: &models.EncryptionStore{Key: strings.Repeat("0", crypto.KeySize*2), IV: strings.Repeat("0", crypto.IVSize*2)}}
+	defer os.Remove(downloadFilePath)
+
+	// resume a download that already has the first segment
+	ioutil.WriteFile(downloadPartPath(downloadFilePath), encrypted[:segmentSize], 0600)
+	state := &DownloadState{BackupID: dbJobID, Size: int64(len(encrypted)), SegmentSize: int64(segmentSize), Segments: []int{0}, path: downloadStatePath(downloadFilePath)}
+	state.Save()
+
+	// test
+	err := New(settings, crypto.New(), jobs.New(settings)).Export(downloadFilePath, 2, job, &models.Service{ID: dbID})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b, _ := ioutil.ReadFile(downloadFilePath)
+	if !bytes.Equal(b, plaintext) {
+		t.Fatalf("The downloaded backup does not match the original")
+	}
+	for _, r := range ranges {
+		if r == fmt.Sprintf("bytes=0-%d", segmentSize-1) {
+			t.Fatalf("The previously downloaded segment was downloaded again")
+		}
+	}
+	if len(ranges) != 3 {
+		t.Fatalf("Expected 3 requests but got %d: %v", len(ranges), ranges)
+	}
+	if _, err = os.Stat(downloadStatePath(downloadFilePath)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the download progress to be removed")
+	}
+
+	// a corrupt backup fails verification
+	encrypted[len(encrypted)/2]++
+	err = New(settings, crypto.New(), jobs.New(settings)).Export(downloadFilePath, 2, job, &models.Service{ID: dbID})
+	if err == nil || !strings.Contains(err.Error(), "failed verification") {
+		t.Fatalf("Expected a verification error but got %v", err)
+	}
+	if _, err = os.Stat(downloadFilePath); !os.IsNotExist(err) {
+		t.Fatalf("Expected the corrupt backup to be removed")
+	}
+}
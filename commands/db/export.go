@@ -2,10 +2,7 @@ package db
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -17,7 +14,7 @@ import (
 	"github.com/daticahealth/cli/models"
 )
 
-func CmdExport(databaseName, filePath string, force bool, id IDb, ip prompts.IPrompts, is services.IServices, ij jobs.IJobs) error {
+func CmdExport(databaseName, filePath string, force bool, parallel int, id IDb, ip prompts.IPrompts, is services.IServices, ij jobs.IJobs) error {
 	err := ip.PHI()
 	if err != nil {
 		return err
@@ -41,8 +38,7 @@ func CmdExport(databaseName, filePath string, force bool, id IDb, ip prompts.IPr
 		return err
 	}
 	logrus.Printf("Export started (job ID = %s)", job.ID)
-	// all because logrus treats print, println, and printf the same
-	logrus.StandardLogger().Out.Write([]byte("Polling until backup finishes."))
+	logrus.Println("Polling until backup finishes.")
 	if job.IsSnapshotBackup != nil && *job.IsSnapshotBackup {
 		logrus.Printf("This is a snapshot backup, it may be a while before this backup shows up in the \"datica db list %s\" command.", databaseName)
 		err = ij.WaitToAppear(job.ID, service.ID)
@@ -61,9 +57,9 @@ func CmdExport(databaseName, filePath string, force bool, id IDb, ip prompts.IPr
 		return fmt.Errorf("Job finished with invalid status %s", job.Status)
 	}
 
-	err = id.Export(filePath, job, service)
+	err = id.Export(filePath, parallel, job, service)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s\nYou can resume the download of this backup with the \"datica db download %s %s %s\" command", err, databaseName, job.ID, filePath)
 	}
 	err = id.DumpLogs("backup", job, service)
 	if err != nil {
@@ -76,42 +72,25 @@ func CmdExport(databaseName, filePath string, force bool, id IDb, ip prompts.IPr
 // Export dumps all data from a database service and downloads the encrypted
 // data to the local machine. The export is accomplished by first creating a
 // backup. Once finished, the CLI asks where the file can be downloaded from.
-// The file is downloaded, decrypted, and saved locally.
-func (d *SDb) Export(filePath string, job *models.Job, service *models.Service) error {
+// The encrypted file is downloaded in parallel segments next to the given file
+// path, then verified, decrypted, and saved locally. An interrupted download
+// of the same backup to the same file path picks up where it left off.
+func (d *SDb) Export(filePath string, parallel int, job *models.Job, service *models.Service) error {
 	tempURL, err := d.TempDownloadURL(job.ID, service)
 	if err != nil {
 		return err
 	}
-	resp, err := http.Get(tempURL.URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	size, err := strconv.Atoi(resp.Header.Get("Content-Length"))
-	if err != nil {
-		return err
-	}
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	size, err := d.downloadSegments(tempURL.URL, filePath, job.ID, parallel)
 	if err != nil {
 		return err
 	}
-	dfw, err := d.Crypto.NewDecryptWriteCloser(file, job.Backup.Key, job.Backup.IV)
+	checksum, err := d.decryptDownload(downloadPartPath(filePath), filePath, size, job)
+	removeDownload(filePath)
 	if err != nil {
 		return err
 	}
-
-	wct := transfer.NewWriteCloserTransfer(dfw, size)
-	done := make(chan bool)
-	go printTransferStatus(true, wct, done)
-
-	_, err = io.Copy(wct, resp.Body)
-	if err != nil {
-		done <- false
-		dfw.Close()
-		return err
-	}
-	done <- true
-	return dfw.Close()
+	logrus.Printf("Verified the decrypted backup (SHA-256 %s)", checksum)
+	return nil
 }
 
 func printTransferStatus(isDownload bool, tr transfer.Transfer, done <-chan bool) {
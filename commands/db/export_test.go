@@ -80,7 +80,7 @@ func TestDbExport(t *testing.T) {
 		t.Logf("Data: %+v", data)
 
 		// test
-		err := CmdExport(data.databaseName, data.filePath, data.force, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
+		err := CmdExport(data.databaseName, data.filePath, data.force, defaultDownloadParallel, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
 
 		// assert
 		if err != nil {
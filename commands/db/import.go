@@ -14,12 +14,13 @@ import (
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/lib/crypto"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/lib/transfer"
 	"github.com/daticahealth/cli/models"
 )
 
-func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, skipBackup bool, id IDb, ip prompts.IPrompts, is services.IServices, ij jobs.IJobs) error {
+func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, skipBackup, multipart, resume bool, partSize int, id IDb, ip prompts.IPrompts, is services.IServices, ij jobs.IJobs) error {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("A file does not exist at path '%s'", filePath)
 	}
@@ -30,10 +31,6 @@ func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, sk
 	if service == nil {
 		return fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.", databaseName)
 	}
-	key := make([]byte, crypto.KeySize)
-	iv := make([]byte, crypto.IVSize)
-	rand.Read(key)
-	rand.Read(iv)
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
@@ -43,17 +40,61 @@ func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, sk
 	if err != nil {
 		return err
 	}
+	state, err := loadImportState(filePath)
+	if err != nil {
+		return err
+	}
+	if state != nil && !resume {
+		return fmt.Errorf("An interrupted import of %s was found. Run the same command with --resume to continue it or delete %s to start over", filePath, importStatePath(filePath))
+	} else if state == nil && resume {
+		return fmt.Errorf("No interrupted import of %s was found to resume", filePath)
+	}
+	key := make([]byte, crypto.KeySize)
+	iv := make([]byte, crypto.IVSize)
+	if state != nil {
+		if err = state.Validate(fi, service); err != nil {
+			return err
+		}
+		key = state.Key
+		iv = state.IV
+		multipart = true
+	} else {
+		rand.Read(key)
+		rand.Read(iv)
+	}
 	encryptFileReader, err := id.NewEncryptReader(file, key, iv)
 	if err != nil {
 		return err
 	}
 	uploadSize := encryptFileReader.CalculateTotalSize(int(fi.Size()))
 	fiveGB := transfer.GB * 5
-	if transfer.ByteSize(uploadSize) > fiveGB {
-		return fmt.Errorf("the encrypted size of %s exceeds the maximum upload size of %s", filePath, fiveGB)
+	if transfer.ByteSize(uploadSize) > fiveGB && !multipart {
+		logrus.Printf("The encrypted size of %s exceeds %s, it will be uploaded in multiple parts", filePath, fiveGB)
+		multipart = true
+	}
+	if multipart && state == nil {
+		if partSize < minImportPartSize {
+			return fmt.Errorf("--part-size must be at least %d MB", minImportPartSize)
+		}
+		state = &ImportState{
+			FilePath:  filePath,
+			FileSize:  fi.Size(),
+			ModTime:   fi.ModTime().Unix(),
+			ServiceID: service.ID,
+			Key:       key,
+			IV:        iv,
+			PartSize:  int64(partSize) * int64(transfer.MB),
+			Parts:     []models.UploadPart{},
+			path:      importStatePath(filePath),
+		}
+		if state.PartCount() > maxImportParts {
+			return fmt.Errorf("%s would be uploaded in %d parts which exceeds the maximum of %d. Please increase the --part-size", filePath, state.PartCount(), maxImportParts)
+		}
 	}
 	rt := transfer.NewReaderTransfer(encryptFileReader, uploadSize)
-	if !skipBackup {
+	if resume {
+		logrus.Printf("Resuming the import of '%s' (%d of %d parts uploaded)", filePath, len(state.Parts), state.PartCount())
+	} else if !skipBackup {
 		logrus.Printf("Backing up \"%s\" before performing the import", databaseName)
 		job, err := id.Backup(service)
 		if err != nil {
@@ -61,7 +102,6 @@ func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, sk
 		}
 		logrus.Printf("Backup started (job ID = %s)", job.ID)
 
-		// all because logrus treats print, println, and printf the same
 		logrus.Println("Polling until backup finishes.")
 		if job.IsSnapshotBackup != nil && *job.IsSnapshotBackup {
 			logrus.Printf("This is a snapshot backup, it may be a while before this backup shows up in the \"datica db list %s\" command.", databaseName)
@@ -90,19 +130,34 @@ func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, sk
 		}
 	}
 	logrus.Printf("Importing '%s' into %s (ID = %s)", filePath, databaseName, service.ID)
-	job, err := id.Import(rt, key, iv, mongoCollection, mongoDatabase, service)
-	if err != nil {
-		return err
+	var job *models.Job
+	if multipart {
+		// the backup has been taken at this point, so a resumed import only
+		// needs to finish uploading
+		if err = state.Save(); err != nil {
+			return err
+		}
+		job, err = id.ImportMultipart(state, mongoCollection, mongoDatabase, service)
+		if err != nil {
+			return err
+		}
+		if err = state.Remove(); err != nil {
+			logrus.Warnf("Failed to remove the import progress file %s: %s", importStatePath(filePath), err)
+		}
+	} else {
+		job, err = id.Import(rt, key, iv, mongoCollection, mongoDatabase, service)
+		if err != nil {
+			return err
+		}
 	}
-	// all because logrus treats print, println, and printf the same
-	logrus.StandardLogger().Out.Write([]byte(fmt.Sprintf("Processing import (job ID = %s).", job.ID)))
+	logrus.Printf("Processing import (job ID = %s)", job.ID)
 
 	status, err := ij.PollTillFinished(job.ID, service.ID)
 	if err != nil {
 		return err
 	}
 	job.Status = status
-	logrus.Printf("\nImport complete (end status = '%s')", job.Status)
+	logrus.Printf("Import complete (end status = '%s')", job.Status)
 	err = id.DumpLogs("restore", job, service)
 	if err != nil {
 		return err
@@ -123,13 +178,6 @@ func CmdImport(databaseName, filePath, mongoCollection, mongoDatabase string, sk
 // should be a single tar'ed, gzipped archive (`.tar.gz`) of the database dump
 // that you want to import.
 func (d *SDb) Import(rt *transfer.ReaderTransfer, key, iv []byte, mongoCollection, mongoDatabase string, service *models.Service) (*models.Job, error) {
-	options := map[string]string{}
-	if mongoCollection != "" {
-		options["databaseCollection"] = mongoCollection
-	}
-	if mongoDatabase != "" {
-		options["database"] = mongoDatabase
-	}
 	tmpURL, err := d.TempUploadURL(service)
 	if err != nil {
 		return nil, err
@@ -143,7 +191,11 @@ func (d *SDb) Import(rt *transfer.ReaderTransfer, key, iv []byte, mongoCollectio
 	req.ContentLength = int64(rt.Length())
 	done := make(chan bool)
 	go printTransferStatus(false, rt, done)
-	uploadResp, err := http.DefaultClient.Do(req)
+	client, err := network.Client(d.Settings.Network)
+	if err != nil {
+		return nil, err
+	}
+	uploadResp, err := client.Do(req)
 	if err != nil {
 		done <- false
 		return nil, err
@@ -156,11 +208,24 @@ func (d *SDb) Import(rt *transfer.ReaderTransfer, key, iv []byte, mongoCollectio
 		return nil, fmt.Errorf("Failed to upload import file - received status code %d", uploadResp.StatusCode)
 	}
 	done <- true
+	return d.startImport(strings.TrimLeft(u.Path, "/"), key, iv, mongoCollection, mongoDatabase, service)
+}
+
+// startImport kicks off the import job for an uploaded file. The given key and
+// IV are the ones the file was encrypted with.
+func (d *SDb) startImport(filename string, key, iv []byte, mongoCollection, mongoDatabase string, service *models.Service) (*models.Job, error) {
+	options := map[string]string{}
+	if mongoCollection != "" {
+		options["databaseCollection"] = mongoCollection
+	}
+	if mongoDatabase != "" {
+		options["database"] = mongoDatabase
+	}
 	importParams := map[string]interface{}{}
 	for key, value := range options {
 		importParams[key] = value
 	}
-	importParams["filename"] = strings.TrimLeft(u.Path, "/")
+	importParams["filename"] = filename
 	importParams["encryptionKey"] = string(d.Crypto.Hex(key, crypto.KeySize*2))
 	importParams["encryptionIV"] = string(d.Crypto.Hex(iv, crypto.IVSize*2))
 	importParams["dropDatabase"] = false
@@ -108,7 +108,7 @@ func TestDbImport(t *testing.T) {
 		backedUp = false
 
 		// test
-		err := CmdImport(data.databaseName, data.filePath, data.collection, data.database, data.skipBackup, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
+		err := CmdImport(data.databaseName, data.filePath, data.collection, data.database, data.skipBackup, false, false, 100, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
 
 		// assert
 		if err != nil {
@@ -160,7 +160,7 @@ func TestDbImportFailedUpload(t *testing.T) {
 	)
 
 	// test
-	err := CmdImport(dbName, importFilePath, "", "", true, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
+	err := CmdImport(dbName, importFilePath, "", "", true, false, false, 100, New(settings, crypto.New(), jobs.New(settings)), &test.FakePrompts{}, services.New(settings), jobs.New(settings))
 
 	// assert
 	if err == nil {
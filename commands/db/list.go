@@ -6,10 +6,11 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 )
 
-func CmdList(databaseName string, page, pageSize int, id IDb, is services.IServices) error {
+func CmdList(databaseName string, page, pageSize int, id IDb, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(databaseName)
 	if err != nil {
 		return err
@@ -22,6 +23,9 @@ func CmdList(databaseName string, page, pageSize int, id IDb, is services.IServi
 		return err
 	}
 	sort.Sort(SortedJobs(*jobs))
+	if iout.Structured() {
+		return iout.Render(jobs)
+	}
 	for _, job := range *jobs {
 		logrus.Printf("%s %s (status = %s)", job.ID, job.CreatedAt, job.Status)
 	}
@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/models"
 )
 
@@ -44,7 +44,11 @@ func (d *SDb) DumpLogs(taskType string, job *models.Job, service *models.Service
 	if err != nil {
 		return err
 	}
-	resp, err := http.Get(tempURL.URL)
+	client, err := network.Client(d.Settings.Network)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(tempURL.URL)
 	if err != nil {
 		return err
 	}
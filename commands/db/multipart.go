@@ -0,0 +1,289 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/lib/network"
+	"github.com/daticahealth/cli/lib/transfer"
+	"github.com/daticahealth/cli/models"
+	"github.com/pmylund/sortutil"
+)
+
+const (
+	// importStateSuffix is appended to the import file path to build the path
+	// of the file tracking a multipart import's progress
+	importStateSuffix = ".datica-import"
+	// importPartRetries is the number of times a single part is attempted
+	// before the import is aborted
+	importPartRetries = 5
+	// maxImportParts is the maximum number of parts in a multipart upload
+	maxImportParts = 10000
+	// minImportPartSize is the smallest allowed part size in MB
+	minImportPartSize = 5
+)
+
+// ImportState records the progress of a multipart import so that an
+// interrupted import can be resumed with the same key and IV. It is stored
+// next to the import file with 0600 permissions since it contains the
+// encryption key and is removed once the import has been started.
+type ImportState struct {
+	FilePath  string              `json:"filePath"`
+	FileSize  int64               `json:"fileSize"`
+	ModTime   int64               `json:"modTime"`
+	ServiceID string              `json:"serviceId"`
+	Key       []byte              `json:"key"`
+	IV        []byte              `json:"iv"`
+	PartSize  int64               `json:"partSize"`
+	UploadID  string              `json:"uploadId"`
+	Filename  string              `json:"filename"`
+	Parts     []models.UploadPart `json:"parts"`
+
+	path string
+}
+
+func importStatePath(filePath string) string {
+	return filePath + importStateSuffix
+}
+
+// loadImportState reads the state of an interrupted multipart import for the
+// given file. If no import was interrupted, nil is returned.
+func loadImportState(filePath string) (*ImportState, error) {
+	path := importStatePath(filePath)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state ImportState
+	if err = json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("The import progress file %s is corrupt. Please delete it and start the import again", path)
+	}
+	state.path = path
+	return &state, nil
+}
+
+// Validate ensures the import file and target service have not changed since
+// the import was started.
+func (s *ImportState) Validate(fi os.FileInfo, service *models.Service) error {
+	if s.ServiceID != service.ID {
+		return fmt.Errorf("The interrupted import of %s was for a different database service. Please delete %s to start a new import", s.FilePath, s.path)
+	}
+	if s.FileSize != fi.Size() || s.ModTime != fi.ModTime().Unix() {
+		return fmt.Errorf("%s has changed since the import was started and cannot be resumed. Please delete %s to start a new import", s.FilePath, s.path)
+	}
+	return nil
+}
+
+// PartCount returns the total number of parts the import file is split into.
+func (s *ImportState) PartCount() int {
+	return int((s.FileSize + s.PartSize - 1) / s.PartSize)
+}
+
+// HasPart returns whether or not the given part has already been uploaded.
+func (s *ImportState) HasPart(partNumber int) bool {
+	for _, p := range s.Parts {
+		if p.PartNumber == partNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// partRange returns the offset and length of the plaintext for the given part.
+func (s *ImportState) partRange(partNumber int) (int64, int64) {
+	offset := int64(partNumber-1) * s.PartSize
+	length := s.PartSize
+	if offset+length > s.FileSize {
+		length = s.FileSize - offset
+	}
+	return offset, length
+}
+
+// Save writes the import progress to disk.
+func (s *ImportState) Save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, 0600)
+}
+
+// Remove deletes the import progress file.
+func (s *ImportState) Remove() error {
+	err := os.Remove(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ImportMultipart imports data into a database service by encrypting and
+// uploading the file in independently retryable parts. Every uploaded part is
+// recorded in the given state so that an interrupted import can be resumed.
+// Each part is a whole number of encryption chunks, so the uploaded parts
+// concatenate to the same ciphertext a single pass import would produce.
+func (d *SDb) ImportMultipart(state *ImportState, mongoCollection, mongoDatabase string, service *models.Service) (*models.Job, error) {
+	key, iv := state.Key, state.IV
+	if state.UploadID == "" {
+		upload, err := d.StartMultipartUpload(service)
+		if err != nil {
+			return nil, err
+		}
+		state.UploadID = upload.UploadID
+		state.Filename = upload.Filename
+		if err = state.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(state.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sizer, err := d.Crypto.NewEncryptReader(nil, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := 0
+	for _, p := range state.Parts {
+		_, length := state.partRange(p.PartNumber)
+		uploaded += sizer.CalculateTotalSize(int(length))
+	}
+	pt := transfer.NewPartsTransfer(sizer.CalculateTotalSize(int(state.FileSize)), uploaded)
+	done := make(chan bool)
+	go printTransferStatus(false, pt, done)
+	for partNumber := 1; partNumber <= state.PartCount(); partNumber++ {
+		if state.HasPart(partNumber) {
+			continue
+		}
+		var etag string
+		for attempt := 1; attempt <= importPartRetries; attempt++ {
+			etag, err = d.uploadPart(file, state, partNumber, key, iv, pt, service)
+			if err == nil {
+				break
+			}
+			logrus.Debugf("Failed to upload part %d (attempt %d of %d): %s", partNumber, attempt, importPartRetries, err)
+			if attempt < importPartRetries {
+				time.Sleep(time.Duration(1<<uint(attempt)) * time.Second)
+			}
+		}
+		if err != nil {
+			done <- false
+			return nil, fmt.Errorf("Failed to upload part %d of %d: %s. Run the same command with --resume to continue the import", partNumber, state.PartCount(), err)
+		}
+		state.Parts = append(state.Parts, models.UploadPart{PartNumber: partNumber, ETag: etag})
+		if err = state.Save(); err != nil {
+			done <- false
+			return nil, err
+		}
+	}
+	done <- true
+
+	sortutil.AscByField(state.Parts, "PartNumber")
+	if err = d.CompleteMultipartUpload(state.UploadID, state.Parts, service); err != nil {
+		return nil, err
+	}
+	return d.startImport(state.Filename, key, iv, mongoCollection, mongoDatabase, service)
+}
+
+// uploadPart encrypts and uploads a single part of the import file, returning
+// the ETag of the uploaded part.
+func (d *SDb) uploadPart(file *os.File, state *ImportState, partNumber int, key, iv []byte, pt *transfer.PartsTransfer, service *models.Service) (string, error) {
+	tmpURL, err := d.TempUploadPartURL(state.UploadID, partNumber, service)
+	if err != nil {
+		return "", err
+	}
+	offset, length := state.partRange(partNumber)
+	if _, err = file.Seek(offset, 0); err != nil {
+		return "", err
+	}
+	encryptReader, err := d.Crypto.NewEncryptReaderAt(io.LimitReader(file, length), key, iv, offset/crypto.ChunkSize)
+	if err != nil {
+		return "", err
+	}
+	// the encrypt reader seals an empty trailing chunk when the plaintext ends
+	// on a chunk boundary. Parts must not contain it or the concatenated parts
+	// would no longer decrypt as a single stream.
+	size := int64(encryptReader.CalculateTotalSize(int(length)))
+	pr := pt.NewReader(io.LimitReader(encryptReader, size))
+	req, err := http.NewRequest("PUT", tmpURL.URL, pr)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-amz-server-side-encryption", "AES256")
+	req.ContentLength = size
+	client, err := network.Client(d.Settings.Network)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		pr.Rewind()
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		pr.Rewind()
+		b, err := ioutil.ReadAll(resp.Body)
+		logrus.Debugf("Error uploading part %d: %d %s %s", partNumber, resp.StatusCode, string(b), err)
+		return "", fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// StartMultipartUpload begins a new multipart upload for an import file.
+func (d *SDb) StartMultipartUpload(service *models.Service) (*models.MultipartUpload, error) {
+	headers := d.Settings.HTTPManager.GetHeaders(d.Settings.SessionToken, d.Settings.Version, d.Settings.Pod, d.Settings.UsersID)
+	resp, statusCode, err := d.Settings.HTTPManager.Post(nil, fmt.Sprintf("%s%s/environments/%s/services/%s/restore-uploads", d.Settings.PaasHost, d.Settings.PaasHostVersion, d.Settings.EnvironmentID, service.ID), headers)
+	if err != nil {
+		return nil, err
+	}
+	var upload models.MultipartUpload
+	err = d.Settings.HTTPManager.ConvertResp(resp, statusCode, &upload)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// TempUploadPartURL retrieves a temporary URL to upload a single part of a
+// multipart upload to.
+func (d *SDb) TempUploadPartURL(uploadID string, partNumber int, service *models.Service) (*models.TempURL, error) {
+	headers := d.Settings.HTTPManager.GetHeaders(d.Settings.SessionToken, d.Settings.Version, d.Settings.Pod, d.Settings.UsersID)
+	resp, statusCode, err := d.Settings.HTTPManager.Get(nil, fmt.Sprintf("%s%s/environments/%s/services/%s/restore-uploads/%s/parts/%d", d.Settings.PaasHost, d.Settings.PaasHostVersion, d.Settings.EnvironmentID, service.ID, uploadID, partNumber), headers)
+	if err != nil {
+		return nil, err
+	}
+	var tempURL models.TempURL
+	err = d.Settings.HTTPManager.ConvertResp(resp, statusCode, &tempURL)
+	if err != nil {
+		return nil, err
+	}
+	return &tempURL, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final import
+// file.
+func (d *SDb) CompleteMultipartUpload(uploadID string, parts []models.UploadPart, service *models.Service) error {
+	b, err := json.Marshal(map[string][]models.UploadPart{"parts": parts})
+	if err != nil {
+		return err
+	}
+	headers := d.Settings.HTTPManager.GetHeaders(d.Settings.SessionToken, d.Settings.Version, d.Settings.Pod, d.Settings.UsersID)
+	resp, statusCode, err := d.Settings.HTTPManager.Post(b, fmt.Sprintf("%s%s/environments/%s/services/%s/restore-uploads/%s/complete", d.Settings.PaasHost, d.Settings.PaasHostVersion, d.Settings.EnvironmentID, service.ID, uploadID), headers)
+	if err != nil {
+		return err
+	}
+	return d.Settings.HTTPManager.ConvertResp(resp, statusCode, nil)
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+var multipartFilePath = "db-import-multipart.sql"
+
+func TestDbImportMultipart(t *testing.T) {
+	// 2.5 chunks worth of data split into 1 chunk parts
+	plaintext := make([]byte, crypto.ChunkSize*5/2)
+	rand.Read(plaintext)
+	ioutil.WriteFile(multipartFilePath, plaintext, 0644)
+	defer os.Remove(multipartFilePath)
+	defer os.Remove(importStatePath(multipartFilePath))
+	fi, _ := os.Stat(multipartFilePath)
+
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+
+	var lock sync.Mutex
+	parts := map[string][]byte{}
+	var completed []models.UploadPart
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+dbID+"/restore-uploads",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "POST")
+			fmt.Fprint(w, `{"uploadId":"u1","filename":"import-file"}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+dbID+"/restore-uploads/u1/parts/",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			partNumber := strings.TrimPrefix(r.URL.Path, "/environments/"+test.EnvID+"/services/"+dbID+"/restore-uploads/u1/parts/")
+			fmt.Fprint(w, fmt.Sprintf(`{"url":"%s/part/%s"}`, baseURL.String(), partNumber))
+		},
+	)
+	mux.HandleFunc("/part/",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "PUT")
+			b, _ := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			partNumber := strings.TrimPrefix(r.URL.Path, "/part/")
+			lock.Lock()
+			parts[partNumber] = b
+			lock.Unlock()
+			w.Header().Set("ETag", "etag-"+partNumber)
+			w.WriteHeader(200)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+dbID+"/restore-uploads/u1/complete",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "POST")
+			var body map[string][]models.UploadPart
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			completed = body["parts"]
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+dbID+"/import",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "POST")
+			fmt.Fprint(w, fmt.Sprintf(`{"id":"%s","type":"restore","status":"running"}`, dbImportID))
+		},
+	)
+
+	key := make([]byte, crypto.KeySize)
+	iv := make([]byte, crypto.IVSize)
+	rand.Read(key)
+	rand.Read(iv)
+	// the first part was uploaded by a previous, interrupted attempt
+	firstPart, _ := crypto.New().NewEncryptReader(bytes.NewReader(plaintext[:crypto.ChunkSize]), key, iv)
+	parts["1"], _ = ioutil.ReadAll(io.LimitReader(firstPart, int64(firstPart.CalculateTotalSize(crypto.ChunkSize))))
+	state := &ImportState{
+		FilePath:  multipartFilePath,
+		FileSize:  fi.Size(),
+		ModTime:   fi.ModTime().Unix(),
+		ServiceID: dbID,
+		Key:       key,
+		IV:        iv,
+		PartSize:  crypto.ChunkSize,
+		UploadID:  "u1",
+		Filename:  "import-file",
+		Parts:     []models.UploadPart{{PartNumber: 1, ETag: "etag-1"}},
+		path:      importStatePath(multipartFilePath),
+	}
+
+	// test
+	job, err := New(settings, crypto.New(), jobs.New(settings)).ImportMultipart(state, "", "", &models.Service{ID: dbID})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if job.ID != dbImportID {
+		t.Fatalf("Expected job %s but got %s", dbImportID, job.ID)
+	}
+	if len(completed) != 3 || completed[0].PartNumber != 1 || completed[2].ETag != "etag-3" {
+		t.Fatalf("Unexpected parts sent on completion: %+v", completed)
+	}
+	encrypted := append(append(parts["1"], parts["2"]...), parts["3"]...)
+	decrypted := &closingBuffer{}
+	dwc, _ := crypto.New().NewDecryptWriteCloser(decrypted, string(crypto.New().Hex(key, crypto.KeySize*2)), string(crypto.New().Hex(iv, crypto.IVSize*2)))
+	if _, err = dwc.Write(encrypted); err != nil {
+		t.Fatalf("Failed to decrypt the uploaded parts: %s", err)
+	}
+	if err = dwc.Close(); err != nil {
+		t.Fatalf("Failed to decrypt the uploaded parts: %s", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Fatalf("The uploaded parts do not decrypt to the original file")
+	}
+}
+
+type closingBuffer struct {
+	bytes.Buffer
+}
+
+func (c *closingBuffer) Close() error {
+	return nil
+}
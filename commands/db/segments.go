@@ -0,0 +1,396 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/lib/network"
+	"github.com/daticahealth/cli/lib/transfer"
+	"github.com/daticahealth/cli/models"
+)
+
+const (
+	// downloadStateSuffix is appended to the download file path to build the
+	// path of the file tracking a download's progress
+	downloadStateSuffix = ".datica-download"
+	// downloadPartSuffix is appended to the download file path to build the
+	// path of the still encrypted data while it is being downloaded
+	downloadPartSuffix = ".datica-download.part"
+	// downloadSegmentChunks is the number of encrypted chunks in each segment
+	downloadSegmentChunks = 8
+	// downloadSegmentRetries is the number of times a single segment is
+	// attempted before the download is aborted
+	downloadSegmentRetries = 5
+	// defaultDownloadParallel is the default number of segments downloaded at
+	// the same time
+	defaultDownloadParallel = 4
+)
+
+// DownloadState records which segments of an encrypted backup have been
+// downloaded so that an interrupted download of the same backup can be
+// resumed.
+type DownloadState struct {
+	BackupID    string `json:"backupId"`
+	Size        int64  `json:"size"`
+	SegmentSize int64  `json:"segmentSize"`
+	Segments    []int  `json:"segments"`
+
+	path string
+}
+
+func downloadStatePath(filePath string) string {
+	return filePath + downloadStateSuffix
+}
+
+func downloadPartPath(filePath string) string {
+	return filePath + downloadPartSuffix
+}
+
+// loadDownloadState reads the state of an interrupted download of the given
+// backup to the given file. If there is no interrupted download of that
+// backup, nil is returned and any leftovers from a download of a different
+// backup are removed.
+func loadDownloadState(filePath, backupID string, size int64) (*DownloadState, error) {
+	path := downloadStatePath(filePath)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state DownloadState
+	if err = json.Unmarshal(b, &state); err != nil || state.BackupID != backupID || state.Size != size {
+		logrus.Debugf("Discarding the progress of a previous download to %s", filePath)
+		os.Remove(downloadPartPath(filePath))
+		return nil, os.Remove(path)
+	}
+	if _, err = os.Stat(downloadPartPath(filePath)); os.IsNotExist(err) {
+		return nil, os.Remove(path)
+	}
+	state.path = path
+	return &state, nil
+}
+
+// SegmentCount returns the total number of segments the backup is split into.
+func (s *DownloadState) SegmentCount() int {
+	return int((s.Size + s.SegmentSize - 1) / s.SegmentSize)
+}
+
+// HasSegment returns whether or not the given segment has already been
+// downloaded.
+func (s *DownloadState) HasSegment(segment int) bool {
+	for _, seg := range s.Segments {
+		if seg == segment {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentRange returns the offset and length of the given segment.
+func (s *DownloadState) segmentRange(segment int) (int64, int64) {
+	offset := int64(segment) * s.SegmentSize
+	length := s.SegmentSize
+	if offset+length > s.Size {
+		length = s.Size - offset
+	}
+	return offset, length
+}
+
+// Save writes the download progress to disk.
+func (s *DownloadState) Save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, 0600)
+}
+
+type segmentResult struct {
+	segment int
+	err     error
+}
+
+// downloadSegments downloads the encrypted backup at the given URL into the
+// part file next to the given file path using up to parallel concurrent HTTP
+// Range requests. Segments recorded by an interrupted download of the same
+// backup are skipped. If the server does not support Range requests, the
+// backup is downloaded in a single stream instead. The size of the encrypted
+// backup is returned.
+func (d *SDb) downloadSegments(url, filePath, backupID string, parallel int) (int64, error) {
+	partPath := downloadPartPath(filePath)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	client, err := network.Client(d.Settings.Network)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		logrus.Debugln("Ranged downloads are not supported, downloading in a single stream")
+		return downloadStream(resp, partPath)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("Failed to download the backup: received status code %d", resp.StatusCode)
+	}
+	// Content-Range: bytes 0-0/<size>
+	contentRange := resp.Header.Get("Content-Range")
+	size, err := strconv.ParseInt(contentRange[strings.LastIndex(contentRange, "/")+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to download the backup: invalid Content-Range header \"%s\"", contentRange)
+	}
+
+	state, err := loadDownloadState(filePath, backupID, size)
+	if err != nil {
+		return 0, err
+	}
+	if state != nil {
+		logrus.Printf("Resuming the previous download of backup %s", backupID)
+	} else {
+		state = &DownloadState{
+			BackupID:    backupID,
+			Size:        size,
+			SegmentSize: downloadSegmentChunks * (crypto.ChunkSize + crypto.TagSize),
+			Segments:    []int{},
+			path:        downloadStatePath(filePath),
+		}
+	}
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if err = file.Truncate(size); err != nil {
+		return 0, err
+	}
+	if err = state.Save(); err != nil {
+		return 0, err
+	}
+
+	pending := []int{}
+	downloaded := int64(0)
+	for segment := 0; segment < state.SegmentCount(); segment++ {
+		if state.HasSegment(segment) {
+			_, length := state.segmentRange(segment)
+			downloaded += length
+		} else {
+			pending = append(pending, segment)
+		}
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	pt := transfer.NewPartsTransfer(int(size), int(downloaded))
+	done := make(chan bool)
+	go printTransferStatus(true, pt, done)
+
+	segments := make(chan int)
+	results := make(chan segmentResult)
+	quit := make(chan bool)
+	go func() {
+		defer close(segments)
+		for _, segment := range pending {
+			select {
+			case segments <- segment:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range segments {
+				results <- segmentResult{segment, d.downloadSegmentWithRetries(url, file, state, segment, pt)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var failed error
+	for result := range results {
+		if result.err != nil {
+			if failed == nil {
+				failed = fmt.Errorf("Failed to download segment %d of %d: %s", result.segment+1, state.SegmentCount(), result.err)
+				close(quit)
+			}
+			continue
+		}
+		// segments finishing after a failure are still recorded so that
+		// resuming does not download them again
+		state.Segments = append(state.Segments, result.segment)
+		if err = state.Save(); err != nil && failed == nil {
+			failed = err
+			close(quit)
+		}
+	}
+	done <- failed == nil
+	if failed != nil {
+		return 0, failed
+	}
+	return size, nil
+}
+
+// removeDownload deletes the encrypted part file and progress of a download.
+func removeDownload(filePath string) {
+	os.Remove(downloadPartPath(filePath))
+	os.Remove(downloadStatePath(filePath))
+}
+
+// downloadSegmentWithRetries downloads a single segment, retrying with an
+// exponential backoff on failure.
+func (d *SDb) downloadSegmentWithRetries(url string, file *os.File, state *DownloadState, segment int, pt *transfer.PartsTransfer) error {
+	client, err := network.Client(d.Settings.Network)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; attempt <= downloadSegmentRetries; attempt++ {
+		if err = downloadSegment(client, url, file, state, segment, pt); err == nil {
+			return nil
+		}
+		logrus.Debugf("Failed to download segment %d (attempt %d of %d): %s", segment+1, attempt, downloadSegmentRetries, err)
+		if attempt < downloadSegmentRetries {
+			time.Sleep(time.Duration(1<<uint(attempt)) * time.Second)
+		}
+	}
+	return err
+}
+
+// downloadSegment fetches a single segment with an HTTP Range request and
+// writes it to its offset in the part file.
+func downloadSegment(client *http.Client, url string, file *os.File, state *DownloadState, segment int, pt *transfer.PartsTransfer) error {
+	offset, length := state.segmentRange(segment)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+	pr := pt.NewReader(io.LimitReader(resp.Body, length))
+	written, err := io.Copy(&offsetWriter{file: file, offset: offset}, pr)
+	if err == nil && written != length {
+		err = fmt.Errorf("received %d of %d bytes", written, length)
+	}
+	if err != nil {
+		pr.Rewind()
+	}
+	return err
+}
+
+// downloadStream writes the entire response body to the part file.
+func downloadStream(resp *http.Response, partPath string) (int64, error) {
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	size, err := strconv.Atoi(resp.Header.Get("Content-Length"))
+	if err != nil {
+		return 0, err
+	}
+	pt := transfer.NewPartsTransfer(size, 0)
+	done := make(chan bool)
+	go printTransferStatus(true, pt, done)
+	written, err := io.Copy(file, pt.NewReader(resp.Body))
+	done <- err == nil
+	return written, err
+}
+
+// offsetWriter writes sequentially to a file starting at the given offset.
+type offsetWriter struct {
+	file   *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// verifyWriteCloser writes decrypted data to a file while counting the bytes
+// and calculating their SHA-256 checksum.
+type verifyWriteCloser struct {
+	file    *os.File
+	hash    hash.Hash
+	written int64
+}
+
+func (w *verifyWriteCloser) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hash.Write(p[:n])
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *verifyWriteCloser) Close() error {
+	return w.file.Close()
+}
+
+// decryptDownload decrypts the downloaded part file into the given file path.
+// Every chunk's GCM tag is checked as it is decrypted and the decrypted size
+// is compared against the size expected from the encrypted size, so a
+// corrupt or truncated download is reported instead of silently producing
+// an incomplete file. The SHA-256 checksum of the decrypted file is returned.
+func (d *SDb) decryptDownload(partPath, filePath string, size int64, job *models.Job) (string, error) {
+	logrus.Println("Verifying and decrypting...")
+	part, err := os.Open(partPath)
+	if err != nil {
+		return "", err
+	}
+	defer part.Close()
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	vwc := &verifyWriteCloser{file: file, hash: sha256.New()}
+	dfw, err := d.Crypto.NewDecryptWriteCloser(vwc, job.Backup.Key, job.Backup.IV)
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+	_, err = io.Copy(dfw, part)
+	if closeErr := dfw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("The downloaded backup failed verification (%s). The file is incomplete or corrupt and has been removed, please download it again", err)
+	}
+	chunks := (size + crypto.ChunkSize + crypto.TagSize - 1) / (crypto.ChunkSize + crypto.TagSize)
+	if expected := size - chunks*crypto.TagSize; vwc.written != expected {
+		os.Remove(filePath)
+		return "", fmt.Errorf("The downloaded backup failed verification (decrypted %d of %d bytes). The file is incomplete and has been removed, please download it again", vwc.written, expected)
+	}
+	return fmt.Sprintf("%x", vwc.hash.Sum(nil)), nil
+}
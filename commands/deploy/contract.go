@@ -4,10 +4,12 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/images"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -20,12 +22,18 @@ var Cmd = models.Command{
 	ShortHelp: "Deploy a Docker image to a container service.",
 	LongHelp: "<code>deploy</code> deploys a Docker image for the given service. " +
 		"This command will only deploy for \"container\" services. " +
-		"Here is a sample command\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" deploy <service> <image>:<tag>\n</pre>",
+		"By default the command returns as soon as the deploy is started. " +
+		"Pass <code>--wait</code> to follow the new deploy jobs until they are running and exit with an error if they fail, which is useful in Continuous Deployment scenarios. " +
+		"<code>--health-check</code> additionally requests a path on the service's site (or a full URL) until it responds with a 2xx status, " +
+		"and <code>--rollback-on-failure</code> redeploys the previously running release if the deploy or the health check fails. " +
+		"Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" deploy <service> <image>:<tag>\n" +
+		"datica -E \"<your_env_name>\" deploy <service> <image>:<tag> --health-check /health --rollback-on-failure\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
 			serviceName := cmd.StringArg("SERVICE_NAME", "", "The name of the service where the image will be deployed. (e.g. 'container-1')")
 			imageName := cmd.StringArg("TAGGED_IMAGE", "", "The name and tag of the image to deploy. (e.g. 'my-image:tag)")
+			waitOpts := RollbackWaitOpts(cmd)
 			cmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -33,12 +41,16 @@ var Cmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdDeploy(settings.EnvironmentID, *serviceName, *imageName, jobs.New(settings), services.New(settings), environments.New(settings), images.New(settings))
+				client, err := network.Client(settings.Network)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdDeploy(settings.EnvironmentID, *serviceName, *imageName, waitOpts(client), jobs.New(settings), services.New(settings), environments.New(settings), images.New(settings), sites.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			cmd.Spec = "SERVICE_NAME TAGGED_IMAGE"
+			cmd.Spec = "SERVICE_NAME TAGGED_IMAGE " + RollbackWaitSpec
 		}
 	},
 }
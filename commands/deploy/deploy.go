@@ -6,11 +6,12 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/lib/images"
 	"github.com/daticahealth/cli/lib/jobs"
 )
 
-func CmdDeploy(envID, svcName, imgName string, ij jobs.IJobs, is services.IServices, ie environments.IEnvironments, ii images.IImages) error {
+func CmdDeploy(envID, svcName, imgName string, opts WaitOptions, ij jobs.IJobs, is services.IServices, ie environments.IEnvironments, ii images.IImages, isites sites.ISites) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -31,11 +32,22 @@ func CmdDeploy(envID, svcName, imgName string, ij jobs.IJobs, is services.IServi
 	}
 	imageTag := fmt.Sprintf("%s:%s", namespacedImage, tag)
 
+	waiter, err := NewDeployWaiter(opts, service, ij, is, isites)
+	if err != nil {
+		return err
+	}
 	logrus.Printf("Deploying image %s to service %s (ID = %s) in environment %s (ID = %s)", imageTag, svcName, service.ID, env.Name, env.ID)
 	err = ij.DeployRelease(imageTag, service.ID)
 	if err != nil {
 		return err
 	}
+	if opts.Wait {
+		if err = waiter.Wait(); err != nil {
+			return err
+		}
+		logrus.Printf("Deploy finished! %s is running %s", svcName, imageTag)
+		return nil
+	}
 	logrus.Println("Deploy successful! Check the status with \"datica status\" and your logging dashboard for updates")
 	return nil
 }
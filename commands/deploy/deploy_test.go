@@ -3,11 +3,14 @@ package deploy
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/lib/images"
 	"github.com/daticahealth/cli/lib/jobs"
 	"github.com/daticahealth/cli/test"
@@ -66,7 +69,7 @@ func TestDeploy(t *testing.T) {
 		t.Logf("Data: %+v", data)
 
 		// test
-		err := CmdDeploy(settings.EnvironmentID, data.container, data.image, jobs.New(settings), services.New(settings), environments.New(settings), images.New(settings))
+		err := CmdDeploy(settings.EnvironmentID, data.container, data.image, WaitOptions{}, jobs.New(settings), services.New(settings), environments.New(settings), images.New(settings), sites.New(settings))
 
 		// assert
 		if (err != nil) != data.expectErr {
@@ -75,3 +78,84 @@ func TestDeploy(t *testing.T) {
 		}
 	}
 }
+
+var deployWaitTests = []struct {
+	status      string
+	healthy     bool
+	rollback    bool
+	expectErr   bool
+	expectedLog []string
+}{
+	{"running", true, false, false, []string{image2}},
+	{"failed", true, false, true, []string{image2}},
+	{"failed", true, true, true, []string{image2, "prev"}},
+	{"running", false, true, true, []string{image2, "prev"}},
+	// a deploy that times out is not rolled back
+	{"started", true, true, true, []string{image2}},
+}
+
+func TestDeployWait(t *testing.T) {
+	health := httptest.NewServer(nil)
+	defer health.Close()
+	for _, data := range deployWaitTests {
+		t.Logf("Data: %+v", data)
+		mux, server, baseURL := test.Setup()
+		settings := test.GetSettings(baseURL.String())
+		mux.HandleFunc("/environments/"+test.EnvID,
+			func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, fmt.Sprintf(`{"id":"%s","name":"%s","namespace":"%s","organizationId":"%s"}`, test.EnvID, test.EnvName, test.Namespace, test.OrgID))
+			},
+		)
+		mux.HandleFunc("/environments/"+test.EnvID+"/services",
+			func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s","type":"container","release_version":"prev"}]`, test.SvcID, container))
+			},
+		)
+		// the first deploy ends in the status of the test, the rollback succeeds
+		var deployed []string
+		mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/deploy",
+			func(w http.ResponseWriter, r *http.Request) {
+				release := r.URL.Query().Get("release")
+				deployed = append(deployed, release[strings.LastIndex(release, "/")+1:])
+				w.WriteHeader(202)
+			},
+		)
+		mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs",
+			func(w http.ResponseWriter, r *http.Request) {
+				test.AssertEquals(t, "deploy", r.URL.Query().Get("type"))
+				jobs := []string{`{"id":"old","type":"deploy","status":"running"}`}
+				for i := range deployed {
+					jobs = append(jobs, fmt.Sprintf(`{"id":"job%d","type":"deploy"}`, i))
+				}
+				fmt.Fprintf(w, "[%s]", strings.Join(jobs, ","))
+			},
+		)
+		mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs/",
+			func(w http.ResponseWriter, r *http.Request) {
+				status := "running"
+				if strings.HasSuffix(r.URL.Path, "/job0") {
+					status = data.status
+				}
+				fmt.Fprintf(w, `{"id":"job","type":"deploy","status":"%s"}`, status)
+			},
+		)
+		healthy := data.healthy
+		health.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, "/health", r.URL.Path)
+			if !healthy {
+				w.WriteHeader(503)
+			}
+		})
+		opts := WaitOptions{Wait: true, Timeout: time.Second, HealthCheck: health.URL + "/health", RollbackOnFailure: data.rollback}
+
+		err := CmdDeploy(settings.EnvironmentID, container, image2, opts, jobs.New(settings), services.New(settings), environments.New(settings), images.New(settings), sites.New(settings))
+		test.Teardown(server)
+
+		if (err != nil) != data.expectErr {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if strings.Join(deployed, ",") != strings.Join(data.expectedLog, ",") {
+			t.Errorf("Expected deploys %v but got %v", data.expectedLog, deployed)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/models"
+	"github.com/jault3/mow.cli"
+)
+
+// WaitSpec is the part of a command spec for the options added by WaitOpts
+const WaitSpec = "[--wait] [--wait-timeout] [--health-check]"
+
+// RollbackWaitSpec is the part of a command spec for the options added by
+// RollbackWaitOpts
+const RollbackWaitSpec = WaitSpec + " [--rollback-on-failure]"
+
+// WaitOptions control whether deploy, redeploy, and rollback follow the new
+// deploy jobs until they are running instead of returning right away.
+type WaitOptions struct {
+	Wait bool
+	// Timeout bounds the whole wait including the health check
+	Timeout time.Duration
+	// HealthCheck is a path requested on the service's site or a full URL
+	HealthCheck string
+	// RollbackOnFailure redeploys the previously running release when the
+	// new deploy jobs fail or the health check does not pass. Interrupts,
+	// timeouts waiting for the jobs, and errors retrieving them are not
+	// rolled back.
+	RollbackOnFailure bool
+	// Client is used for the health check
+	Client *http.Client
+}
+
+// WaitOpts adds the --wait options to a command. The returned function reads
+// the options once the command runs.
+func WaitOpts(cmd *cli.Cmd) func(client *http.Client) WaitOptions {
+	wait := cmd.BoolOpt("wait", false, "Wait for the new deploy jobs to be running and exit with an error if they fail")
+	timeout := cmd.IntOpt("wait-timeout", 15, "How many minutes to wait for the deploy to finish")
+	healthCheck := cmd.StringOpt("health-check", "", "After the deploy, request this path on the service's site (or this full URL) until it responds with a 2xx status. Implies --wait")
+	return func(client *http.Client) WaitOptions {
+		return WaitOptions{
+			Wait:        *wait || *healthCheck != "",
+			Timeout:     time.Duration(*timeout) * time.Minute,
+			HealthCheck: *healthCheck,
+			Client:      client,
+		}
+	}
+}
+
+// RollbackWaitOpts adds the --wait options and --rollback-on-failure to a
+// command that deploys a release other than the one running.
+func RollbackWaitOpts(cmd *cli.Cmd) func(client *http.Client) WaitOptions {
+	waitOpts := WaitOpts(cmd)
+	rollback := cmd.BoolOpt("rollback-on-failure", false, "Redeploy the previously running release if the deploy fails. Implies --wait")
+	return func(client *http.Client) WaitOptions {
+		opts := waitOpts(client)
+		opts.Wait = opts.Wait || *rollback
+		opts.RollbackOnFailure = *rollback
+		return opts
+	}
+}
+
+// deployFailure is a deploy job that ended in a failed status or a health
+// check that did not pass. Only these are rolled back.
+type deployFailure struct {
+	error
+}
+
+// DeployWaiter follows a deploy of a single service.
+type DeployWaiter struct {
+	opts            WaitOptions
+	service         *models.Service
+	previousRelease string
+	existingJobs    map[string]bool
+	ij              jobs.IJobs
+	is              services.IServices
+	isites          sites.ISites
+}
+
+// NewDeployWaiter records the running release and the existing deploy jobs of
+// the service. It must be called before the deploy is started.
+func NewDeployWaiter(opts WaitOptions, service *models.Service, ij jobs.IJobs, is services.IServices, isites sites.ISites) (*DeployWaiter, error) {
+	w := &DeployWaiter{opts: opts, service: service, previousRelease: service.ReleaseVersion, ij: ij, is: is, isites: isites}
+	if !opts.Wait {
+		return w, nil
+	}
+	existing, err := w.deployJobs()
+	if err != nil {
+		return nil, err
+	}
+	w.existingJobs = map[string]bool{}
+	for _, job := range existing {
+		w.existingJobs[job.ID] = true
+	}
+	return w, nil
+}
+
+// Wait follows the deploy that was just started. It is a no-op unless
+// --wait was given. When the deploy fails and --rollback-on-failure was given
+// the previously running release is deployed again and waited for.
+func (w *DeployWaiter) Wait() error {
+	if !w.opts.Wait {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.Timeout)
+	err := w.waitForDeploy(ctx, w.opts.HealthCheck)
+	cancel()
+	if err == nil || !w.opts.RollbackOnFailure {
+		return err
+	}
+	if _, ok := err.(*deployFailure); !ok {
+		return fmt.Errorf("%s. %s was not rolled back", err, w.service.Label)
+	}
+	if w.previousRelease == "" {
+		return fmt.Errorf("%s. The previously running release of %s is unknown so it was not rolled back", err, w.service.Label)
+	}
+	logrus.Warnf("%s. Rolling back %s to %s", err, w.service.Label, w.previousRelease)
+	if rollbackErr := w.ij.DeployRelease(w.previousRelease, w.service.ID); rollbackErr != nil {
+		return fmt.Errorf("%s. The rollback to %s could not be started: %s", err, w.previousRelease, rollbackErr)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), w.opts.Timeout)
+	defer cancel()
+	if rollbackErr := w.waitForDeploy(ctx, ""); rollbackErr != nil {
+		return fmt.Errorf("%s. The rollback to %s failed: %s", err, w.previousRelease, rollbackErr)
+	}
+	return fmt.Errorf("%s. Rolled back %s to %s", err, w.service.Label, w.previousRelease)
+}
+
+func (w *DeployWaiter) deployJobs() ([]models.Job, error) {
+	deployJobs, err := w.ij.RetrieveByType(w.service.ID, "deploy", 1, 100)
+	if err != nil {
+		return nil, err
+	}
+	if deployJobs == nil {
+		return []models.Job{}, nil
+	}
+	return *deployJobs, nil
+}
+
+// waitForDeploy waits for new deploy jobs to show up, follows them until
+// they are running, and then runs the health check if there is one.
+func (w *DeployWaiter) waitForDeploy(ctx context.Context, healthCheck string) error {
+	logrus.Printf("Waiting for the new deploy jobs of %s", w.service.Label)
+	var newJobs []models.Job
+	for {
+		all, err := w.deployJobs()
+		if err != nil {
+			return err
+		}
+		for _, job := range all {
+			if !w.existingJobs[job.ID] {
+				newJobs = append(newJobs, job)
+				w.existingJobs[job.ID] = true
+			}
+		}
+		if len(newJobs) > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Timed out waiting for the deploy jobs of %s to start", w.service.Label)
+		case <-time.After(config.JobPollTime * time.Second):
+		}
+	}
+
+	for _, job := range newJobs {
+		status, err := w.ij.Watch(ctx, job.ID, w.service.ID, jobs.WatchOptions{Statuses: []string{"running"}, Timeout: w.opts.Timeout})
+		if err != nil {
+			err = fmt.Errorf("Deploy job %s of %s failed: %s", job.ID, w.service.Label, err)
+			// Ctrl-C, timeouts, and failed polls leave the job pending
+			if ctx.Err() == nil && status != "" && !jobs.IsPending(status) {
+				return &deployFailure{err}
+			}
+			return err
+		}
+	}
+	if healthCheck == "" {
+		return nil
+	}
+	return w.checkHealth(ctx, healthCheck)
+}
+
+// healthCheckURL turns a path into a URL on the first site that proxies to
+// the service. Wildcard sites cannot be requested so they are skipped.
+func (w *DeployWaiter) healthCheckURL(healthCheck string) (string, error) {
+	if strings.HasPrefix(healthCheck, "http://") || strings.HasPrefix(healthCheck, "https://") {
+		return healthCheck, nil
+	}
+	serviceProxy, err := w.is.RetrieveByLabel("service_proxy")
+	if err != nil {
+		return "", err
+	}
+	if serviceProxy == nil {
+		return "", fmt.Errorf("Could not find the service proxy to look up the site of %s. Pass a full URL to --health-check instead", w.service.Label)
+	}
+	siteList, err := w.isites.List(serviceProxy.ID)
+	if err != nil {
+		return "", err
+	}
+	if siteList != nil {
+		for _, site := range *siteList {
+			if site.UpstreamService == w.service.ID && !strings.HasPrefix(site.Name, ".") && !strings.HasPrefix(site.Name, "*") {
+				return fmt.Sprintf("https://%s/%s", site.Name, strings.TrimPrefix(healthCheck, "/")), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Could not find a site for %s. Pass a full URL to --health-check instead", w.service.Label)
+}
+
+// checkHealth requests the health check URL until it responds with a 2xx
+// status or the wait times out.
+func (w *DeployWaiter) checkHealth(ctx context.Context, healthCheck string) error {
+	url, err := w.healthCheckURL(healthCheck)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	if w.opts.Client != nil {
+		client = *w.opts.Client
+		client.Timeout = 30 * time.Second
+	}
+	logrus.Printf("Checking %s", url)
+	for {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				logrus.Printf("Health check passed with status %d", resp.StatusCode)
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		logrus.Debugf("Health check of %s failed: %s", url, err)
+		select {
+		case <-ctx.Done():
+			return &deployFailure{fmt.Errorf("Health check of %s failed: %s", url, err)}
+		case <-time.After(config.JobPollTime * time.Second):
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package deploykeys
 
 import (
-	"crypto/rsa"
+	"crypto"
 
 	"golang.org/x/crypto/ssh"
 
@@ -9,6 +9,7 @@ import (
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -30,7 +31,7 @@ var Cmd = models.Command{
 var AddSubCmd = models.Command{
 	Name:      "add",
 	ShortHelp: "Add a new deploy key",
-	LongHelp: "<code>deploy-keys add</code> allows you to upload an SSH public key in OpenSSH format. " +
+	LongHelp: "<code>deploy-keys add</code> allows you to upload an RSA, ECDSA, or Ed25519 SSH public key in OpenSSH format. " +
 		"These keys are used for pushing code to your code services but are not required. " +
 		"You should be using personal SSH keys with the keys command unless you are pushing code from Continuous Integration or Continuous Deployment scenarios. " +
 		"Deploy keys are intended to be shared among an organization. Here are some sample commands\n\n" +
@@ -72,7 +73,7 @@ var ListSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdList(*serviceName, New(settings), services.New(settings))
+				err := CmdList(*serviceName, New(settings), services.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -112,9 +113,9 @@ var RmSubCmd = models.Command{
 
 type IDeployKeys interface {
 	Add(name, keyType, key, svcID string) error
-	ExtractPublicKey(privKey *rsa.PrivateKey) (ssh.PublicKey, error)
+	ExtractPublicKey(privKey crypto.Signer) (ssh.PublicKey, error)
 	List(svcID string) (*[]models.DeployKey, error)
-	ParsePrivateKey(b []byte) (*rsa.PrivateKey, error)
+	ParsePrivateKey(b []byte) (crypto.Signer, error)
 	ParsePublicKey(b []byte) (ssh.PublicKey, error)
 	Rm(name, keyType, svcID string) error
 }
@@ -8,11 +8,20 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdList(svcName string, id IDeployKeys, is services.IServices) error {
+// deployKeyOutput is the structured representation of a deploy key used when
+// a machine readable output format is selected.
+type deployKeyOutput struct {
+	models.DeployKey
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func CmdList(svcName string, id IDeployKeys, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -28,11 +37,15 @@ func CmdList(svcName string, id IDeployKeys, is services.IServices) error {
 	if err != nil {
 		return err
 	}
-	if keys == nil || len(*keys) == 0 {
+	if keys == nil {
+		keys = &[]models.DeployKey{}
+	}
+	if len(*keys) == 0 && !iout.Structured() {
 		logrus.Println("No deploy-keys found")
 		return nil
 	}
 
+	out := []deployKeyOutput{}
 	invalidKeys := map[string]string{}
 
 	data := [][]string{{"NAME", "TYPE", "FINGERPRINT"}}
@@ -43,12 +56,18 @@ func CmdList(svcName string, id IDeployKeys, is services.IServices) error {
 		s, err := id.ParsePublicKey([]byte(key.Key))
 		if err != nil {
 			invalidKeys[key.Name] = err.Error()
+			out = append(out, deployKeyOutput{DeployKey: key, Error: err.Error()})
 			continue
 		}
 		h := sha256.New()
 		h.Write(s.Marshal())
 		fingerprint := base64.StdEncoding.EncodeToString(h.Sum(nil))
-		data = append(data, []string{key.Name, key.Type, fmt.Sprintf("SHA256:%s", strings.TrimRight(fingerprint, "="))})
+		fingerprint = fmt.Sprintf("SHA256:%s", strings.TrimRight(fingerprint, "="))
+		data = append(data, []string{key.Name, key.Type, fingerprint})
+		out = append(out, deployKeyOutput{DeployKey: key, Fingerprint: fingerprint})
+	}
+	if iout.Structured() {
+		return iout.Render(out)
 	}
 
 	table := tablewriter.NewWriter(logrus.StandardLogger().Out)
@@ -1,33 +1,36 @@
 package deploykeys
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"errors"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/daticahealth/cli/lib/sshkeys"
 )
 
-func (d *SDeployKeys) ParsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
-	in, err := ssh.ParseRawPrivateKey(b)
-	if err != nil {
-		return nil, errors.New("Invalid RSA private key format")
+func (d *SDeployKeys) ParsePrivateKey(b []byte) (crypto.Signer, error) {
+	privKey, err := sshkeys.ParsePrivateKey(b, nil)
+	if err == sshkeys.ErrEncrypted {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.New("Invalid private key format. RSA, ECDSA, and Ed25519 keys are supported")
 	}
-	privKey := in.(*rsa.PrivateKey)
 	return privKey, nil
 }
 
 func (d *SDeployKeys) ParsePublicKey(b []byte) (ssh.PublicKey, error) {
-	s, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	s, err := sshkeys.ParsePublicKey(b)
 	if err != nil {
-		return nil, errors.New("Invalid RSA public key format")
+		return nil, errors.New("Invalid public key format. RSA, ECDSA, and Ed25519 keys are supported")
 	}
 	return s, nil
 }
 
-func (d *SDeployKeys) ExtractPublicKey(privKey *rsa.PrivateKey) (ssh.PublicKey, error) {
-	s, err := ssh.NewPublicKey(&privKey.PublicKey)
+func (d *SDeployKeys) ExtractPublicKey(privKey crypto.Signer) (ssh.PublicKey, error) {
+	s, err := sshkeys.NewPublicKey(privKey.Public())
 	if err != nil {
-		return nil, errors.New("Invalid RSA public key format derived from private key")
+		return nil, errors.New("Invalid public key format derived from private key")
 	}
 	return s, nil
 }
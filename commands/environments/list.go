@@ -5,12 +5,23 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 )
 
 // CmdList lists all environments which the user has access to
-func CmdList(settings *models.Settings, environments IEnvironments) error {
+func CmdList(settings *models.Settings, environments IEnvironments, iout output.IOutput) error {
 	envs, errs := environments.List()
+	if iout.Structured() {
+		for pod, err := range errs {
+			logrus.Debugf("Failed to list environments for pod \"%s\": %s", pod, err)
+		}
+		if envs == nil {
+			envs = &[]models.Environment{}
+		}
+		config.StoreEnvironments(envs, settings)
+		return iout.Render(envs)
+	}
 	if envs == nil || len(*envs) == 0 {
 		logrus.Println("no environments found")
 	} else {
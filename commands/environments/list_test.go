@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/daticahealth/cli/test"
 )
@@ -25,7 +26,7 @@ func TestList(t *testing.T) {
 		},
 	)
 
-	err := CmdList(settings, New(settings))
+	err := CmdList(settings, New(settings), output.New(settings))
 
 	// assert
 	if err != nil {
@@ -62,7 +63,7 @@ func TestListWithPodError(t *testing.T) {
 		},
 	)
 
-	err := CmdList(settings, New(settings))
+	err := CmdList(settings, New(settings), output.New(settings))
 
 	// assert
 	if err != nil {
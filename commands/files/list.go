@@ -7,6 +7,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
@@ -14,7 +15,7 @@ import (
 // CmdList lists all service files that are able to be downloaded
 // by a member of the environment. Typically service files of interest
 // will be on the service_proxy.
-func CmdList(svcName string, showTimestamps bool, ifiles IFiles, is services.IServices) error {
+func CmdList(svcName string, showTimestamps bool, ifiles IFiles, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -26,6 +27,12 @@ func CmdList(svcName string, showTimestamps bool, ifiles IFiles, is services.ISe
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		if files == nil {
+			files = &[]models.ServiceFile{}
+		}
+		return iout.Render(files)
+	}
 	if files == nil || len(*files) == 0 {
 		logrus.Println("No service files found")
 		return nil
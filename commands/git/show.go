@@ -5,9 +5,10 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 )
 
-func CmdShow(svcName string, is services.IServices) error {
+func CmdShow(svcName string, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -18,6 +19,12 @@ func CmdShow(svcName string, is services.IServices) error {
 	if service.Source == "" {
 		return fmt.Errorf("No git remote found for the \"%s\" service.", svcName)
 	}
+	if iout.Structured() {
+		return iout.Render(struct {
+			Service string `json:"service"`
+			Source  string `json:"source"`
+		}{svcName, service.Source})
+	}
 	logrus.Println(service.Source)
 	return nil
 }
@@ -1,12 +1,16 @@
 package git
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/test"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var showTests = []struct {
@@ -32,7 +36,7 @@ func TestShow(t *testing.T) {
 		t.Logf("Data: %+v", data)
 
 		// test
-		err := CmdShow(data.svcLabel, services.New(settings))
+		err := CmdShow(data.svcLabel, services.New(settings), output.New(settings))
 
 		// assert
 		if err != nil != data.expectErr {
@@ -41,3 +45,39 @@ func TestShow(t *testing.T) {
 		}
 	}
 }
+
+func TestShowStructured(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	mux.HandleFunc("/environments/"+test.EnvID+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprintf(w, `[{"id":"%s","label":"%s","source":"git@github.com/github/github.git"}]`, test.SvcID, test.SvcLabel)
+		},
+	)
+
+	for _, format := range []string{output.FormatJSON, output.FormatYAML} {
+		t.Logf("Format: %s", format)
+		buf := &bytes.Buffer{}
+
+		// test
+		err := CmdShow(test.SvcLabel, services.New(settings), &output.SOutput{Format: format, Writer: buf})
+
+		// assert
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		var show map[string]string
+		if format == output.FormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &show)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &show)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s output: %s", format, err)
+		}
+		test.AssertEquals(t, test.SvcLabel, show["service"])
+		test.AssertEquals(t, "git@github.com/github/github.git", show["source"])
+	}
+}
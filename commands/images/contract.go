@@ -8,6 +8,7 @@ import (
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -46,7 +47,7 @@ var listCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := cmdImageList(settings.EnvironmentID, environments.New(settings), images.New(settings))
+				err := cmdImageList(settings.EnvironmentID, environments.New(settings), images.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatalln(err.Error())
 				}
@@ -6,9 +6,10 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 )
 
-func cmdImageList(envID string, ie environments.IEnvironments, ii images.IImages) error {
+func cmdImageList(envID string, ie environments.IEnvironments, ii images.IImages, iout output.IOutput) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -17,6 +18,10 @@ func cmdImageList(envID string, ie environments.IEnvironments, ii images.IImages
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		sort.Strings(*images)
+		return iout.Render(images)
+	}
 	if len(*images) == 0 {
 		logrus.Println("No images found for this environment. Note that images will not be visible in this list until they have been deployed.")
 	} else {
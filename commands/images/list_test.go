@@ -1,13 +1,18 @@
 package images
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/test"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestListImages(t *testing.T) {
@@ -29,9 +34,50 @@ func TestListImages(t *testing.T) {
 		},
 	)
 
-	err := cmdImageList(test.EnvID, environments.New(settings), images.New(settings))
+	err := cmdImageList(test.EnvID, environments.New(settings), images.New(settings), output.New(settings))
 
 	if err != nil {
 		t.Fatalf("Unexpected error while listing images: %v", err)
 	}
 }
+
+func TestListImagesStructured(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+
+	mux.HandleFunc("/environments/"+test.EnvID,
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`{"id":"%s","name":"%s","namespace":"%s","organizationId":"%s","docker_registry_enabled": true}`, test.EnvID, test.EnvName, test.Namespace, test.OrgID))
+		},
+	)
+
+	mux.HandleFunc(fmt.Sprintf("/environments/"+test.EnvID+"/images"),
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, `["pod1234/b", "pod1234/a"]`)
+		},
+	)
+
+	for _, format := range []string{output.FormatJSON, output.FormatYAML} {
+		t.Logf("Format: %s", format)
+		buf := &bytes.Buffer{}
+
+		err := cmdImageList(test.EnvID, environments.New(settings), images.New(settings), &output.SOutput{Format: format, Writer: buf})
+
+		if err != nil {
+			t.Fatalf("Unexpected error while listing images: %v", err)
+		}
+		var list []string
+		if format == output.FormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &list)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &list)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s output: %s", format, err)
+		}
+		test.AssertEquals(t, "pod1234/a,pod1234/b", strings.Join(list, ","))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -41,7 +42,7 @@ var listCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := cmdTagList(images.New(settings), environments.New(settings), settings.EnvironmentID, *image)
+				err := cmdTagList(images.New(settings), environments.New(settings), output.New(settings), settings.EnvironmentID, *image)
 				if err != nil {
 					logrus.Fatalln(err.Error())
 				}
@@ -6,9 +6,10 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 )
 
-func cmdTagList(ii images.IImages, ie environments.IEnvironments, envID, image string) error {
+func cmdTagList(ii images.IImages, ie environments.IEnvironments, iout output.IOutput, envID, image string) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -21,9 +22,12 @@ func cmdTagList(ii images.IImages, ie environments.IEnvironments, envID, image s
 	if err != nil {
 		return err
 	}
+	sort.Strings(*tags)
+	if iout.Structured() {
+		return iout.Render(tags)
+	}
 	logrus.Printf("Tags for image \"%s\"", image)
 	logrus.Println("")
-	sort.Strings(*tags)
 	for _, tag := range *tags {
 		logrus.Println(tag)
 	}
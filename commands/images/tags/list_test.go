@@ -1,13 +1,18 @@
 package tags
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/test"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var listTagsTests = []struct {
@@ -39,9 +44,47 @@ func TestListTags(t *testing.T) {
 	)
 
 	for _, data := range listTagsTests {
-		err := cmdTagList(images.New(settings), environments.New(settings), settings.EnvironmentID, data.imageName)
+		err := cmdTagList(images.New(settings), environments.New(settings), output.New(settings), settings.EnvironmentID, data.imageName)
 		if (err != nil) != data.expectErr {
 			t.Fatalf("Unexpected error while listing tags: %v", err)
 		}
 	}
 }
+
+func TestListTagsStructured(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	mux.HandleFunc("/environments/"+test.EnvID,
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`{"id":"%s","name":"%s","namespace":"%s","organizationId":"%s"}`, test.EnvID, test.EnvName, test.Namespace, test.OrgID))
+		},
+	)
+	mux.HandleFunc(fmt.Sprintf("/environments/"+test.EnvID+"/images/"+test.Namespace+"%%2F"+"hello/tags"),
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, `["v1", "v2", "latest"]`)
+		},
+	)
+
+	for _, format := range []string{output.FormatJSON, output.FormatYAML} {
+		t.Logf("Format: %s", format)
+		buf := &bytes.Buffer{}
+
+		err := cmdTagList(images.New(settings), environments.New(settings), &output.SOutput{Format: format, Writer: buf}, settings.EnvironmentID, "hello")
+		if err != nil {
+			t.Fatalf("Unexpected error while listing tags: %v", err)
+		}
+		var tags []string
+		if format == output.FormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &tags)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &tags)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s output: %s", format, err)
+		}
+		test.AssertEquals(t, "latest,v1,v2", strings.Join(tags, ","))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -45,7 +46,7 @@ var listCmd = models.Command{
 				if err = config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				if err = cmdTargetsList(settings.EnvironmentID, *image, user, environments.New(settings), images.New(settings)); err != nil {
+				if err = cmdTargetsList(settings.EnvironmentID, *image, user, environments.New(settings), images.New(settings), output.New(settings)); err != nil {
 					logrus.Fatalln(err.Error())
 				}
 			}
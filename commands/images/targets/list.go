@@ -6,11 +6,12 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/lib/images"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func cmdTargetsList(envID, imageName string, user *models.User, ie environments.IEnvironments, ii images.IImages) error {
+func cmdTargetsList(envID, imageName string, user *models.User, ie environments.IEnvironments, ii images.IImages, iout output.IOutput) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -24,13 +25,17 @@ func cmdTargetsList(envID, imageName string, user *models.User, ie environments.
 	}
 	repo := ii.GetNotaryRepository(env.Pod, repositoryName, user)
 	if tag == "" {
-		logrus.Printf("Searching for signed targets in trust repository %s\n", repositoryName)
+		if !iout.Structured() {
+			logrus.Printf("Searching for signed targets in trust repository %s\n", repositoryName)
+		}
 		targets, err = ii.ListTargets(repo)
 		if err != nil {
 			return err
 		}
 	} else {
-		logrus.Printf("Searching for signed target \"%s\" in trust repository %s\n", tag, repositoryName)
+		if !iout.Structured() {
+			logrus.Printf("Searching for signed target \"%s\" in trust repository %s\n", tag, repositoryName)
+		}
 		target, err := ii.LookupTarget(repo, tag)
 		if err != nil {
 			return err
@@ -38,6 +43,19 @@ func cmdTargetsList(envID, imageName string, user *models.User, ie environments.
 		targets = append(targets, target)
 	}
 
+	if iout.Structured() {
+		type target struct {
+			Name   string `json:"name"`
+			Digest string `json:"digest"`
+			Size   int64  `json:"size"`
+			Role   string `json:"role"`
+		}
+		result := []target{}
+		for _, t := range targets {
+			result = append(result, target{Name: t.Name, Digest: string(t.Digest), Size: t.Size, Role: t.Role})
+		}
+		return iout.Render(result)
+	}
 	if len(targets) > 0 {
 		data := [][]string{{"Name", "Digest", "Size", "Role"}, {"----", "------", "----", "----"}}
 		for _, t := range targets {
@@ -4,14 +4,21 @@ import (
 	"fmt"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 )
 
-func CmdList(envName string, ii IInvites) error {
+func CmdList(envName string, ii IInvites, iout output.IOutput) error {
 	invts, err := ii.List()
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		if invts == nil {
+			invts = &[]models.Invite{}
+		}
+		return iout.Render(invts)
+	}
 	if invts == nil || len(*invts) == 0 {
 		logrus.Printf("There are no pending invites for %s", envName)
 		return nil
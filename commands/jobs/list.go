@@ -7,6 +7,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
@@ -25,7 +26,7 @@ func (jobs SortedJobs) Less(i, j int) bool {
 	return jobs[i].Type < jobs[j].Type
 }
 
-func CmdList(svcName string, ij IJobs, is services.IServices) error {
+func CmdList(svcName string, ij IJobs, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -39,6 +40,14 @@ func CmdList(svcName string, ij IJobs, is services.IServices) error {
 		return err
 	}
 
+	if iout.Structured() {
+		if jbs == nil {
+			jbs = &[]models.Job{}
+		}
+		sort.Sort(SortedJobs(*jbs))
+		return iout.Render(jbs)
+	}
+
 	if jbs == nil || len(*jbs) == 0 {
 		logrus.Println("No releases found")
 		return nil
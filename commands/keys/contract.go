@@ -4,6 +4,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/deploykeys"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -35,7 +36,7 @@ var AddSubCmd = models.Command{
 		"SSH keys added to your user account should be private and not shared with others. " +
 		"SSH keys can be used for authentication (as opposed to the traditional email and password) as well as pushing code to an environment's code services. " +
 		"Please note, you must specify the path to the public key file and not the private key. " +
-		"All SSH keys should be in either OpenSSH RSA format or PEM format. Here is a sample command\n\n" +
+		"RSA, ECDSA, and Ed25519 keys are supported. Here is a sample command\n\n" +
 		"<pre>\ndatica keys add my_prod_key ~/.ssh/prod_rsa.pub\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
@@ -66,7 +67,7 @@ var ListSubCmd = models.Command{
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdList(New(settings), deploykeys.New(settings))
+				err := CmdList(New(settings), deploykeys.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err)
 				}
@@ -88,7 +89,7 @@ var RemoveSubCmd = models.Command{
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdRemove(*name, settings.PrivateKeyPath, New(settings), deploykeys.New(settings))
+				err := CmdRemove(*name, settings.PrivateKeyPath, New(settings), prompts.New())
 				if err != nil {
 					logrus.Fatal(err)
 				}
@@ -103,6 +104,8 @@ var SetSubCmd = models.Command{
 	LongHelp: "<code>keys set</code> allows the CLI to use an SSH key for authentication instead of the traditional email and password combination. " +
 		"This can be useful for automation or where shared workstations are involved. " +
 		"Please note that you must pass in the path to the private key and not the public key. " +
+		"RSA, ECDSA, and Ed25519 keys in PEM or OpenSSH format are supported. " +
+		"If an ssh-agent running at <code>SSH_AUTH_SOCK</code> holds the key and the public key is at the same path with a <code>.pub</code> extension, the agent signs in for you and the private key file does not need to be readable. " +
 		"The given key must already be added to your account by using the keys add command. " +
 		"Here is a sample command\n\n" +
 		"<pre>\ndatica keys set ~/.ssh/my_key\n</pre>",
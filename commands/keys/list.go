@@ -8,21 +8,34 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/deploykeys"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdList(ik IKeys, id deploykeys.IDeployKeys) error {
+// keyOutput is the structured representation of a user's public key used when
+// a machine readable output format is selected.
+type keyOutput struct {
+	models.UserKey
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func CmdList(ik IKeys, id deploykeys.IDeployKeys, iout output.IOutput) error {
 	keys, err := ik.List()
 	if err != nil {
 		return err
 	}
 
-	if keys == nil || len(*keys) == 0 {
+	if keys == nil {
+		keys = &[]models.UserKey{}
+	}
+	if len(*keys) == 0 && !iout.Structured() {
 		logrus.Println("No keys found")
 		return nil
 	}
 
+	out := []keyOutput{}
 	invalidKeys := map[string]string{}
 
 	data := [][]string{{"NAME", "FINGERPRINT"}}
@@ -30,12 +43,18 @@ func CmdList(ik IKeys, id deploykeys.IDeployKeys) error {
 		s, err := id.ParsePublicKey([]byte(key.Key))
 		if err != nil {
 			invalidKeys[key.Name] = err.Error()
+			out = append(out, keyOutput{UserKey: key, Error: err.Error()})
 			continue
 		}
 		h := sha256.New()
 		h.Write(s.Marshal())
 		fingerprint := base64.StdEncoding.EncodeToString(h.Sum(nil))
-		data = append(data, []string{key.Name, fmt.Sprintf("SHA256:%s", strings.TrimRight(fingerprint, "="))})
+		fingerprint = fmt.Sprintf("SHA256:%s", strings.TrimRight(fingerprint, "="))
+		data = append(data, []string{key.Name, fingerprint})
+		out = append(out, keyOutput{UserKey: key, Fingerprint: fingerprint})
+	}
+	if iout.Structured() {
+		return iout.Render(out)
 	}
 
 	table := tablewriter.NewWriter(logrus.StandardLogger().Out)
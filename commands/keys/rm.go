@@ -3,28 +3,22 @@ package keys
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/daticahealth/cli/commands/deploykeys"
 	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/prompts"
+	"github.com/daticahealth/cli/lib/sshkeys"
 )
 
-func CmdRemove(name, privateKeyPath string, ik IKeys, id deploykeys.IDeployKeys) error {
+func CmdRemove(name, privateKeyPath string, ik IKeys, ip prompts.IPrompts) error {
 	if privateKeyPath != "" {
 		// if ssh key auth is being used, don't let the key used for key auth be removed
-		b, err := ioutil.ReadFile(privateKeyPath)
-		if err != nil {
-			return err
-		}
-		privKey, err := id.ParsePrivateKey(b)
-		if err != nil {
-			return err
-		}
-		pubKey, err := id.ExtractPublicKey(privKey)
+		pubKey, err := sshkeys.LoadPublicKey(privateKeyPath, func() string {
+			return ip.KeyPassphrase(privateKeyPath)
+		})
 		if err != nil {
 			return err
 		}
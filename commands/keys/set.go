@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/lib/auth"
@@ -22,21 +23,23 @@ func CmdSet(path string, settings *models.Settings) error {
 		return err
 	}
 
-	// make sure both files exist
-	_, err = os.Stat(fullPath + ".pub")
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("Public key file '%s' does not exist.", fullPath+".pub")
-		}
-		return err
-	}
+	fullPath = strings.TrimSuffix(fullPath, ".pub")
 
+	// the private key file is optional when an ssh-agent holds the key, the
+	// public key file is then used to find the key in the agent
+	_, pubErr := os.Stat(fullPath + ".pub")
+	if pubErr != nil && !os.IsNotExist(pubErr) {
+		return pubErr
+	}
 	_, err = os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("Private key file '%s' does not exist.", fullPath)
+			if os.IsNotExist(pubErr) {
+				return fmt.Errorf("Neither the private key file '%s' nor the public key file '%s' exist.", fullPath, fullPath+".pub")
+			}
+		} else {
+			return err
 		}
-		return err
 	}
 
 	settings.PrivateKeyPath = fullPath
@@ -1,8 +1,6 @@
 package logs
 
 import (
-	"time"
-
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
@@ -16,14 +14,22 @@ import (
 )
 
 type CMDLogQuery struct {
-	Query   string // default *
-	Follow  bool
-	Hours   int
-	Minutes int
-	Seconds int
-	Service string
-	JobID   string
-	Target  string
+	Query      string // default *
+	Follow     bool
+	Hours      int
+	Minutes    int
+	Seconds    int
+	Since      string
+	Until      string
+	Services   []string
+	JobID      string
+	Target     string
+	Host       string
+	Source     string
+	File       string
+	Level      string
+	Format     string
+	OutputFile string
 }
 
 // Cmd is the contract between the user and the CLI. This specifies the command
@@ -35,13 +41,25 @@ var Cmd = models.Command{
 		"If you do not see your logs, try adjusting the number of hours, minutes, or seconds of logs that are retrieved with the <code>--hours</code>, <code>--minutes</code>, and <code>--seconds</code> options respectively. " +
 		"To specify a specific service, job, or target use the '--service', '--job-id', and '--target' commands. " +
 		"You must specify a service to use '--job-id' or '--target', and you cannot specify both a job-id and a target at the same time. " +
+		"To see the logs of multiple services together, for example while following the logs during an incident, repeat the '--service' option. " +
+		"A target of a code service can be given as '--service=<service_name>:<target>'. " +
+		"The logs of all services are merged in timestamp order and every line is prefixed by the service, target, and job it came from. " +
+		"When printing to a terminal, the prefix is colored per service. " +
 		"You can also follow the logs with the <code>-f</code> option. " +
 		"When using <code>-f</code> all logs will be printed to the console within the given time frame as well as any new logs that are sent to the logging Dashboard for the duration of the command. " +
-		"When using the <code>-f</code> option, hit ctrl-c to stop. Here are some sample commands\n\n" +
+		"When using the <code>-f</code> option, hit ctrl-c to stop. " +
+		"Instead of a number of hours, minutes, or seconds before now, you can retrieve the logs between two points in time with the <code>--since</code> and <code>--until</code> options. " +
+		"These take a timestamp in RFC3339 format such as <code>2017-10-11T15:04:05Z</code>, or a date and time in UTC such as <code>2017-10-11 15:04:05</code> or <code>2017-10-11</code>. " +
+		"Logs can be narrowed down further by the host that sent them, their source, the file they were written to, and their level with the <code>--host</code>, <code>--source</code>, <code>--file</code>, and <code>--level</code> options. " +
+		"By default logs are printed as <code>timestamp - message</code>. " +
+		"To hand logs to other tools, use <code>--format ndjson</code> to write every log as a JSON document on its own line or <code>--format csv</code> to write them as CSV rows. " +
+		"These are written to the file given by <code>--output-file</code>, or to the console if no file is given. Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" logs --hours=6 --minutes=30\n" +
 		"datica -E \"<your_env_name>\" logs -f\n" +
 		"datica -E \"<your_env_name>\" logs --service=\"<your_service_name>\"\n" +
-		"datica -E \"<your_env_name>\" logs --service=\"<your_service_name>\" --job-id=\"<your_job_id>\"\n</pre>",
+		"datica -E \"<your_env_name>\" logs --service=\"<your_service_name>\" --job-id=\"<your_job_id>\"\n" +
+		"datica -E \"<your_env_name>\" logs -f --service=\"<your_service_name>\" --service=\"<your_service_name>:worker\" --service=service_proxy\n" +
+		"datica -E \"<your_env_name>\" logs \"*error*\" --since=\"2017-10-11 15:00:00\" --until=\"2017-10-11 16:00:00\" --level=error --format=ndjson --output-file=./incident.ndjson\n</pre>",
 	// TODO: add documentation here
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
@@ -51,9 +69,22 @@ var Cmd = models.Command{
 			hours := cmd.IntOpt("hours", 0, "The number of hours before now (in combination with minutes and seconds) to retrieve logs")
 			mins := cmd.IntOpt("minutes", 0, "The number of minutes before now (in combination with hours and seconds) to retrieve logs")
 			secs := cmd.IntOpt("seconds", 0, "The number of seconds before now (in combination with hours and minutes) to retrieve logs")
-			service := cmd.StringOpt("service", "", "Query logs for a specific service label")
+			serviceLabels := cmd.Strings(cli.StringsOpt{
+				Name:      "service",
+				Value:     []string{},
+				Desc:      "Query logs for a specific service label, or a target of a code service as <service>:<target>. Repeat to query multiple services together",
+				HideValue: true,
+			})
 			jobID := cmd.StringOpt("job-id", "", "Query logs for a particular job by id")
 			target := cmd.StringOpt("target", "", "Query logs for a particular procfile target")
+			since := cmd.StringOpt("since", "", "Retrieve logs from this point in time onwards (RFC3339 or UTC date and time)")
+			until := cmd.StringOpt("until", "", "Retrieve logs up to this point in time (RFC3339 or UTC date and time)")
+			host := cmd.StringOpt("host", "", "Query logs sent from a particular host")
+			source := cmd.StringOpt("source", "", "Query logs from a particular source instead of your application logs")
+			file := cmd.StringOpt("file", "", "Query logs written to a particular file")
+			level := cmd.StringOpt("level", "", "Query logs of a particular level")
+			format := cmd.StringOpt("format", FormatText, "The format to print logs in: text, ndjson, or csv")
+			outputFile := cmd.StringOpt("output-file", "", "The path of a file to write ndjson or csv logs to instead of the console")
 			cmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -62,32 +93,40 @@ var Cmd = models.Command{
 					logrus.Fatal(err.Error())
 				}
 				cmdQuery := CMDLogQuery{
-					Query:   *query,
-					Follow:  *follow || *tail,
-					Hours:   *hours,
-					Minutes: *mins,
-					Seconds: *secs,
-					Service: *service,
-					JobID:   *jobID,
-					Target:  *target,
+					Query:      *query,
+					Follow:     *follow || *tail,
+					Hours:      *hours,
+					Minutes:    *mins,
+					Seconds:    *secs,
+					Since:      *since,
+					Until:      *until,
+					Services:   *serviceLabels,
+					JobID:      *jobID,
+					Target:     *target,
+					Host:       *host,
+					Source:     *source,
+					File:       *file,
+					Level:      *level,
+					Format:     *format,
+					OutputFile: *outputFile,
 				}
 				err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, New(settings), prompts.New(), environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			cmd.Spec = "[QUERY] [(-f | -t)] [--hours] [--minutes] [--seconds] [--service [(--job-id | --target)]]"
+			cmd.Spec = "[QUERY] [(-f | -t)] [--hours] [--minutes] [--seconds] [--since] [--until] [--service... [(--job-id | --target)]] [--host] [--source] [--file] [--level] [--format] [--output-file]"
 		}
 	},
 }
 
-type queryGenerator func(queryString, appLogsIdentifier, appLogsValue string, timestamp time.Time, from int, hostNames []string, fileName string) ([]byte, error)
+type queryGenerator func(queryString, appLogsIdentifier, appLogsValue string, cursor *logCursor, filter *LogFilter) ([]byte, error)
 
 // ILogs ...
 type ILogs interface {
-	Output(queryString, domain string, generator queryGenerator, from int, startTimestamp time.Time, endTimestamp time.Time, hostNames []string, fileName string) (int, error)
+	Output(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error
 	RetrieveElasticsearchVersion(domain string) (string, error)
-	Stream(queryString, domain string, generator queryGenerator, from int, timestamp time.Time, hostNames []string, fileName string) error
+	Stream(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error
 	Watch(queryString, domain string) error
 }
 
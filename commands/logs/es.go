@@ -6,8 +6,62 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/daticahealth/cli/models"
 )
 
+// LogFilter narrows down a log query by time range and log fields. Fields left
+// empty are not filtered on and a zero Until leaves the time range open ended.
+type LogFilter struct {
+	Since time.Time
+	Until time.Time
+	// HostNames and FileNames are determined from the jobs of the queried
+	// services. A log matches if it was sent from any of the host names or
+	// written to any of the files.
+	HostNames []string
+	FileNames []string
+	// Host, File, and Level are given by the user and must all match. Source
+	// replaces the default source of application logs.
+	Host   string
+	Source string
+	File   string
+	Level  string
+}
+
+// logCursor is the position after the last log retrieved, from which the next
+// page of logs continues. ES5 continues with search_after using the sort values
+// of the last log. ES1 and ES2 do not support search_after, so Since is moved
+// up to the timestamp of the last log and Skip counts the logs already
+// retrieved with exactly that timestamp. Either way every page moves the
+// cursor forward, so paging always ends.
+type logCursor struct {
+	SearchAfter []interface{}
+	Since       time.Time
+	Skip        int
+}
+
+// advance moves the cursor past the given log.
+func (c *logCursor) advance(lh models.LogHits) {
+	c.SearchAfter = lh.Sort
+	// the range query only has millisecond precision
+	t, err := time.Parse(time.RFC3339Nano, getLogField(lh, "@timestamp"))
+	if t = t.Truncate(time.Millisecond); err == nil && t.After(c.Since) {
+		c.Since = t
+		c.Skip = 1
+	} else {
+		c.Skip++
+	}
+}
+
+// since returns the start of the time range for the next page when paging
+// without search_after.
+func (c *logCursor) since(filter *LogFilter) time.Time {
+	if c.Since.After(filter.Since) {
+		return c.Since
+	}
+	return filter.Since
+}
+
 func chooseQueryGenerator(version string) queryGenerator {
 	generator := generateES5Query
 	if strings.HasPrefix(version, "1.") {
@@ -18,16 +72,20 @@ func chooseQueryGenerator(version string) queryGenerator {
 	return generator
 }
 
-func generateES5Query(queryString, appLogsIdentifier, appLogsValue string, timestamp time.Time, from int, hostNames []string, fileName string) ([]byte, error) {
-	hostFilter, fileFilter := createFilters(hostNames, fileName)
+func generateES5Query(queryString, appLogsIdentifier, appLogsValue string, cursor *logCursor, filter *LogFilter) ([]byte, error) {
+	hostFilter, fieldFilters := createFilters(filter)
+	since := cursor.since(filter)
+	if len(cursor.SearchAfter) > 0 {
+		since = filter.Since
+	}
 	query := `{
-	"_source": ["@timestamp", "message", "` + appLogsIdentifier + `"],
+	"_source": ` + logFields(appLogsIdentifier) + `,
 	"query": {
 		"bool": {
 			"must": [
 				{"wildcard": {"message": "` + queryString + `"}},
-				{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},` + fileFilter + `
-				{"range": {"@timestamp": {"gt": "` + fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02dZ", timestamp.Year(), timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second()) + `"}}}
+				{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},` + fieldFilters + `
+				` + createRangeFilter(since, filter.Until) + `
 			]` + hostFilter + `
 		}
 	},
@@ -39,13 +97,11 @@ func generateES5Query(queryString, appLogsIdentifier, appLogsValue string, times
 			}
 		},
 		{
-			"message.raw": {
-				"order": "asc",
-				"unmapped_type":"boolean"
+			"_uid": {
+				"order": "asc"
 			}
 		}
-	],
-	"from": ` + fmt.Sprintf("%d", from) + `,
+	],` + createPaging(cursor) + `
 	"size": ` + fmt.Sprintf("%d", size) + `
 	}`
 	var buf bytes.Buffer
@@ -56,10 +112,10 @@ func generateES5Query(queryString, appLogsIdentifier, appLogsValue string, times
 	return buf.Bytes(), nil
 }
 
-func generateES2Query(queryString, appLogsIdentifier, appLogsValue string, timestamp time.Time, from int, hostNames []string, fileName string) ([]byte, error) {
-	hostFilter, fileFilter := createFilters(hostNames, fileName)
+func generateES2Query(queryString, appLogsIdentifier, appLogsValue string, cursor *logCursor, filter *LogFilter) ([]byte, error) {
+	hostFilter, fieldFilters := createFilters(filter)
 	query := `{
-	"fields": ["@timestamp", "message", "` + appLogsIdentifier + `"],
+	"fields": ` + logFields(appLogsIdentifier) + `,
 	"query": {
 		"wildcard": {
 			"message": "` + queryString + `"
@@ -69,9 +125,8 @@ func generateES2Query(queryString, appLogsIdentifier, appLogsValue string, times
 		"query": {
 			"bool": {
 				"must": [
-					{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},
-					` + fileFilter + `
-					{"range": {"@timestamp": {"gt": "` + fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02dZ", timestamp.Year(), timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second()) + `"}}}
+					{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},` + fieldFilters + `
+					` + createRangeFilter(cursor.since(filter), filter.Until) + `
 				]` + hostFilter + `
 			}
 		}
@@ -90,7 +145,7 @@ func generateES2Query(queryString, appLogsIdentifier, appLogsValue string, times
 			}
 		}
 	],
-	"from": ` + fmt.Sprintf("%d", from) + `,
+	"from": ` + fmt.Sprintf("%d", cursor.Skip) + `,
 	"size": ` + fmt.Sprintf("%d", size) + `
 	}`
 	var buf bytes.Buffer
@@ -101,10 +156,10 @@ func generateES2Query(queryString, appLogsIdentifier, appLogsValue string, times
 	return buf.Bytes(), nil
 }
 
-func generateES1Query(queryString, appLogsIdentifier, appLogsValue string, timestamp time.Time, from int, hostNames []string, fileName string) ([]byte, error) {
-	hostFilter, fileFilter := createFilters(hostNames, fileName)
+func generateES1Query(queryString, appLogsIdentifier, appLogsValue string, cursor *logCursor, filter *LogFilter) ([]byte, error) {
+	hostFilter, fieldFilters := createFilters(filter)
 	query := `{
-	"fields": ["@timestamp", "message", "` + appLogsIdentifier + `"],
+	"fields": ` + logFields(appLogsIdentifier) + `,
 	"query": {
 		"wildcard": {
 			"message": "` + queryString + `"
@@ -114,9 +169,8 @@ func generateES1Query(queryString, appLogsIdentifier, appLogsValue string, times
 		"query": {
 			"bool": {
 				"must": [
-					{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},
-					` + fileFilter + `
-					{"range": {"@timestamp": {"gt": "` + fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02dZ", timestamp.Year(), timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second()) + `"}}}
+					{"term": {"` + appLogsIdentifier + `": "` + appLogsValue + `"}},` + fieldFilters + `
+					` + createRangeFilter(cursor.since(filter), filter.Until) + `
 				]` + hostFilter + `
 			}
 		}
@@ -129,7 +183,7 @@ func generateES1Query(queryString, appLogsIdentifier, appLogsValue string, times
 			"order": "asc"
 		}
 	},
-	"from": ` + fmt.Sprintf("%d", from) + `,
+	"from": ` + fmt.Sprintf("%d", cursor.Skip) + `,
 	"size": ` + fmt.Sprintf("%d", size) + `
 	}`
 	var buf bytes.Buffer
@@ -137,27 +191,80 @@ func generateES1Query(queryString, appLogsIdentifier, appLogsValue string, times
 	return buf.Bytes(), nil
 }
 
-func createFilters(hostNames []string, fileName string) (string, string) {
-	var hostFilter string
-	var fileFilter string
-	if len(hostNames) > 0 {
-		formattedHostNames := make([]string, len(hostNames))
-		for i, hostName := range hostNames {
-			formattedHostNames[i] = fmt.Sprintf(`"%s"`, hostName)
+// logFields returns the JSON array of fields retrieved for every log. These are
+// the columns written when exporting logs.
+func logFields(appLogsIdentifier string) string {
+	fields := []string{"@timestamp", "message", "host", "source", "file", "level"}
+	found := false
+	for _, field := range fields {
+		if field == appLogsIdentifier {
+			found = true
 		}
+	}
+	if !found {
+		fields = append(fields, appLogsIdentifier)
+	}
+	b, _ := json.Marshal(fields)
+	return string(b)
+}
+
+// createPaging continues after the last retrieved log with search_after. If
+// there is no log to continue after, or it has no sort values, the cursor's
+// skip is used instead.
+func createPaging(cursor *logCursor) string {
+	if len(cursor.SearchAfter) > 0 {
+		b, _ := json.Marshal(cursor.SearchAfter)
+		return `
+	"search_after": ` + string(b) + `,`
+	}
+	return `
+	"from": ` + fmt.Sprintf("%d", cursor.Skip) + `,`
+}
+
+func createRangeFilter(since, until time.Time) string {
+	timeRange := `"gte": "` + formatTimestamp(since) + `"`
+	if !until.IsZero() {
+		timeRange += `, "lte": "` + formatTimestamp(until) + `"`
+	}
+	return `{"range": {"@timestamp": {` + timeRange + `}}}`
+}
+
+func formatTimestamp(timestamp time.Time) string {
+	return timestamp.In(time.UTC).Format("2006-01-02T15:04:05.000Z")
+}
+
+func createFilters(filter *LogFilter) (string, string) {
+	var hostFilter string
+	var fieldFilters string
+	if len(filter.HostNames) > 0 || len(filter.FileNames) > 0 {
 		hostFilter = `,
 			"should": [`
-		for _, hostName := range hostNames {
+		for _, hostName := range filter.HostNames {
 			hostFilter += `
 				{"match_phrase": {"host": "` + hostName + `"}},`
 		}
+		for _, fileName := range filter.FileNames {
+			hostFilter += matchPhrase("file", fileName)
+		}
 		hostFilter = strings.TrimSuffix(hostFilter, ",")
 		hostFilter += `
 			],
 			"minimum_should_match": 1`
-	} else if len(fileName) > 0 {
-		fileFilter += `
-		{"match_phrase": {"file": "` + fileName + `"}},`
 	}
-	return hostFilter, fileFilter
+	if len(filter.Host) > 0 {
+		fieldFilters += matchPhrase("host", filter.Host)
+	}
+	if len(filter.File) > 0 {
+		fieldFilters += matchPhrase("file", filter.File)
+	}
+	if len(filter.Level) > 0 {
+		fieldFilters += matchPhrase("level", filter.Level)
+	}
+	return hostFilter, fieldFilters
+}
+
+func matchPhrase(field, value string) string {
+	b, _ := json.Marshal(value)
+	return `
+		{"match_phrase": {"` + field + `": ` + string(b) + `}},`
 }
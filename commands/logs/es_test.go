@@ -0,0 +1,212 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daticahealth/cli/lib/httpclient"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+func TestQueryFilters(t *testing.T) {
+	since := time.Date(2017, 10, 11, 15, 0, 0, 0, time.UTC)
+	filter := &LogFilter{
+		Since:     since,
+		Until:     since.Add(time.Hour),
+		HostNames: []string{"code-1-abcdef"},
+		Host:      "code-1-abcdef",
+		File:      "/data/log/app/code_1/current",
+		Level:     "error",
+	}
+	for version, generator := range map[string]queryGenerator{"1.7": generateES1Query, "2.4": generateES2Query, "5.6": generateES5Query} {
+		queryBytes, err := generator("*", "source", "app", &logCursor{}, filter)
+		if err != nil {
+			t.Fatalf("Unexpected error generating an ES %s query: %s", version, err)
+		}
+		var query map[string]interface{}
+		if err = json.Unmarshal(queryBytes, &query); err != nil {
+			t.Fatalf("Expected the ES %s query to be valid JSON but got %s: %s", version, err, string(queryBytes))
+		}
+		query2 := string(queryBytes)
+		expected := []string{
+			`{"range":{"@timestamp":{"gte":"2017-10-11T15:00:00.000Z","lte":"2017-10-11T16:00:00.000Z"}}}`,
+			`{"match_phrase":{"host":"code-1-abcdef"}}`,
+			`{"match_phrase":{"file":"/data/log/app/code_1/current"}}`,
+			`{"match_phrase":{"level":"error"}}`,
+			`"minimum_should_match":1`,
+			`["@timestamp","message","host","source","file","level"]`,
+		}
+		for _, e := range expected {
+			if !strings.Contains(query2, e) {
+				t.Errorf("Expected the ES %s query to contain %s but got %s", version, e, query2)
+			}
+		}
+	}
+
+	// an open ended range without user filters
+	queryBytes, _ := generateES5Query("*", "syslog_program", "supervisord", &logCursor{}, &LogFilter{Since: since})
+	query := string(queryBytes)
+	if !strings.Contains(query, `{"range":{"@timestamp":{"gte":"2017-10-11T15:00:00.000Z"}}}`) || strings.Contains(query, "match_phrase") {
+		t.Errorf("Expected only a time range filter but got %s", query)
+	}
+	if !strings.Contains(query, `"level","syslog_program"]`) {
+		t.Errorf("Expected the app logs identifier to be retrieved but got %s", query)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2017, 10, 11, 15, 4, 5, 0, time.UTC)
+	for _, value := range []string{"2017-10-11T15:04:05Z", "2017-10-11T17:04:05+02:00", "2017-10-11T15:04:05", "2017-10-11 15:04:05"} {
+		timestamp, err := parseTimestamp(value)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %s", value, err)
+		} else if !timestamp.Equal(expected) {
+			t.Errorf("Expected %s to be parsed as %s but got %s", value, expected, timestamp)
+		}
+	}
+	if timestamp, err := parseTimestamp("2017-10-11"); err != nil || !timestamp.Equal(time.Date(2017, 10, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a date to be parsed as midnight UTC but got %s (%v)", timestamp, err)
+	}
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Errorf("Expected an error parsing an invalid timestamp")
+	}
+}
+
+func TestCursorPaging(t *testing.T) {
+	since := time.Date(2017, 10, 11, 15, 0, 0, 0, time.UTC)
+	filter := &LogFilter{Since: since}
+
+	// without sort values the cursor moves the range up and skips the logs
+	// already retrieved with the same timestamp
+	cursor := &logCursor{}
+	cursor.advance(models.LogHits{Fields: map[string][]string{"@timestamp": {"2017-10-11T15:04:05.123456Z"}}})
+	cursor.advance(models.LogHits{Fields: map[string][]string{"@timestamp": {"2017-10-11T15:04:05.123Z"}}})
+	queryBytes, _ := generateES2Query("*", "source", "app", cursor, filter)
+	query := string(queryBytes)
+	if !strings.Contains(query, `"gte":"2017-10-11T15:04:05.123Z"`) || !strings.Contains(query, `"from":2`) {
+		t.Errorf("Expected the query to continue after 2 logs at 15:04:05.123 but got %s", query)
+	}
+	// unparseable timestamps still move the cursor forward
+	cursor.advance(models.LogHits{})
+	if cursor.Skip != 3 {
+		t.Errorf("Expected to skip 3 logs but got %d", cursor.Skip)
+	}
+
+	// with sort values ES5 continues with search_after
+	cursor.advance(models.LogHits{Sort: []interface{}{float64(1507734245123), "logs#AV1"}, Source: map[string]string{"@timestamp": "2017-10-11T15:04:05.123Z"}})
+	queryBytes, _ = generateES5Query("*", "source", "app", cursor, filter)
+	query = string(queryBytes)
+	if !strings.Contains(query, `"search_after":[1507734245123,"logs#AV1"]`) || strings.Contains(query, `"from"`) || !strings.Contains(query, `"gte":"2017-10-11T15:00:00.000Z"`) {
+		t.Errorf("Expected the query to continue with search_after but got %s", query)
+	}
+}
+
+// esLog is a log stored by the fake Elasticsearch server
+type esLog struct {
+	ID        string
+	Timestamp time.Time
+}
+
+// newFakeES starts a TLS server that pages through the given logs, sorted by
+// timestamp and ID, the way Elasticsearch does for the generated queries.
+func newFakeES(t *testing.T, logs []esLog, searches *int) *httptest.Server {
+	gteRegex := regexp.MustCompile(`"gte":"([^"]+)"`)
+	fromRegex := regexp.MustCompile(`"from":(\d+)`)
+	searchAfterRegex := regexp.MustCompile(`"search_after":\[(\d+),"([^"]+)"\]`)
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*searches++
+		body, _ := ioutil.ReadAll(r.Body)
+		gte, err := time.Parse(time.RFC3339Nano, gteRegex.FindStringSubmatch(string(body))[1])
+		if err != nil {
+			t.Fatalf("Invalid range in query %s", string(body))
+		}
+		from := 0
+		if m := fromRegex.FindStringSubmatch(string(body)); m != nil {
+			from, _ = strconv.Atoi(m[1])
+		}
+		var afterMillis int64 = -1
+		afterID := ""
+		if m := searchAfterRegex.FindStringSubmatch(string(body)); m != nil {
+			afterMillis, _ = strconv.ParseInt(m[1], 10, 64)
+			afterID = m[2]
+		}
+		hits := []models.LogHits{}
+		for _, l := range logs {
+			millis := l.Timestamp.UnixNano() / int64(time.Millisecond)
+			if l.Timestamp.Before(gte) || millis < afterMillis || (millis == afterMillis && l.ID <= afterID) {
+				continue
+			}
+			if from > 0 {
+				from--
+				continue
+			}
+			if len(hits) == size {
+				break
+			}
+			hits = append(hits, models.LogHits{
+				ID:     l.ID,
+				Source: map[string]string{"@timestamp": l.Timestamp.Format(time.RFC3339Nano), "message": l.ID},
+				Sort:   []interface{}{millis, l.ID},
+			})
+		}
+		b, _ := json.Marshal(models.Logs{Hits: &models.Hits{Hits: &hits}})
+		w.Write(b)
+	}))
+}
+
+func TestOutputPaging(t *testing.T) {
+	since := time.Date(2017, 10, 11, 15, 0, 0, 0, time.UTC)
+	// more logs than fit on a page share the same timestamp
+	logs := []esLog{}
+	for i := 0; i < size*3; i++ {
+		timestamp := since.Add(time.Second)
+		if i >= size*2 {
+			timestamp = timestamp.Add(time.Duration(i) * time.Millisecond)
+		}
+		logs = append(logs, esLog{ID: fmt.Sprintf("log#%05d", i), Timestamp: timestamp})
+	}
+	for version, generator := range map[string]queryGenerator{"2.4": generateES2Query, "5.6": generateES5Query} {
+		searches := 0
+		server := newFakeES(t, logs, &searches)
+		settings := test.GetSettings("")
+		httpManager, err := httpclient.NewTLSHTTPManager(true, httpclient.RetryPolicy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings.HTTPManager = httpManager
+		var out bytes.Buffer
+
+		// test
+		err = New(settings).Output("*", strings.TrimPrefix(server.URL, "https://"), generator, &logCursor{}, &LogFilter{Since: since}, &ndjsonLogWriter{Writer: &out})
+		server.Close()
+
+		// assert
+		if err != nil {
+			t.Fatalf("Unexpected error paging ES %s logs: %s", version, err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(logs) {
+			t.Fatalf("Expected %d ES %s logs but got %d", len(logs), version, len(lines))
+		}
+		for i, line := range lines {
+			var lh models.LogHits
+			json.Unmarshal([]byte(line), &lh)
+			if lh.ID != logs[i].ID {
+				t.Fatalf("Expected ES %s log %d to be %s but got %s", version, i, logs[i].ID, lh.ID)
+			}
+		}
+		if searches != 4 {
+			t.Errorf("Expected ES %s logs to be retrieved in 4 searches but took %d", version, searches)
+		}
+	}
+}
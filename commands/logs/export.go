@@ -0,0 +1,163 @@
+package logs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/models"
+)
+
+// The formats logs can be printed or exported in
+const (
+	FormatText   = "text"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// csvColumns are the log fields written to each row of a CSV export
+var csvColumns = []string{"@timestamp", "host", "source", "file", "level", "message"}
+
+// logWriter writes the logs retrieved by a query
+type logWriter interface {
+	Write(lh models.LogHits) error
+	Close() error
+}
+
+// newLogWriter returns the logWriter for the given format. Text is printed to
+// the console while the other formats are written to w.
+func newLogWriter(format string, w io.Writer) (logWriter, error) {
+	switch format {
+	case "", FormatText:
+		return &textLogWriter{}, nil
+	case FormatNDJSON:
+		return &ndjsonLogWriter{Writer: w}, nil
+	case FormatCSV:
+		return &csvLogWriter{Writer: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("Invalid log format \"%s\". Supported formats are %s, %s, and %s", format, FormatText, FormatNDJSON, FormatCSV)
+}
+
+// logColors are the ANSI colors used to tell the logs of each service apart
+var logColors = []string{"36", "33", "35", "32", "34", "31"}
+
+// textLogWriter prints logs as "timestamp - message". When the logs of multiple
+// services are queried, each log is prefixed with the service, target, and job
+// it came from.
+type textLogWriter struct {
+	sources []logSource
+	color   bool
+	width   int
+}
+
+// prefixWith prefixes every log with the source it came from, colored per
+// source if color is true.
+func (t *textLogWriter) prefixWith(sources []logSource, color bool) {
+	t.sources = sources
+	t.color = color
+	for _, source := range sources {
+		if len(source.Label) > t.width {
+			t.width = len(source.Label)
+		}
+		for _, prefix := range source.Prefixes {
+			if len(prefix) > t.width {
+				t.width = len(prefix)
+			}
+		}
+	}
+}
+
+func (t *textLogWriter) Write(lh models.LogHits) error {
+	timestamp, message := getLogData(lh)
+	if len(timestamp) == 0 || len(message) == 0 {
+		return nil
+	}
+	if len(t.sources) == 0 {
+		logrus.Printf("%s - %s", timestamp, message)
+		return nil
+	}
+	prefix, index := t.prefix(lh)
+	if t.color && index >= 0 {
+		logrus.Printf("\033[%sm%-*s\033[0m %s - %s", logColors[index%len(logColors)], t.width, prefix, timestamp, message)
+	} else {
+		logrus.Printf("%-*s %s - %s", t.width, prefix, timestamp, message)
+	}
+	return nil
+}
+
+// prefix returns the prefix of a log and the index of the source it came from,
+// or -1 if it does not belong to any of the sources.
+func (t *textLogWriter) prefix(lh models.LogHits) (string, int) {
+	host := getLogField(lh, "host")
+	file := getLogField(lh, "file")
+	for i, source := range t.sources {
+		if prefix, ok := source.Prefixes[host]; ok {
+			return prefix, i
+		}
+		if len(source.FileName) > 0 && source.FileName == file {
+			return source.Label, i
+		}
+	}
+	if len(host) > 0 {
+		return host, -1
+	}
+	return "-", -1
+}
+
+func (t *textLogWriter) Close() error {
+	return nil
+}
+
+// ndjsonLogWriter writes each log document as a single line of JSON
+type ndjsonLogWriter struct {
+	Writer io.Writer
+}
+
+func (n *ndjsonLogWriter) Write(lh models.LogHits) error {
+	b, err := json.Marshal(lh)
+	if err != nil {
+		return err
+	}
+	_, err = n.Writer.Write(append(b, '\n'))
+	return err
+}
+
+func (n *ndjsonLogWriter) Close() error {
+	return nil
+}
+
+// csvLogWriter writes each log as a row of csvColumns preceded by a header row
+type csvLogWriter struct {
+	Writer      *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvLogWriter) Write(lh models.LogHits) error {
+	if !c.wroteHeader {
+		if err := c.Writer.Write(csvColumns); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	row := make([]string, len(csvColumns))
+	for i, column := range csvColumns {
+		row[i] = getLogField(lh, column)
+	}
+	return c.Writer.Write(row)
+}
+
+func (c *csvLogWriter) Close() error {
+	c.Writer.Flush()
+	return c.Writer.Error()
+}
+
+// getLogField returns the value of a field regardless of whether it was
+// retrieved through "fields" (ES1 and ES2) or "_source" (ES5).
+func getLogField(lh models.LogHits, field string) string {
+	if values, ok := lh.Fields[field]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return lh.Source[field]
+}
@@ -3,8 +3,11 @@ package logs
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
@@ -16,9 +19,10 @@ import (
 	"github.com/daticahealth/cli/lib/jobs"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
-const size = 50
+const size = 500
 const hostNameFeatureReleaseDate = "2017-09-23T00:00:00.0Z07:00"
 
 type esVersion struct {
@@ -35,108 +39,88 @@ func CmdLogs(query *CMDLogQuery, envID string, settings *models.Settings, il ILo
 	if query.Follow && (query.Hours > 0 || query.Minutes > 0 || query.Seconds > 0) {
 		return fmt.Errorf("Specifying \"-f\" in combination with \"--hours\", \"--minutes\", or \"--seconds\" is unsupported.")
 	}
+	if query.Follow && len(query.Until) > 0 {
+		return fmt.Errorf("Specifying \"-f\" in combination with \"--until\" is unsupported.")
+	}
+	if (len(query.Since) > 0 || len(query.Until) > 0) && (query.Hours > 0 || query.Minutes > 0 || query.Seconds > 0) {
+		return fmt.Errorf("Specifying \"--since\" or \"--until\" in combination with \"--hours\", \"--minutes\", or \"--seconds\" is unsupported.")
+	}
+	offset := time.Duration(query.Hours)*time.Hour + time.Duration(query.Minutes)*time.Minute + time.Duration(query.Seconds)*time.Second
+	filter := LogFilter{
+		Since:  time.Now().In(time.UTC).Add(-1 * offset),
+		Host:   query.Host,
+		Source: query.Source,
+		File:   query.File,
+		Level:  query.Level,
+	}
+	if len(query.Since) > 0 {
+		since, err := parseTimestamp(query.Since)
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+	if len(query.Until) > 0 {
+		until, err := parseTimestamp(query.Until)
+		if err != nil {
+			return err
+		}
+		if !until.After(filter.Since) {
+			return fmt.Errorf("The time given by \"--until\" must be after the start of the logs to retrieve.")
+		}
+		filter.Until = until
+	}
+	if len(query.OutputFile) > 0 && (query.Format == "" || query.Format == FormatText) {
+		return fmt.Errorf("Specifying \"--output-file\" requires a \"--format\" of %s or %s.", FormatNDJSON, FormatCSV)
+	}
 	if len(query.JobID) > 0 && len(query.Target) > 0 {
 		return fmt.Errorf("Specifying \"--job-id\" in combination with \"--target\" is unsupported.")
 	}
-	if len(query.JobID) > 0 && len(query.Service) == 0 {
+	if len(query.JobID) > 0 && len(query.Services) == 0 {
 		return fmt.Errorf("You must specify a service to query the logs for a particular job.")
 	}
-	if len(query.Target) > 0 && len(query.Service) == 0 {
+	if len(query.Target) > 0 && len(query.Services) == 0 {
 		return fmt.Errorf("You must specify a code service to query the logs for a particular target")
 	}
-	var hostNames []string
-	var fileName string
-	isServiceQuery := false
-
-	if len(query.Service) > 0 {
-		isServiceQuery = true
-		svc, err := is.RetrieveByLabel(query.Service)
+	if (len(query.JobID) > 0 || len(query.Target) > 0) && len(query.Services) > 1 {
+		return fmt.Errorf("Specifying \"--job-id\" or \"--target\" is only supported for a single service. Use \"--service=<service_name>:<target>\" to query the logs for a target of each service.")
+	}
+	var logSources []logSource
+	for _, service := range query.Services {
+		svcLabel, target := service, query.Target
+		if pieces := strings.SplitN(service, ":", 2); len(pieces) == 2 {
+			if len(query.Target) > 0 {
+				return fmt.Errorf("Specifying \"--target\" in combination with \"--service=%s\" is unsupported.", service)
+			}
+			svcLabel, target = pieces[0], pieces[1]
+		}
+		source, err := resolveLogSource(svcLabel, query.JobID, target, ip, is, ij)
 		if err != nil {
 			return err
 		}
-		if svc == nil {
-			return fmt.Errorf("Cannot find the specified service \"%s\".", query.Service)
+		filter.HostNames = append(filter.HostNames, source.HostNames...)
+		if len(source.FileName) > 0 {
+			filter.FileNames = append(filter.FileNames, source.FileName)
 		}
-
-		var jobs []models.Job
-		if len(query.JobID) > 0 {
-			job, err := ij.Retrieve(query.JobID, svc.ID, false)
-			if err != nil {
-				return err
-			}
-			if job == nil || job.ID != query.JobID {
-				return fmt.Errorf("Cannot find the specified job \"%s\".", query.JobID)
-			}
-			jobs = append(jobs, *job)
-		} else if len(query.Target) > 0 {
-			if svc.Type != "code" {
-				return fmt.Errorf("Cannot specifiy a target for a non-code service type")
-			}
-			jobsPointer, err := ij.RetrieveByTarget(svc.ID, query.Target, 1, 25)
-			if err != nil {
-				return err
-			}
-			jobs = *jobsPointer
-			if jobs == nil || len(jobs) == 0 {
-				return fmt.Errorf("Cannot find any jobs with target \"%s\" for service \"%s\"", query.Target, svc.ID)
-			}
-		} else {
-			//TODO: Make better retrieve function? May need to create a new podAPI route for this, or edit the current one
-			deployJobs, err := ij.RetrieveByType(svc.ID, "deploy", 1, 25)
-			if err != nil {
-				return err
-			}
-			workerJobs, err := ij.RetrieveByType(svc.ID, "worker", 1, 25)
-			if err != nil {
-				return err
-			}
-			jobs = append(*deployJobs, *workerJobs...)
-			if jobs == nil || len(jobs) == 0 {
-				return fmt.Errorf("Cannot find any jobs for service \"%s\"", svc.ID)
-			}
-		}
-
-		var validJobs []models.Job
-		badCount := 0
-		for _, job := range jobs {
-			if job.CreatedAt >= hostNameFeatureReleaseDate {
-				validJobs = append(validJobs, job)
-			} else {
-				badCount++
-			}
-		}
-
-		if badCount > 0 {
-			totalJobs := len(jobs)
-			if svc.Type != "code" {
-				return fmt.Errorf("\"%s\" was deployed before service logging was added. If you would like to use this functionality, please redeploy the service", svc.Label)
-			} else if len(query.JobID) == 0 && len(query.Target) == 0 {
-				reg, err := regexp.Compile("[^a-zA-Z0-9]+")
-				if err != nil {
-					return err
-				}
-				fileName = "/data/log/app/" + reg.ReplaceAllString(svc.Label, "_") + "/current"
-			} else {
-				targetString := ""
-				if len(query.Target) > 0 {
-					targetString = fmt.Sprintf(` that have a target of "%s"`, query.Target)
-				}
-
-				if badCount < totalJobs { //NOTE: This code path will most likely never be reached. Either all jobs should have valid hostnames, or none of them will
-					defer logrus.Println("To view logs for all jobs, please redeploy the service.")
-					prompt := fmt.Sprintf("Of the %d jobs for the service \"%s\"%s %d do not have a valid hostname to allow their logs to be queried. Would you like to proceed anyways?", totalJobs, query.Service, targetString, badCount)
-					err := ip.YesNo("(y/n)", prompt)
-					if err != nil {
-						return err
-					}
-					hostNames = buildHostNames(jobs, svc.Label)
-				} else {
-					return fmt.Errorf(`All %d jobs for the service "%s"%s do not have valid hostnames to allow their logs to be queried. Redeploy the service if you would like to use this functionality.`, totalJobs, query.Service, targetString)
-				}
-			}
-		} else {
-			hostNames = buildHostNames(jobs, svc.Label)
+		logSources = append(logSources, *source)
+	}
+	// the file is only truncated once the query is known to be valid
+	var out io.Writer = logrus.StandardLogger().Out
+	if len(query.OutputFile) > 0 {
+		f, err := os.OpenFile(query.OutputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
 		}
+		defer f.Close()
+		out = f
+	}
+	lw, err := newLogWriter(query.Format, out)
+	if err != nil {
+		return err
+	}
+	defer lw.Close()
+	if tw, ok := lw.(*textLogWriter); ok && len(logSources) > 1 {
+		tw.prefixWith(logSources, runtime.GOOS != "windows" && terminal.IsTerminal(int(os.Stdout.Fd())))
 	}
 
 	env, err := ie.Retrieve(envID)
@@ -166,26 +150,46 @@ func CmdLogs(query *CMDLogQuery, envID string, settings *models.Settings, il ILo
 		version = ""
 	}
 	generator := chooseQueryGenerator(version)
-	if query.Follow && !isServiceQuery {
+	_, isText := lw.(*textLogWriter)
+	isFiltered := len(query.Since) > 0 || len(filter.Host) > 0 || len(filter.Source) > 0 || len(filter.File) > 0 || len(filter.Level) > 0
+	// logwatch streams every new log as text, so it can only be used when
+	// nothing needs to be filtered or exported
+	if query.Follow && len(logSources) == 0 && isText && !isFiltered {
 		if err = il.Watch(query.Query, domain); err != nil {
 			logrus.Debugf("Error attempting to stream logs from logwatch: %s", err)
 		} else {
 			return nil
 		}
 	}
-	from := 0
-	offset := time.Duration(query.Hours)*time.Hour + time.Duration(query.Minutes)*time.Minute + time.Duration(query.Seconds)*time.Second
-	timestamp := time.Now().In(time.UTC).Add(-1 * offset)
-	from, err = il.Output(query.Query, domain, generator, from, timestamp, time.Now(), hostNames, fileName)
-	if err != nil {
+	cursor := &logCursor{}
+	if err = il.Output(query.Query, domain, generator, cursor, &filter, lw); err != nil {
 		return err
 	}
 	if query.Follow {
-		return il.Stream(query.Query, domain, generator, from, timestamp, hostNames, fileName)
+		return il.Stream(query.Query, domain, generator, cursor, &filter, lw)
 	}
 	return nil
 }
 
+// timestampLayouts are the accepted formats of the --since and --until
+// options. Layouts without a time zone are parsed as UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp.In(time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid timestamp \"%s\". Timestamps must be in RFC3339 format such as 2017-10-11T15:04:05Z, or a date and time in UTC such as \"2017-10-11 15:04:05\" or 2017-10-11.", value)
+}
+
 func (l *SLogs) RetrieveElasticsearchVersion(domain string) (string, error) {
 	headers := map[string][]string{"Cookie": {"sessionToken=" + url.QueryEscape(l.Settings.SessionToken)}}
 	resp, statusCode, err := l.Settings.HTTPManager.Get(nil, fmt.Sprintf("https://%s/__es/", domain), headers)
@@ -202,69 +206,183 @@ func (l *SLogs) RetrieveElasticsearchVersion(domain string) (string, error) {
 	return wrapper.Version.Number, nil
 }
 
-func (l *SLogs) Output(queryString, domain string, generator queryGenerator, from int, startTimestamp, endTimestamp time.Time, hostNames []string, fileName string) (int, error) {
+// Output retrieves and writes the logs after the given cursor page by page
+// until there are no more logs or the end of the time range has been reached.
+// The cursor is moved past every log written.
+func (l *SLogs) Output(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error {
 	appLogsIdentifier := "source"
 	appLogsValue := "app"
 	if strings.HasPrefix(domain, "csb01") {
 		appLogsIdentifier = "syslog_program"
 		appLogsValue = "supervisord"
 	}
+	if len(filter.Source) > 0 {
+		appLogsValue = filter.Source
+	}
+	endTimestamp := filter.Until
+	if endTimestamp.IsZero() {
+		endTimestamp = time.Now()
+	}
 
 	urlString := fmt.Sprintf("https://%s/__es/logstash-*", domain)
 
 	headers := map[string][]string{"Cookie": {"sessionToken=" + url.QueryEscape(l.Settings.SessionToken)}}
 
-	logrus.Println("        @timestamp       -        message")
+	if _, ok := lw.(*textLogWriter); ok {
+		logrus.Println("        @timestamp       -        message")
+	}
 	for {
-		queryBytes, err := generator(queryString, appLogsIdentifier, appLogsValue, startTimestamp, from, hostNames, fileName)
+		queryBytes, err := generator(queryString, appLogsIdentifier, appLogsValue, cursor, filter)
 		if err != nil {
-			return -1, fmt.Errorf("Error generating query: %s", err)
+			return fmt.Errorf("Error generating query: %s", err)
 		} else if queryBytes == nil || len(queryBytes) == 0 {
-			return -1, errors.New("Error generating query")
+			return errors.New("Error generating query")
 		}
 
 		resp, statusCode, err := l.Settings.HTTPManager.Get(queryBytes, fmt.Sprintf("%s/_search", urlString), headers)
 		if err != nil {
-			return from, err
+			return err
 		}
 		var logs models.Logs
 		err = l.Settings.HTTPManager.ConvertResp(resp, statusCode, &logs)
 		if err != nil {
-			return from, err
+			return err
+		}
+		if logs.Hits == nil || logs.Hits.Hits == nil {
+			break
 		}
 
-		end := time.Time{}
 		for _, lh := range *logs.Hits.Hits {
-			timestamp, message := getLogData(lh)
-			if len(timestamp) != 0 && len(message) != 0 { // QUESTION: Do we care if the timestamp is missing? Would that ever happen?
-				logrus.Printf("%s - %s", timestamp, message)
-				end, _ = time.Parse(time.RFC3339Nano, timestamp)
+			if err = lw.Write(lh); err != nil {
+				return err
 			}
+			cursor.advance(lh)
 		}
-		amount := len(*logs.Hits.Hits)
-
-		from += len(*logs.Hits.Hits)
-		// TODO this will infinite loop if it always retrieves `size` hits
-		// and it fails to parse the end timestamp. very small window of opportunity.
-		if amount < size || end.After(endTimestamp) {
+		if len(*logs.Hits.Hits) < size || cursor.Since.After(endTimestamp) {
 			break
 		}
-		time.Sleep(config.JobPollTime * time.Second)
 	}
-	return from, nil
+	return nil
 }
 
-func (l *SLogs) Stream(queryString, domain string, generator queryGenerator, from int, timestamp time.Time, hostNames []string, fileName string) error {
+// Stream polls for new logs after the given cursor until interrupted.
+func (l *SLogs) Stream(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error {
 	for {
-		f, err := l.Output(queryString, domain, generator, from, timestamp, time.Now(), hostNames, fileName)
-		if err != nil {
+		if err := l.Output(queryString, domain, generator, cursor, filter, lw); err != nil {
 			return err
 		}
-		from = f
 		time.Sleep(config.LogPollTime * time.Second)
 	}
 }
 
+// logSource is a service, or a target of a code service, whose logs are
+// queried. Logs are matched either by the host names of its jobs or, for code
+// services deployed before service logging was added, by its log file.
+type logSource struct {
+	Label     string
+	HostNames []string
+	FileName  string
+	// Prefixes maps each host name to the service, target, and job it belongs to
+	Prefixes map[string]string
+}
+
+// resolveLogSource finds the jobs of a service whose logs are queried, either
+// a single job, the jobs of a target, or all deploy and worker jobs.
+func resolveLogSource(svcLabel, jobID, target string, ip prompts.IPrompts, is services.IServices, ij jobs.IJobs) (*logSource, error) {
+	svc, err := is.RetrieveByLabel(svcLabel)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("Cannot find the specified service \"%s\".", svcLabel)
+	}
+	source := &logSource{Label: svc.Label, Prefixes: map[string]string{}}
+	if len(target) > 0 {
+		source.Label = fmt.Sprintf("%s/%s", svc.Label, target)
+	}
+
+	var jobs []models.Job
+	if len(jobID) > 0 {
+		job, err := ij.Retrieve(jobID, svc.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil || job.ID != jobID {
+			return nil, fmt.Errorf("Cannot find the specified job \"%s\".", jobID)
+		}
+		jobs = append(jobs, *job)
+	} else if len(target) > 0 {
+		if svc.Type != "code" {
+			return nil, fmt.Errorf("Cannot specifiy a target for a non-code service type")
+		}
+		jobsPointer, err := ij.RetrieveByTarget(svc.ID, target, 1, 25)
+		if err != nil {
+			return nil, err
+		}
+		jobs = *jobsPointer
+		if jobs == nil || len(jobs) == 0 {
+			return nil, fmt.Errorf("Cannot find any jobs with target \"%s\" for service \"%s\"", target, svc.ID)
+		}
+	} else {
+		//TODO: Make better retrieve function? May need to create a new podAPI route for this, or edit the current one
+		deployJobs, err := ij.RetrieveByType(svc.ID, "deploy", 1, 25)
+		if err != nil {
+			return nil, err
+		}
+		workerJobs, err := ij.RetrieveByType(svc.ID, "worker", 1, 25)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(*deployJobs, *workerJobs...)
+		if jobs == nil || len(jobs) == 0 {
+			return nil, fmt.Errorf("Cannot find any jobs for service \"%s\"", svc.ID)
+		}
+	}
+
+	badCount := 0
+	for _, job := range jobs {
+		if job.CreatedAt < hostNameFeatureReleaseDate {
+			badCount++
+		}
+	}
+
+	if badCount > 0 {
+		totalJobs := len(jobs)
+		if svc.Type != "code" {
+			return nil, fmt.Errorf("\"%s\" was deployed before service logging was added. If you would like to use this functionality, please redeploy the service", svc.Label)
+		} else if len(jobID) == 0 && len(target) == 0 {
+			reg, err := regexp.Compile("[^a-zA-Z0-9]+")
+			if err != nil {
+				return nil, err
+			}
+			source.FileName = "/data/log/app/" + reg.ReplaceAllString(svc.Label, "_") + "/current"
+			return source, nil
+		}
+		targetString := ""
+		if len(target) > 0 {
+			targetString = fmt.Sprintf(` that have a target of "%s"`, target)
+		}
+		if badCount == totalJobs {
+			return nil, fmt.Errorf(`All %d jobs for the service "%s"%s do not have valid hostnames to allow their logs to be queried. Redeploy the service if you would like to use this functionality.`, totalJobs, svcLabel, targetString)
+		}
+		//NOTE: This code path will most likely never be reached. Either all jobs should have valid hostnames, or none of them will
+		prompt := fmt.Sprintf("Of the %d jobs for the service \"%s\"%s %d do not have a valid hostname to allow their logs to be queried. Would you like to proceed anyways?", totalJobs, svcLabel, targetString, badCount)
+		if err := ip.YesNo("(y/n)", prompt); err != nil {
+			return nil, err
+		}
+		logrus.Println("To view logs for all jobs, please redeploy the service.")
+	}
+	source.HostNames = buildHostNames(jobs, svc.Label)
+	for i, job := range jobs {
+		if job.Type == "deploy" {
+			source.Prefixes[source.HostNames[i]] = fmt.Sprintf("%s/%s", svc.Label, job.ID[:6])
+		} else {
+			source.Prefixes[source.HostNames[i]] = fmt.Sprintf("%s/%s/%s", svc.Label, job.Target, job.ID[:6])
+		}
+	}
+	return source, nil
+}
+
 func buildHostNames(jobs []models.Job, serviceLabel string) []string {
 	var hostNames []string
 	for _, job := range jobs {
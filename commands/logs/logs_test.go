@@ -1,9 +1,12 @@
 package logs
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -20,13 +23,15 @@ import (
 
 type SLogsMock struct {
 	Settings *models.Settings
+	Filter   *LogFilter
 }
 
 func (l *SLogsMock) RetrieveElasticsearchVersion(domain string) (string, error) {
 	return "5", nil
 }
 
-func (l *SLogsMock) Output(queryString, domain string, generator queryGenerator, from int, startTimestamp, endTimestamp time.Time, hostNames []string, fileName string) (int, error) {
+func (l *SLogsMock) Output(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error {
+	l.Filter = filter
 	appLogsIdentifier := "source"
 	appLogsValue := "app"
 	if strings.HasPrefix(domain, "csb01") {
@@ -34,13 +39,19 @@ func (l *SLogsMock) Output(queryString, domain string, generator queryGenerator,
 		appLogsValue = "supervisord"
 	}
 
-	logrus.Println("        @timestamp       -        message")
+	endTimestamp := filter.Until
+	if endTimestamp.IsZero() {
+		endTimestamp = time.Now()
+	}
+	if _, ok := lw.(*textLogWriter); ok {
+		logrus.Println("        @timestamp       -        message")
+	}
 	for {
-		queryBytes, err := generator(queryString, appLogsIdentifier, appLogsValue, startTimestamp, from, hostNames, fileName)
+		queryBytes, err := generator(queryString, appLogsIdentifier, appLogsValue, cursor, filter)
 		if err != nil {
-			return -1, fmt.Errorf("Error generating query: %s", err)
+			return fmt.Errorf("Error generating query: %s", err)
 		} else if queryBytes == nil || len(queryBytes) == 0 {
-			return -1, errors.New("Error generating query")
+			return errors.New("Error generating query")
 		}
 
 		var logs models.Logs
@@ -62,32 +73,25 @@ func (l *SLogsMock) Output(queryString, domain string, generator queryGenerator,
 		hits.Total = 1
 		logs.Hits = &hits
 
-		end := time.Time{}
 		for _, lh := range *logs.Hits.Hits {
-			logrus.Printf("%s - %s", lh.Fields["@timestamp"][0], lh.Fields["message"][0])
-			end, _ = time.Parse(time.RFC3339Nano, lh.Fields["@timestamp"][0])
+			if err = lw.Write(lh); err != nil {
+				return err
+			}
+			cursor.advance(lh)
 		}
-		amount := len(*logs.Hits.Hits)
-
-		from += len(*logs.Hits.Hits)
-		// TODO this will infinite loop if it always retrieves `size` hits
-		// and it fails to parse the end timestamp. very small window of opportunity.
-		if amount < size || end.After(endTimestamp) {
+		if len(*logs.Hits.Hits) < size || cursor.Since.After(endTimestamp) {
 			break
 		}
-		time.Sleep(config.JobPollTime * time.Second)
 	}
-	return from, nil
+	return nil
 }
 
-func (l *SLogsMock) Stream(queryString, domain string, generator queryGenerator, from int, timestamp time.Time, hostNames []string, fileName string) error {
+func (l *SLogsMock) Stream(queryString, domain string, generator queryGenerator, cursor *logCursor, filter *LogFilter, lw logWriter) error {
 	//Don't want to run stream forever in test
 	for i := 0; i < 2; i++ {
-		f, err := l.Output(queryString, domain, generator, from, timestamp, time.Now(), hostNames, fileName)
-		if err != nil {
+		if err := l.Output(queryString, domain, generator, cursor, filter, lw); err != nil {
 			return err
 		}
-		from = f
 		time.Sleep(config.LogPollTime * time.Second)
 	}
 	return nil
@@ -166,9 +170,9 @@ func TestLogsService(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -186,10 +190,10 @@ func TestLogsJobID(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		JobID:   test.JobID,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		JobID:    test.JobID,
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -207,10 +211,10 @@ func TestLogsTarget(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		Target:  test.Target,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		Target:   test.Target,
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate, test.GoodDate}, &cmdQuery)
 
@@ -228,9 +232,9 @@ func TestLogsStream(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  true,
-		Service: test.SvcLabel,
+		Query:    "",
+		Follow:   true,
+		Services: []string{test.SvcLabel},
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -248,9 +252,9 @@ func TestLogsBadService(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: "BadServiceLabel",
+		Query:    "",
+		Follow:   false,
+		Services: []string{"BadServiceLabel"},
 	}
 	muxSetup(mux, t, "code", []string{test.BadDate}, &cmdQuery)
 
@@ -258,7 +262,7 @@ func TestLogsBadService(t *testing.T) {
 		Settings: settings,
 	}
 	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
-	expectedErr := fmt.Sprintf("Cannot find the specified service \"%s\".", cmdQuery.Service)
+	expectedErr := fmt.Sprintf("Cannot find the specified service \"%s\".", cmdQuery.Services[0])
 	if err == nil {
 		t.Fatalf("Expected: %s\n", expectedErr)
 	} else if err.Error() != expectedErr {
@@ -318,10 +322,10 @@ func TestLogsBadRequestTargetNonCodeService(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		Target:  test.Target,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		Target:   test.Target,
 	}
 	muxSetup(mux, t, "database", []string{test.GoodDate}, &cmdQuery)
 
@@ -342,10 +346,10 @@ func TestLogsBadServiceWithTarget(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: "BadServiceLabel",
-		Target:  test.Target,
+		Query:    "",
+		Follow:   false,
+		Services: []string{"BadServiceLabel"},
+		Target:   test.Target,
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -353,7 +357,7 @@ func TestLogsBadServiceWithTarget(t *testing.T) {
 		Settings: settings,
 	}
 	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
-	expectedErr := fmt.Sprintf("Cannot find the specified service \"%s\".", cmdQuery.Service)
+	expectedErr := fmt.Sprintf("Cannot find the specified service \"%s\".", cmdQuery.Services[0])
 	if err == nil {
 		t.Fatalf("Expected: %s\n", expectedErr)
 	} else if err.Error() != expectedErr {
@@ -366,10 +370,10 @@ func TestLogsBadJobID(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		JobID:   "BadJobID",
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		JobID:    "BadJobID",
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -390,10 +394,10 @@ func TestLogsBadTarget(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		Target:  "BadTarget",
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		Target:   "BadTarget",
 	}
 	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
 
@@ -414,10 +418,10 @@ func TestLogsNoValidHostNames(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		Target:  test.Target,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		Target:   test.Target,
 	}
 	muxSetup(mux, t, "code", []string{test.BadDate, test.BadDate}, &cmdQuery)
 
@@ -425,7 +429,7 @@ func TestLogsNoValidHostNames(t *testing.T) {
 		Settings: settings,
 	}
 	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
-	expectedErr := fmt.Sprintf(`All %d jobs for the service "%s"%s do not have valid hostnames to allow their logs to be queried. Redeploy the service if you would like to use this functionality.`, 2, cmdQuery.Service, fmt.Sprintf(` that have a target of "%s"`, cmdQuery.Target))
+	expectedErr := fmt.Sprintf(`All %d jobs for the service "%s"%s do not have valid hostnames to allow their logs to be queried. Redeploy the service if you would like to use this functionality.`, 2, cmdQuery.Services[0], fmt.Sprintf(` that have a target of "%s"`, cmdQuery.Target))
 	if err == nil || err.Error() != expectedErr {
 		t.Fatalf("Expected: %s\nGot: %s", expectedErr, err)
 	}
@@ -436,10 +440,10 @@ func TestLogsOneValidHostName(t *testing.T) {
 	defer test.Teardown(server)
 	settings := test.GetSettings(baseURL.String())
 	cmdQuery := CMDLogQuery{
-		Query:   "",
-		Follow:  false,
-		Service: test.SvcLabel,
-		Target:  test.Target,
+		Query:    "",
+		Follow:   false,
+		Services: []string{test.SvcLabel},
+		Target:   test.Target,
 	}
 	muxSetup(mux, t, "code", []string{test.BadDate, test.GoodDate, test.BadDate, test.BadDate}, &cmdQuery)
 
@@ -452,3 +456,146 @@ func TestLogsOneValidHostName(t *testing.T) {
 		t.Fatalf("Unexpected error: %s", err)
 	}
 }
+
+func TestLogsExport(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	outputFile := "datica-logs-test.csv"
+	defer os.Remove(outputFile)
+	cmdQuery := CMDLogQuery{
+		Query:      "",
+		Since:      "2017-10-11",
+		Until:      "2017-10-12",
+		Level:      "error",
+		Format:     FormatCSV,
+		OutputFile: outputFile,
+	}
+	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
+
+	ilogs := &SLogsMock{
+		Settings: settings,
+	}
+	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b, _ := ioutil.ReadFile(outputFile)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 || lines[0] != "@timestamp,host,source,file,level,message" || lines[1] != "2017-10-11T15:04:00.999999999Z07:00,,,,,Wow so log 0" {
+		t.Fatalf("Expected a header and 3 rows but got %s", string(b))
+	}
+
+	// ndjson writes the full documents
+	cmdQuery.Format = FormatNDJSON
+	err = CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b, _ = ioutil.ReadFile(outputFile)
+	lines = strings.Split(strings.TrimSpace(string(b)), "\n")
+	var lh models.LogHits
+	if len(lines) != 3 || json.Unmarshal([]byte(lines[2]), &lh) != nil || lh.ID != "log_2" || lh.Fields["message"][0] != "Wow so log 2" {
+		t.Fatalf("Expected 3 JSON documents but got %s", string(b))
+	}
+}
+
+func TestLogsBadRequestTimeRange(t *testing.T) {
+	settings := test.GetSettings("")
+	badQueries := map[string]CMDLogQuery{
+		"Specifying \"-f\" in combination with \"--until\" is unsupported.":                                                      {Follow: true, Until: "2017-10-11"},
+		"Specifying \"--since\" or \"--until\" in combination with \"--hours\", \"--minutes\", or \"--seconds\" is unsupported.": {Since: "2017-10-11", Hours: 1},
+		"The time given by \"--until\" must be after the start of the logs to retrieve.":                                         {Since: "2017-10-11", Until: "2017-10-10"},
+		"Invalid log format \"xml\". Supported formats are text, ndjson, and csv":                                                {Format: "xml"},
+		"Specifying \"--output-file\" requires a \"--format\" of ndjson or csv.":                                                 {OutputFile: "logs.txt"},
+	}
+	for expectedErr, cmdQuery := range badQueries {
+		err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, &SLogsMock{Settings: settings}, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+		if err == nil || err.Error() != expectedErr {
+			t.Errorf("Expected: %s\nGot: %v", expectedErr, err)
+		}
+	}
+}
+
+func TestLogsBadRequestKeepsOutputFile(t *testing.T) {
+	settings := test.GetSettings("")
+	outputFile := "datica-logs-test.ndjson"
+	defer os.Remove(outputFile)
+	ioutil.WriteFile(outputFile, []byte("previous export\n"), 0600)
+
+	cmdQuery := CMDLogQuery{JobID: "job1", Format: FormatNDJSON, OutputFile: outputFile}
+	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, &SLogsMock{Settings: settings}, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+	if err == nil {
+		t.Fatal("Expected an error for --job-id without a service")
+	}
+	b, _ := ioutil.ReadFile(outputFile)
+	test.AssertEquals(t, "previous export\n", string(b))
+}
+
+func TestLogsMultipleServices(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	cmdQuery := CMDLogQuery{
+		Query:    "",
+		Follow:   true,
+		Services: []string{test.SvcLabel + ":" + test.Target, "service_proxy"},
+	}
+	muxSetup(mux, t, "code", []string{test.GoodDate}, &cmdQuery)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcIDAlt+"/jobs",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			if r.URL.Query().Get("type") == "deploy" {
+				fmt.Fprint(w, fmt.Sprintf(`[{"id":"proxy1abcdef","type":"deploy","status":"running","created_at":"%s"}]`, test.GoodDate))
+			} else {
+				fmt.Fprint(w, `[]`)
+			}
+		},
+	)
+
+	ilogs := &SLogsMock{
+		Settings: settings,
+	}
+	err := CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	hostNames := strings.Join(ilogs.Filter.HostNames, ",")
+	if hostNames != test.SvcLabel+"-"+test.Target+"-"+test.JobID[:6]+",service_proxy-proxy1" {
+		t.Fatalf("Expected the hosts of both services to be queried but got %s", hostNames)
+	}
+
+	cmdQuery.Target = test.Target
+	err = CmdLogs(&cmdQuery, settings.EnvironmentID, settings, ilogs, &test.FakePrompts{}, environments.New(settings), services.New(settings), jobs.New(settings), sites.New(settings))
+	if err == nil {
+		t.Fatalf("Expected an error specifying a target for multiple services")
+	}
+}
+
+func TestLogsPrefixes(t *testing.T) {
+	tw := &textLogWriter{}
+	tw.prefixWith([]logSource{
+		{Label: "code-1", HostNames: []string{"code-1-abcdef"}, Prefixes: map[string]string{"code-1-abcdef": "code-1/abcdef"}},
+		{Label: "code-2", FileName: "/data/log/app/code_2/current", Prefixes: map[string]string{}},
+	}, false)
+	logHit := func(host, file string) models.LogHits {
+		return models.LogHits{Source: map[string]string{"@timestamp": "2017-10-11T15:04:05Z", "message": "log", "host": host, "file": file}}
+	}
+	expected := []struct {
+		lh     models.LogHits
+		prefix string
+		index  int
+	}{
+		{logHit("code-1-abcdef", ""), "code-1/abcdef", 0},
+		{logHit("code-2-123456", "/data/log/app/code_2/current"), "code-2", 1},
+		{logHit("other-host", ""), "other-host", -1},
+	}
+	for _, e := range expected {
+		if prefix, index := tw.prefix(e.lh); prefix != e.prefix || index != e.index {
+			t.Errorf("Expected prefix %s of source %d but got %s of source %d", e.prefix, e.index, prefix, index)
+		}
+	}
+	if tw.width != len("code-1/abcdef") {
+		t.Errorf("Expected prefixes to be padded to %d characters but got %d", len("code-1/abcdef"), tw.width)
+	}
+}
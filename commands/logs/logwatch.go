@@ -1,7 +1,6 @@
 package logs
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/gorilla/websocket"
 )
 
@@ -34,11 +34,13 @@ func (l *SLogs) Watch(queryString, domain string) error {
 		return err
 	}
 	logrus.Println("Streaming logs...")
+	tlsConfig, err := network.TLSConfig(l.Settings.Network)
+	if err != nil {
+		return err
+	}
 	dialer := &websocket.Dialer{
-		Proxy: http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		},
+		Proxy:           network.Proxy(l.Settings.Network),
+		TLSClientConfig: tlsConfig,
 	}
 	headers := http.Header{"Cookie": {"sessionToken=" + url.QueryEscape(l.Settings.SessionToken)}}
 	urlString := fmt.Sprintf("wss://%s/stream/", domain)
@@ -5,16 +5,26 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdShow(svcName, envID, podID string, im IMaintenance, is services.IServices) error {
+// maintenanceOutput is the structured representation of the maintenance mode
+// status of a code service used when a machine readable output format is
+// selected.
+type maintenanceOutput struct {
+	Service   string `json:"service"`
+	Enabled   bool   `json:"enabled"`
+	EnabledAt string `json:"enabledAt,omitempty"`
+}
+
+func CmdShow(svcName, envID, podID string, im IMaintenance, is services.IServices, iout output.IOutput) error {
 	svcs, err := is.ListByEnvID(envID, podID)
 	if err != nil {
 		return err
 	}
-	if svcs == nil || len(*svcs) == 0 {
+	if (svcs == nil || len(*svcs) == 0) && !iout.Structured() {
 		logrus.Println("No services found")
 		return nil
 	}
@@ -27,6 +37,7 @@ func CmdShow(svcName, envID, podID string, im IMaintenance, is services.IService
 		return err
 	}
 
+	out := []maintenanceOutput{}
 	data := [][]string{{"SERVICE", "MAINTENANCE MODE", "ENABLED AT"}}
 	for _, svc := range *svcs {
 		if svc.Type == "code" && (svcName == "" || svc.Label == svcName) {
@@ -39,8 +50,12 @@ func CmdShow(svcName, envID, podID string, im IMaintenance, is services.IService
 				}
 			}
 			data = append(data, []string{svc.Label, status, createdAt})
+			out = append(out, maintenanceOutput{svc.Label, status == "enabled", createdAt})
 		}
 	}
+	if iout.Structured() {
+		return iout.Render(out)
+	}
 	if len(data) == 1 {
 		if svcName == "" {
 			logrus.Println("No code services found")
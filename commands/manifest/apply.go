@@ -0,0 +1,50 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/prompts"
+)
+
+func CmdApply(manifestPath string, force bool, im IManifest, ip prompts.IPrompts) error {
+	m, err := im.Load(manifestPath)
+	if err != nil {
+		return err
+	}
+	p, err := im.Plan(m)
+	if err != nil {
+		return err
+	}
+	printPlan(p)
+	if len(p.Changes) == 0 {
+		return nil
+	}
+	if !force {
+		if err = ip.YesNo("", "Would you like to apply these changes? (y/n) "); err != nil {
+			return err
+		}
+	}
+	if err = im.Apply(p); err != nil {
+		return err
+	}
+	logrus.Printf("Applied %d changes", len(p.Changes))
+	for _, c := range p.Changes {
+		if c.Kind == "site" || c.Kind == "cert" {
+			logrus.Printf("To make your site and cert changes go live, you must redeploy your service proxy with the \"datica redeploy %s\" command", m.ServiceProxy)
+			break
+		}
+	}
+	return nil
+}
+
+// Apply makes the changes of a plan. Changes are made in order and applying
+// stops at the first failure.
+func (s *SManifest) Apply(p *Plan) error {
+	for i, step := range p.steps {
+		if err := step(); err != nil {
+			return fmt.Errorf("Failed to apply the manifest after %d of %d steps: %s. Run the same command again to apply the remaining changes", i, len(p.steps), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/certs"
+	"github.com/daticahealth/cli/commands/deploykeys"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
+	"github.com/daticahealth/cli/commands/vars"
+	"github.com/daticahealth/cli/commands/worker"
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/lib/prompts"
+	"github.com/daticahealth/cli/models"
+	"github.com/jault3/mow.cli"
+)
+
+const manifestLongHelp = "The manifest is a YAML file describing the desired configuration of an environment. Here is a sample manifest\n\n" +
+	"<pre>\n" +
+	"serviceProxy: service_proxy\n" +
+	"certs:\n" +
+	"  - name: example.com\n" +
+	"    pubKeyFile: ./example.com.crt\n" +
+	"    privKeyFile: ./example.com.key\n" +
+	"  - name: api.example.com\n" +
+	"    letsEncrypt: true\n" +
+	"sites:\n" +
+	"  - name: example.com\n" +
+	"    service: code-1\n" +
+	"    cert: example.com\n" +
+	"    siteValues:\n" +
+	"      clientMaxBodySize: 20m\n" +
+	"      enableWebSockets: true\n" +
+	"services:\n" +
+	"  code-1:\n" +
+	"    vars:\n" +
+	"      RAILS_ENV: production\n" +
+	"    workers:\n" +
+	"      worker: 2\n" +
+	"    deployKeys:\n" +
+	"      - name: ci\n" +
+	"        keyFile: ./ci.pub\n" +
+	"</pre>\n\n" +
+	"Every section is optional. A section that is left out is not managed by the manifest. " +
+	"A section that is present is managed exclusively by the manifest, so anything of that kind not listed in the manifest is removed. " +
+	"For example, listing <code>vars</code> for a service removes any environment variable of that service not in the manifest, while leaving out <code>vars</code> leaves them all untouched. " +
+	"File paths are relative to the manifest. " +
+	"Certs are matched by name only, use the <code>certs update</code> command to replace the contents of an existing cert."
+
+// PlanCmd is the contract between the user and the CLI. This specifies the
+// command name, arguments, and required/optional arguments and flags for the
+// command.
+var PlanCmd = models.Command{
+	Name:      "plan",
+	ShortHelp: "Show the changes needed to make an environment match a manifest",
+	LongHelp: "<code>plan</code> compares a manifest against the current vars, sites, certs, worker scales, and deploy keys of the associated environment and prints the changes that <code>apply</code> would make. " +
+		"No changes are made by this command. " + manifestLongHelp + " Here is a sample command\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" plan ./datica.yml\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(cmd *cli.Cmd) {
+			manifestPath := cmd.StringArg("MANIFEST", "", "The path to the manifest describing the environment")
+			cmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err := CmdPlan(*manifestPath, New(settings), output.New(settings))
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			cmd.Spec = "MANIFEST"
+		}
+	},
+}
+
+// ApplyCmd is the contract between the user and the CLI. This specifies the
+// command name, arguments, and required/optional arguments and flags for the
+// command.
+var ApplyCmd = models.Command{
+	Name:      "apply",
+	ShortHelp: "Make an environment match a manifest",
+	LongHelp: "<code>apply</code> makes the vars, sites, certs, worker scales, and deploy keys of the associated environment match a manifest. " +
+		"The same changes shown by <code>plan</code> are printed and you are asked to confirm them before anything is changed. " +
+		"Only the resources that differ from the manifest are changed. " + manifestLongHelp + " " +
+		"Changes to sites and certs only take effect once the service proxy is redeployed with the <code>redeploy</code> command. Here is a sample command\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" apply ./datica.yml\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(cmd *cli.Cmd) {
+			manifestPath := cmd.StringArg("MANIFEST", "", "The path to the manifest describing the environment")
+			force := cmd.BoolOpt("f force", false, "Apply the changes without prompting to confirm")
+			cmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err := CmdApply(*manifestPath, *force, New(settings), prompts.New())
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			cmd.Spec = "MANIFEST [-f]"
+		}
+	},
+}
+
+// IManifest
+type IManifest interface {
+	Load(path string) (*Manifest, error)
+	Plan(m *Manifest) (*Plan, error)
+	Apply(p *Plan) error
+}
+
+// SManifest is a concrete implementation of IManifest
+type SManifest struct {
+	Settings   *models.Settings
+	Vars       vars.IVars
+	Sites      sites.ISites
+	Certs      certs.ICerts
+	Worker     worker.IWorker
+	DeployKeys deploykeys.IDeployKeys
+	Services   services.IServices
+	Jobs       jobs.IJobs
+}
+
+// New returns an instance of IManifest
+func New(settings *models.Settings) IManifest {
+	return &SManifest{
+		Settings:   settings,
+		Vars:       vars.New(settings),
+		Sites:      sites.New(settings),
+		Certs:      certs.New(settings),
+		Worker:     worker.New(settings),
+		DeployKeys: deploykeys.New(settings),
+		Services:   services.New(settings),
+		Jobs:       jobs.New(settings),
+	}
+}
@@ -0,0 +1,168 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest describes the desired configuration of an environment. A nil
+// section is not managed by the manifest.
+type Manifest struct {
+	ServiceProxy string                     `yaml:"serviceProxy"`
+	Certs        []Cert                     `yaml:"certs"`
+	Sites        []Site                     `yaml:"sites"`
+	Services     map[string]ServiceManifest `yaml:"services"`
+
+	dir string
+}
+
+// Cert is a cert on the service proxy. Either both key files or letsEncrypt
+// must be given.
+type Cert struct {
+	Name        string `yaml:"name"`
+	PubKeyFile  string `yaml:"pubKeyFile"`
+	PrivKeyFile string `yaml:"privKeyFile"`
+	LetsEncrypt bool   `yaml:"letsEncrypt"`
+}
+
+// Site is a site on the service proxy. Service is the label of the upstream
+// service.
+type Site struct {
+	Name       string                 `yaml:"name"`
+	Service    string                 `yaml:"service"`
+	Cert       string                 `yaml:"cert"`
+	SiteValues map[string]interface{} `yaml:"siteValues"`
+}
+
+// ServiceManifest is the configuration of a single code service.
+type ServiceManifest struct {
+	Vars       map[string]string `yaml:"vars"`
+	Workers    map[string]int    `yaml:"workers"`
+	DeployKeys []DeployKey       `yaml:"deployKeys"`
+}
+
+// DeployKey is a public SSH deploy key of a code service.
+type DeployKey struct {
+	Name    string `yaml:"name"`
+	KeyFile string `yaml:"keyFile"`
+}
+
+// Load reads and validates the manifest at the given path.
+func (s *SManifest) Load(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err = yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest %s: %s", path, err)
+	}
+	m.dir = filepath.Dir(path)
+	if m.ServiceProxy == "" {
+		m.ServiceProxy = "service_proxy"
+	}
+	for _, c := range m.Certs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("Invalid manifest %s: every cert must have a name", path)
+		}
+		if !c.LetsEncrypt && (c.PubKeyFile == "" || c.PrivKeyFile == "") {
+			return nil, fmt.Errorf("Invalid manifest %s: cert %s must either have a pubKeyFile and privKeyFile or use letsEncrypt", path, c.Name)
+		}
+	}
+	for i, site := range m.Sites {
+		if site.Name == "" || site.Service == "" {
+			return nil, fmt.Errorf("Invalid manifest %s: every site must have a name and service", path)
+		}
+		siteValues, err := normalizeSiteValues(site.SiteValues)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid manifest %s: invalid siteValues for site %s: %s", path, site.Name, err)
+		}
+		m.Sites[i].SiteValues = siteValues
+	}
+	for label, svc := range m.Services {
+		for target, scale := range svc.Workers {
+			if scale <= 0 {
+				return nil, fmt.Errorf("Invalid manifest %s: invalid scale %d for worker target %s of service %s. Leave the target out of the manifest to remove it", path, scale, target, label)
+			}
+		}
+		for _, dk := range svc.DeployKeys {
+			if dk.Name == "" || dk.KeyFile == "" {
+				return nil, fmt.Errorf("Invalid manifest %s: every deploy key of service %s must have a name and keyFile", path, label)
+			}
+		}
+	}
+	return &m, nil
+}
+
+// path resolves a file path in the manifest relative to the manifest itself.
+func (m *Manifest) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(m.dir, p)
+}
+
+// normalizeSiteValues converts site values to the same representation as site
+// values retrieved from the API so that the two can be compared.
+func normalizeSiteValues(siteValues map[string]interface{}) (map[string]interface{}, error) {
+	if siteValues == nil {
+		return map[string]interface{}{}, nil
+	}
+	converted, err := jsonCompatible(siteValues)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(converted)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	err = json.Unmarshal(b, &normalized)
+	return normalized, err
+}
+
+// jsonCompatible replaces the map[interface{}]interface{} values produced by
+// the YAML parser with map[string]interface{} values.
+func jsonCompatible(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key %v", k)
+			}
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = converted
+		}
+		return m, nil
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, val := range t {
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = converted
+		}
+		return l, nil
+	}
+	return v, nil
+}
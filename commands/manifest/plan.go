@@ -0,0 +1,488 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/models"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// Plan is the set of changes needed to make an environment match a manifest
+// along with the API calls that make those changes, in the order they must be
+// made.
+type Plan struct {
+	Changes []Change
+
+	steps []func() error
+}
+
+// Change is a single resource that differs from the manifest.
+type Change struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func (p *Plan) add(action, kind, name, service, detail string) {
+	p.Changes = append(p.Changes, Change{Action: action, Kind: kind, Name: name, Service: service, Detail: detail})
+}
+
+func (p *Plan) step(f func() error) {
+	p.steps = append(p.steps, f)
+}
+
+func CmdPlan(manifestPath string, im IManifest, iout output.IOutput) error {
+	m, err := im.Load(manifestPath)
+	if err != nil {
+		return err
+	}
+	p, err := im.Plan(m)
+	if err != nil {
+		return err
+	}
+	if iout.Structured() {
+		if p.Changes == nil {
+			p.Changes = []Change{}
+		}
+		return iout.Render(p.Changes)
+	}
+	printPlan(p)
+	return nil
+}
+
+func printPlan(p *Plan) {
+	if len(p.Changes) == 0 {
+		logrus.Println("No changes. The environment matches the manifest")
+		return
+	}
+	symbols := map[string]string{actionCreate: "+", actionUpdate: "~", actionDelete: "-"}
+	counts := map[string]int{}
+	for _, c := range p.Changes {
+		line := fmt.Sprintf("  %s %s %s (%s)", symbols[c.Action], c.Kind, c.Name, c.Service)
+		if c.Detail != "" {
+			line = fmt.Sprintf("%s: %s", line, c.Detail)
+		}
+		logrus.Println(line)
+		counts[c.Action]++
+	}
+	logrus.Printf("Plan: %d to create, %d to update, %d to delete", counts[actionCreate], counts[actionUpdate], counts[actionDelete])
+}
+
+// Plan compares the manifest against the live environment and returns the
+// changes needed to make the environment match it.
+func (s *SManifest) Plan(m *Manifest) (*Plan, error) {
+	svcs, err := s.Services.List()
+	if err != nil {
+		return nil, err
+	}
+	byLabel := map[string]*models.Service{}
+	if svcs != nil {
+		for i := range *svcs {
+			byLabel[(*svcs)[i].Label] = &(*svcs)[i]
+		}
+	}
+	lookup := func(label string) (*models.Service, error) {
+		svc, ok := byLabel[label]
+		if !ok {
+			return nil, fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.", label)
+		}
+		return svc, nil
+	}
+
+	p := &Plan{}
+	if m.Certs != nil || m.Sites != nil {
+		proxy, err := lookup(m.ServiceProxy)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.planProxy(p, m, proxy, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	labels := []string{}
+	for label := range m.Services {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		svc, err := lookup(label)
+		if err != nil {
+			return nil, err
+		}
+		sm := m.Services[label]
+		if sm.Vars != nil {
+			if err = s.planVars(p, sm.Vars, svc); err != nil {
+				return nil, err
+			}
+		}
+		if sm.Workers != nil {
+			if err = s.planWorkers(p, sm.Workers, svc); err != nil {
+				return nil, err
+			}
+		}
+		if sm.DeployKeys != nil {
+			if err = s.planDeployKeys(p, m, sm.DeployKeys, svc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// planProxy plans the certs and sites of the service proxy. Sites are removed
+// before the certs they use and new certs are created before the sites that
+// use them.
+func (s *SManifest) planProxy(p *Plan, m *Manifest, proxy *models.Service, lookup func(string) (*models.Service, error)) error {
+	var siteDeletes, certCreates, siteUpserts, certDeletes Plan
+	if m.Sites != nil {
+		liveSites, err := s.Sites.List(proxy.ID)
+		if err != nil {
+			return err
+		}
+		live := map[string]models.Site{}
+		if liveSites != nil {
+			for _, site := range *liveSites {
+				live[site.Name] = site
+			}
+		}
+		desired := map[string]bool{}
+		for _, site := range m.Sites {
+			desired[site.Name] = true
+			upstream, err := lookup(site.Service)
+			if err != nil {
+				return err
+			}
+			site := site
+			create := func() error {
+				_, err := s.Sites.Create(site.Name, site.Cert, upstream.ID, proxy.ID, site.SiteValues)
+				return err
+			}
+			existing, ok := live[site.Name]
+			if !ok {
+				siteUpserts.add(actionCreate, "site", site.Name, proxy.Label, "")
+				siteUpserts.step(create)
+				continue
+			}
+			liveValues, err := normalizeSiteValues(existing.SiteValues)
+			if err != nil {
+				return err
+			}
+			diffs := []string{}
+			if existing.Cert != site.Cert {
+				diffs = append(diffs, "cert")
+			}
+			if existing.UpstreamService != upstream.ID {
+				diffs = append(diffs, "service")
+			}
+			if !reflect.DeepEqual(liveValues, site.SiteValues) {
+				diffs = append(diffs, "siteValues")
+			}
+			if len(diffs) > 0 {
+				siteID := existing.ID
+				siteUpserts.add(actionUpdate, "site", site.Name, proxy.Label, fmt.Sprintf("%s changed", strings.Join(diffs, ", ")))
+				siteUpserts.step(func() error {
+					if err := s.Sites.Rm(siteID, proxy.ID); err != nil {
+						return err
+					}
+					return create()
+				})
+			}
+		}
+		for _, site := range sortedSites(liveSites) {
+			if !desired[site.Name] {
+				siteID := site.ID
+				siteDeletes.add(actionDelete, "site", site.Name, proxy.Label, "")
+				siteDeletes.step(func() error {
+					return s.Sites.Rm(siteID, proxy.ID)
+				})
+			}
+		}
+	}
+	if m.Certs != nil {
+		liveCerts, err := s.Certs.List(proxy.ID)
+		if err != nil {
+			return err
+		}
+		live := map[string]bool{}
+		if liveCerts != nil {
+			for _, c := range *liveCerts {
+				live[c.Name] = true
+			}
+		}
+		desired := map[string]bool{}
+		for _, c := range m.Certs {
+			desired[c.Name] = true
+			if live[c.Name] {
+				continue
+			}
+			name := c.Name
+			certCreates.add(actionCreate, "cert", name, proxy.Label, "")
+			if c.LetsEncrypt {
+				certCreates.step(func() error {
+					return s.Certs.CreateLetsEncrypt(name, proxy.ID)
+				})
+				continue
+			}
+			pubKey, err := ioutil.ReadFile(m.path(c.PubKeyFile))
+			if err != nil {
+				return err
+			}
+			privKey, err := ioutil.ReadFile(m.path(c.PrivKeyFile))
+			if err != nil {
+				return err
+			}
+			certCreates.step(func() error {
+				return s.Certs.Create(name, string(pubKey), string(privKey), proxy.ID)
+			})
+		}
+		if liveCerts != nil {
+			names := []string{}
+			for _, c := range *liveCerts {
+				if !desired[c.Name] {
+					names = append(names, c.Name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				name := name
+				certDeletes.add(actionDelete, "cert", name, proxy.Label, "")
+				certDeletes.step(func() error {
+					return s.Certs.Rm(name, proxy.ID)
+				})
+			}
+		}
+	}
+	for _, sub := range []Plan{siteDeletes, certCreates, siteUpserts, certDeletes} {
+		p.Changes = append(p.Changes, sub.Changes...)
+		p.steps = append(p.steps, sub.steps...)
+	}
+	return nil
+}
+
+// planVars plans the environment variables of a service. All new and changed
+// variables are set with a single call.
+func (s *SManifest) planVars(p *Plan, desired map[string]string, svc *models.Service) error {
+	live, err := s.Vars.List(svc.ID)
+	if err != nil {
+		return err
+	}
+	set := map[string]string{}
+	for _, key := range sortedKeys(desired) {
+		value, ok := live[key]
+		if !ok {
+			p.add(actionCreate, "var", key, svc.Label, "")
+			set[key] = desired[key]
+		} else if value != desired[key] {
+			p.add(actionUpdate, "var", key, svc.Label, "value changed")
+			set[key] = desired[key]
+		}
+	}
+	if len(set) > 0 {
+		p.step(func() error {
+			return s.Vars.Set(svc.ID, set)
+		})
+	}
+	for _, key := range sortedKeys(live) {
+		if _, ok := desired[key]; !ok {
+			key := key
+			p.add(actionDelete, "var", key, svc.Label, "")
+			p.step(func() error {
+				return s.Vars.Unset(svc.ID, key)
+			})
+		}
+	}
+	return nil
+}
+
+// planWorkers plans the worker scales of a service. Jobs of targets that are
+// scaled down are stopped before the scales are updated with a single call and
+// new workers are deployed afterwards.
+func (s *SManifest) planWorkers(p *Plan, desired map[string]int, svc *models.Service) error {
+	workers, err := s.Worker.Retrieve(svc.ID)
+	if err != nil {
+		return err
+	}
+	live := workers.Workers
+	if live == nil {
+		live = map[string]int{}
+	}
+	changed := false
+	var stops, deploys []func() error
+	for _, target := range sortedTargets(desired) {
+		target, scale := target, desired[target]
+		existing, ok := live[target]
+		if !ok {
+			p.add(actionCreate, "worker", target, svc.Label, fmt.Sprintf("scale %d", scale))
+		} else if existing != scale {
+			p.add(actionUpdate, "worker", target, svc.Label, fmt.Sprintf("scale %d -> %d", existing, scale))
+		} else {
+			continue
+		}
+		changed = true
+		if scale > existing {
+			deploys = append(deploys, func() error {
+				return s.Jobs.DeployTarget(target, svc.ID)
+			})
+		} else {
+			stops = append(stops, func() error {
+				return s.stopWorkers(target, existing-scale, svc)
+			})
+		}
+	}
+	for _, target := range sortedTargets(live) {
+		if _, ok := desired[target]; !ok {
+			target, existing := target, live[target]
+			changed = true
+			p.add(actionDelete, "worker", target, svc.Label, fmt.Sprintf("scale %d", existing))
+			stops = append(stops, func() error {
+				return s.stopWorkers(target, existing, svc)
+			})
+		}
+	}
+	if !changed {
+		return nil
+	}
+	p.steps = append(p.steps, stops...)
+	p.step(func() error {
+		return s.Worker.Update(svc.ID, &models.Workers{Limit: workers.Limit, Workers: desired})
+	})
+	p.steps = append(p.steps, deploys...)
+	return nil
+}
+
+// stopWorkers stops up to count running jobs of the given worker target.
+func (s *SManifest) stopWorkers(target string, count int, svc *models.Service) error {
+	jobs, err := s.Jobs.RetrieveByTarget(svc.ID, target, 1, 1000)
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for _, j := range *jobs {
+		if deleted == count {
+			break
+		}
+		if err = s.Jobs.Delete(j.ID, svc.ID); err != nil {
+			return err
+		}
+		deleted++
+	}
+	return nil
+}
+
+// planDeployKeys plans the SSH deploy keys of a code service. A key whose
+// contents changed is removed and added again.
+func (s *SManifest) planDeployKeys(p *Plan, m *Manifest, desired []DeployKey, svc *models.Service) error {
+	if svc.Type != "code" {
+		return fmt.Errorf("You can only add deploy keys to code services, not %s services", svc.Type)
+	}
+	liveKeys, err := s.DeployKeys.List(svc.ID)
+	if err != nil {
+		return err
+	}
+	live := map[string]string{}
+	if liveKeys != nil {
+		for _, k := range *liveKeys {
+			if k.Type == "ssh" {
+				live[k.Name] = strings.TrimSpace(k.Key)
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, dk := range desired {
+		names[dk.Name] = true
+		b, err := ioutil.ReadFile(m.path(dk.KeyFile))
+		if err != nil {
+			return err
+		}
+		k, err := s.DeployKeys.ParsePublicKey(b)
+		if err != nil {
+			return fmt.Errorf("Invalid deploy key %s for service %s: %s", dk.Name, svc.Label, err)
+		}
+		key := string(ssh.MarshalAuthorizedKey(k))
+		name := dk.Name
+		add := func() error {
+			return s.DeployKeys.Add(name, "ssh", key, svc.ID)
+		}
+		existing, ok := live[name]
+		if !ok {
+			p.add(actionCreate, "deploy key", name, svc.Label, "")
+			p.step(add)
+		} else if existing != strings.TrimSpace(key) {
+			p.add(actionUpdate, "deploy key", name, svc.Label, "key changed")
+			p.step(func() error {
+				if err := s.DeployKeys.Rm(name, "ssh", svc.ID); err != nil {
+					return err
+				}
+				return add()
+			})
+		}
+	}
+	for _, name := range sortedKeys(live) {
+		if !names[name] {
+			name := name
+			p.add(actionDelete, "deploy key", name, svc.Label, "")
+			p.step(func() error {
+				return s.DeployKeys.Rm(name, "ssh", svc.ID)
+			})
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedTargets(m map[string]int) []string {
+	targets := []string{}
+	for t := range m {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func sortedSites(sites *[]models.Site) []models.Site {
+	if sites == nil {
+		return []models.Site{}
+	}
+	sorted := make([]models.Site, len(*sites))
+	copy(sorted, *sites)
+	sort.Sort(SortedSites(sorted))
+	return sorted
+}
+
+// SortedSites is a wrapper for Site arrays in order to sort them by name
+type SortedSites []models.Site
+
+func (s SortedSites) Len() int {
+	return len(s)
+}
+
+func (s SortedSites) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s SortedSites) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
@@ -0,0 +1,240 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/test"
+)
+
+const manifestPath = "datica-manifest-test.yml"
+
+const testManifest = `
+sites:
+  - name: same.example.com
+    service: code1
+    cert: same_cert
+    siteValues:
+      clientMaxBodySize: 20m
+      enableCORS: true
+  - name: changed.example.com
+    service: code1
+    cert: same_cert
+    siteValues:
+      proxyReadTimeout: 30s
+  - name: new.example.com
+    service: code1
+    cert: new_cert
+certs:
+  - name: same_cert
+    letsEncrypt: true
+  - name: new_cert
+    letsEncrypt: true
+services:
+  code1:
+    vars:
+      SAME: value
+      CHANGED: new
+      NEW: value
+    workers:
+      worker: 3
+`
+
+var expectedChanges = []Change{
+	{Action: actionDelete, Kind: "site", Name: "old.example.com", Service: test.DownStream},
+	{Action: actionCreate, Kind: "cert", Name: "new_cert", Service: test.DownStream},
+	{Action: actionUpdate, Kind: "site", Name: "changed.example.com", Service: test.DownStream, Detail: "siteValues changed"},
+	{Action: actionCreate, Kind: "site", Name: "new.example.com", Service: test.DownStream},
+	{Action: actionDelete, Kind: "cert", Name: "old_cert", Service: test.DownStream},
+	{Action: actionUpdate, Kind: "var", Name: "CHANGED", Service: test.SvcLabel, Detail: "value changed"},
+	{Action: actionCreate, Kind: "var", Name: "NEW", Service: test.SvcLabel},
+	{Action: actionDelete, Kind: "var", Name: "OLD", Service: test.SvcLabel},
+	{Action: actionUpdate, Kind: "worker", Name: "worker", Service: test.SvcLabel, Detail: "scale 1 -> 3"},
+}
+
+// setupManifestMocks mocks the live state of an environment and records every
+// request that changes it.
+func setupManifestMocks(t *testing.T, mux *http.ServeMux) func() []string {
+	var lock sync.Mutex
+	calls := []string{}
+	record := func(r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), string(body))))
+	}
+	mux.HandleFunc("/environments/"+test.EnvID+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s","type":"code"},{"id":"%s","label":"%s"}]`, test.SvcID, test.SvcLabel, test.SvcIDAlt, test.DownStream))
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcIDAlt+"/sites",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprint(w, fmt.Sprintf(`[{"id":1,"name":"same.example.com","cert":"same_cert","upstreamService":"%[1]s","site_values":{"clientMaxBodySize":"20m","enableCORS":true}},{"id":2,"name":"changed.example.com","cert":"same_cert","upstreamService":"%[1]s","site_values":{"proxyReadTimeout":"60s"}},{"id":3,"name":"old.example.com","cert":"old_cert","upstreamService":"%[1]s","site_values":{}}]`, test.SvcID))
+				return
+			}
+			record(r)
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcIDAlt+"/sites/",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "DELETE")
+			record(r)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcIDAlt+"/certs",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprint(w, `[{"name":"same_cert"},{"name":"old_cert"}]`)
+				return
+			}
+			record(r)
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcIDAlt+"/certs/",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "DELETE")
+			record(r)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/env",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprint(w, `{"SAME":"value","CHANGED":"old","OLD":"value"}`)
+				return
+			}
+			record(r)
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/env/",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "DELETE")
+			record(r)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/workers",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprint(w, `{"worker_limit":5,"workers":{"worker":1}}`)
+				return
+			}
+			record(r)
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/deploy",
+		func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			fmt.Fprint(w, `{}`)
+		},
+	)
+	return func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return calls
+	}
+}
+
+func TestPlan(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	calls := setupManifestMocks(t, mux)
+	ioutil.WriteFile(manifestPath, []byte(testManifest), 0644)
+	defer os.Remove(manifestPath)
+
+	// test
+	im := New(settings)
+	m, err := im.Load(manifestPath)
+	if err != nil {
+		t.Fatalf("Unexpected error loading the manifest: %s", err)
+	}
+	p, err := im.Plan(m)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(p.Changes) != len(expectedChanges) {
+		t.Fatalf("Expected %d changes but got %d: %+v", len(expectedChanges), len(p.Changes), p.Changes)
+	}
+	for i, c := range p.Changes {
+		if c != expectedChanges[i] {
+			t.Errorf("Expected change %d to be %+v but got %+v", i, expectedChanges[i], c)
+		}
+	}
+	if len(calls()) != 0 {
+		t.Fatalf("Planning should not change anything but made the calls %v", calls())
+	}
+
+	settings.OutputFormat = output.FormatJSON
+	if err = CmdPlan(manifestPath, im, output.New(settings)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	calls := setupManifestMocks(t, mux)
+	ioutil.WriteFile(manifestPath, []byte(testManifest), 0644)
+	defer os.Remove(manifestPath)
+
+	// test
+	err := CmdApply(manifestPath, true, New(settings), &test.FakePrompts{})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	made := calls()
+	expected := []string{
+		"DELETE /environments/env1/services/svc2/sites/3",
+		`POST /environments/env1/services/svc2/certs {"name":"new_cert","letsEncrypt":true}`,
+		"DELETE /environments/env1/services/svc2/sites/2",
+		`POST /environments/env1/services/svc2/sites {"name":"changed.example.com","cert":"same_cert","upstreamService":"svc1","site_values":{"proxyReadTimeout":"30s"}}`,
+		`POST /environments/env1/services/svc2/sites {"name":"new.example.com","cert":"new_cert","upstreamService":"svc1","site_values":{}}`,
+		"DELETE /environments/env1/services/svc2/certs/old_cert",
+		`POST /environments/env1/services/svc1/env {"CHANGED":"new","NEW":"value"}`,
+		"DELETE /environments/env1/services/svc1/env/OLD",
+		`POST /environments/env1/services/svc1/workers {"worker_limit":5,"workers":{"worker":3}}`,
+		"POST /environments/env1/services/svc1/deploy?target=worker",
+	}
+	if len(made) != len(expected) {
+		t.Fatalf("Expected %d calls but got %d: %v", len(expected), len(made), made)
+	}
+	for i := range made {
+		if made[i] != expected[i] {
+			t.Errorf("Expected call %d to be %s but got %s", i, expected[i], made[i])
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	invalid := []string{
+		"certs:\n  - name: c\n",
+		"sites:\n  - name: s\n",
+		"services:\n  code1:\n    workers:\n      worker: 0\n",
+		"services:\n  code1:\n    deployKeys:\n      - name: ci\n",
+		"sites: [",
+	}
+	defer os.Remove(manifestPath)
+	for _, data := range invalid {
+		ioutil.WriteFile(manifestPath, []byte(data), 0644)
+		if _, err := New(test.GetSettings("")).Load(manifestPath); err == nil {
+			t.Errorf("Expected an error loading the manifest %q", data)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/network"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -33,6 +37,8 @@ var Cmd = models.Command{
 			cmd.CommandLong(MemorySubCmd.Name, MemorySubCmd.ShortHelp, MemorySubCmd.LongHelp, MemorySubCmd.CmdFunc(settings))
 			cmd.CommandLong(NetworkInSubCmd.Name, NetworkInSubCmd.ShortHelp, NetworkInSubCmd.LongHelp, NetworkInSubCmd.CmdFunc(settings))
 			cmd.CommandLong(NetworkOutSubCmd.Name, NetworkOutSubCmd.ShortHelp, NetworkOutSubCmd.LongHelp, NetworkOutSubCmd.CmdFunc(settings))
+			cmd.CommandLong(ServeSubCmd.Name, ServeSubCmd.ShortHelp, ServeSubCmd.LongHelp, ServeSubCmd.CmdFunc(settings))
+			cmd.CommandLong(WatchSubCmd.Name, WatchSubCmd.ShortHelp, WatchSubCmd.LongHelp, WatchSubCmd.CmdFunc(settings))
 		}
 	},
 }
@@ -42,36 +48,49 @@ var CPUSubCmd = models.Command{
 	ShortHelp: "Print service and environment CPU metrics in your local time zone",
 	LongHelp: "<code>metrics cpu</code> prints out CPU metrics for your environment or individual services. " +
 		"You can print out metrics in csv, json, plain text, or spark lines format. " +
-		"If you want plain text format, omit the <code>--json</code> and <code>--csv</code> flags. " +
+		"If you want plain text format, omit the <code>--json</code>, <code>--csv</code>, and <code>--graph</code> flags. " +
 		"You can only stream metrics using plain text or spark lines formats. " +
+		"Use <code>--graph</code> for spark lines of every service, or a chart of a single service, that are redrawn in place with every update when streaming. " +
+		"To retrieve a time range within the last day (1440 minutes), use <code>--since</code> and optionally <code>--until</code>. Time ranges are averaged into samples of <code>--step</code>. " +
+		"Add <code>--summary</code> to print the min, avg, max, p50, p95, and p99 of each service instead, " +
+		"or use <code>--compare</code> to compare the <code>--window</code> before a point in time, such as a deploy, with the <code>--window</code> after it. " +
 		"To print out metrics for every service in your environment, omit the <code>SERVICE_NAME</code> argument. " +
 		"Otherwise you may choose a service, such as an app service, to retrieve metrics for. " +
 		"Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" metrics cpu\n" +
 		"datica -E \"<your_env_name>\" metrics cpu app01 --stream\n" +
 		"datica -E \"<your_env_name>\" metrics cpu --json\n" +
-		"datica -E \"<your_env_name>\" metrics cpu db01 --csv -m 60\n</pre>",
+		"datica -E \"<your_env_name>\" metrics cpu app01 --graph --stream\n" +
+		"datica -E \"<your_env_name>\" metrics cpu db01 --csv -m 60\n" +
+		"datica -E \"<your_env_name>\" metrics cpu app01 --since 12h --summary\n" +
+		"datica -E \"<your_env_name>\" metrics cpu --compare \"2017-06-01 14:00\" --window 1h\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to print metrics for")
 			json := subCmd.BoolOpt("json", false, "Output the data as json")
 			csv := subCmd.BoolOpt("csv", false, "Output the data as csv")
 			text := subCmd.BoolOpt("text", true, "Output the data in plain text")
+			graph := subCmd.BoolOpt("graph", false, "Output the data as spark lines, or as a chart for a single service")
 			stream := subCmd.BoolOpt("stream", false, "Repeat calls once per minute until this process is interrupted.")
 			mins := subCmd.IntOpt("m mins", 1, "How many minutes worth of metrics to retrieve.")
+			rangeOpts := RangeOpts(subCmd)
 			subCmd.Action = func() {
+				ro, err := rangeOpts()
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdMetrics(*serviceName, CPU, *json, *csv, *text, *stream, *mins, New(settings), services.New(settings))
+				err = CmdMetrics(*serviceName, CPU, *json, *csv, *text, *graph, *stream, *mins, ro, New(settings), services.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text)] [--stream] [-m]"
+			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text | --graph)] [--stream] [-m] " + RangeSpec
 		}
 	},
 }
@@ -81,36 +100,49 @@ var MemorySubCmd = models.Command{
 	ShortHelp: "Print service and environment memory metrics in your local time zone",
 	LongHelp: "<code>metrics memory</code> prints out memory metrics for your environment or individual services. " +
 		"You can print out metrics in csv, json, plain text, or spark lines format. " +
-		"If you want plain text format, omit the <code>--json</code> and <code>--csv</code> flags. " +
+		"If you want plain text format, omit the <code>--json</code>, <code>--csv</code>, and <code>--graph</code> flags. " +
 		"You can only stream metrics using plain text or spark lines formats. " +
+		"Use <code>--graph</code> for spark lines of every service, or a chart of a single service, that are redrawn in place with every update when streaming. " +
+		"To retrieve a time range within the last day (1440 minutes), use <code>--since</code> and optionally <code>--until</code>. Time ranges are averaged into samples of <code>--step</code>. " +
+		"Add <code>--summary</code> to print the min, avg, max, p50, p95, and p99 of each service instead, " +
+		"or use <code>--compare</code> to compare the <code>--window</code> before a point in time, such as a deploy, with the <code>--window</code> after it. " +
 		"To print out metrics for every service in your environment, omit the <code>SERVICE_NAME</code> argument. " +
 		"Otherwise you may choose a service, such as an app service, to retrieve metrics for. " +
 		"Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" metrics memory\n" +
 		"datica -E \"<your_env_name>\" metrics memory app01 --stream\n" +
 		"datica -E \"<your_env_name>\" metrics memory --json\n" +
-		"datica -E \"<your_env_name>\" metrics memory db01 --csv -m 60\n</pre>",
+		"datica -E \"<your_env_name>\" metrics memory app01 --graph --stream\n" +
+		"datica -E \"<your_env_name>\" metrics memory db01 --csv -m 60\n" +
+		"datica -E \"<your_env_name>\" metrics memory app01 --since 12h --summary\n" +
+		"datica -E \"<your_env_name>\" metrics memory --compare \"2017-06-01 14:00\" --window 1h\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to print metrics for")
 			json := subCmd.BoolOpt("json", false, "Output the data as json")
 			csv := subCmd.BoolOpt("csv", false, "Output the data as csv")
 			text := subCmd.BoolOpt("text", true, "Output the data in plain text")
+			graph := subCmd.BoolOpt("graph", false, "Output the data as spark lines, or as a chart for a single service")
 			stream := subCmd.BoolOpt("stream", false, "Repeat calls once per minute until this process is interrupted.")
 			mins := subCmd.IntOpt("m mins", 1, "How many minutes worth of metrics to retrieve.")
+			rangeOpts := RangeOpts(subCmd)
 			subCmd.Action = func() {
+				ro, err := rangeOpts()
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdMetrics(*serviceName, Memory, *json, *csv, *text, *stream, *mins, New(settings), services.New(settings))
+				err = CmdMetrics(*serviceName, Memory, *json, *csv, *text, *graph, *stream, *mins, ro, New(settings), services.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text)] [--stream] [-m]"
+			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text | --graph)] [--stream] [-m] " + RangeSpec
 		}
 	},
 }
@@ -120,35 +152,48 @@ var NetworkInSubCmd = models.Command{
 	ShortHelp: "Print service and environment received network data metrics in your local time zone",
 	LongHelp: "<code>metrics network-in</code> prints out received network metrics for your environment or individual services. " +
 		"You can print out metrics in csv, json, plain text, or spark lines format. " +
-		"If you want plain text format, omit the <code>--json</code> and <code>--csv</code> flags. " +
+		"If you want plain text format, omit the <code>--json</code>, <code>--csv</code>, and <code>--graph</code> flags. " +
 		"You can only stream metrics using plain text or spark lines formats. " +
+		"Use <code>--graph</code> for spark lines of every service, or a chart of a single service, that are redrawn in place with every update when streaming. " +
+		"To retrieve a time range within the last day (1440 minutes), use <code>--since</code> and optionally <code>--until</code>. Time ranges are averaged into samples of <code>--step</code>. " +
+		"Add <code>--summary</code> to print the min, avg, max, p50, p95, and p99 of each service instead, " +
+		"or use <code>--compare</code> to compare the <code>--window</code> before a point in time, such as a deploy, with the <code>--window</code> after it. " +
 		"To print out metrics for every service in your environment, omit the <code>SERVICE_NAME</code> argument. " +
 		"Otherwise you may choose a service, such as an app service, to retrieve metrics for. Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" metrics network-in\n" +
 		"datica -E \"<your_env_name>\" metrics network-in app01 --stream\n" +
 		"datica -E \"<your_env_name>\" metrics network-in --json\n" +
-		"datica -E \"<your_env_name>\" metrics network-in db01 --csv -m 60\n</pre>",
+		"datica -E \"<your_env_name>\" metrics network-in app01 --graph --stream\n" +
+		"datica -E \"<your_env_name>\" metrics network-in db01 --csv -m 60\n" +
+		"datica -E \"<your_env_name>\" metrics network-in app01 --since 12h --summary\n" +
+		"datica -E \"<your_env_name>\" metrics network-in --compare \"2017-06-01 14:00\" --window 1h\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to print metrics for")
 			json := subCmd.BoolOpt("json", false, "Output the data as json")
 			csv := subCmd.BoolOpt("csv", false, "Output the data as csv")
 			text := subCmd.BoolOpt("text", true, "Output the data in plain text")
+			graph := subCmd.BoolOpt("graph", false, "Output the data as spark lines, or as a chart for a single service")
 			stream := subCmd.BoolOpt("stream", false, "Repeat calls once per minute until this process is interrupted.")
 			mins := subCmd.IntOpt("m mins", 1, "How many minutes worth of metrics to retrieve.")
+			rangeOpts := RangeOpts(subCmd)
 			subCmd.Action = func() {
+				ro, err := rangeOpts()
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdMetrics(*serviceName, NetworkIn, *json, *csv, *text, *stream, *mins, New(settings), services.New(settings))
+				err = CmdMetrics(*serviceName, NetworkIn, *json, *csv, *text, *graph, *stream, *mins, ro, New(settings), services.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text)] [--stream] [-m]"
+			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text | --graph)] [--stream] [-m] " + RangeSpec
 		}
 	},
 }
@@ -158,36 +203,141 @@ var NetworkOutSubCmd = models.Command{
 	ShortHelp: "Print service and environment transmitted network data metrics in your local time zone",
 	LongHelp: "<code>metrics network-out</code> prints out transmitted network metrics for your environment or individual services. " +
 		"You can print out metrics in csv, json, plain text, or spark lines format. " +
-		"If you want plain text format, simply omit the <code>--json</code> and <code>--csv</code> flags. " +
+		"If you want plain text format, simply omit the <code>--json</code>, <code>--csv</code>, and <code>--graph</code> flags. " +
 		"You can only stream metrics using plain text or spark lines formats. " +
+		"Use <code>--graph</code> for spark lines of every service, or a chart of a single service, that are redrawn in place with every update when streaming. " +
+		"To retrieve a time range within the last day (1440 minutes), use <code>--since</code> and optionally <code>--until</code>. Time ranges are averaged into samples of <code>--step</code>. " +
+		"Add <code>--summary</code> to print the min, avg, max, p50, p95, and p99 of each service instead, " +
+		"or use <code>--compare</code> to compare the <code>--window</code> before a point in time, such as a deploy, with the <code>--window</code> after it. " +
 		"To print out metrics for every service in your environment, omit the <code>SERVICE_NAME</code> argument. " +
 		"Otherwise you may choose a service, such as an app service, to retrieve metrics for. " +
 		"Here are some sample commands\n\n" +
 		"<pre>\ndatica -E \"<your_env_name>\" metrics network-out\n" +
 		"datica -E \"<your_env_name>\" metrics network-out app01 --stream\n" +
 		"datica -E \"<your_env_name>\" metrics network-out --json\n" +
-		"datica -E \"<your_env_name>\" metrics network-out db01 --csv -m 60\n</pre>",
+		"datica -E \"<your_env_name>\" metrics network-out app01 --graph --stream\n" +
+		"datica -E \"<your_env_name>\" metrics network-out db01 --csv -m 60\n" +
+		"datica -E \"<your_env_name>\" metrics network-out app01 --since 12h --summary\n" +
+		"datica -E \"<your_env_name>\" metrics network-out --compare \"2017-06-01 14:00\" --window 1h\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to print metrics for")
 			json := subCmd.BoolOpt("json", false, "Output the data as json")
 			csv := subCmd.BoolOpt("csv", false, "Output the data as csv")
 			text := subCmd.BoolOpt("text", true, "Output the data in plain text")
+			graph := subCmd.BoolOpt("graph", false, "Output the data as spark lines, or as a chart for a single service")
 			stream := subCmd.BoolOpt("stream", false, "Repeat calls once per minute until this process is interrupted.")
 			mins := subCmd.IntOpt("m mins", 1, "How many minutes worth of metrics to retrieve.")
+			rangeOpts := RangeOpts(subCmd)
+			subCmd.Action = func() {
+				ro, err := rangeOpts()
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdMetrics(*serviceName, NetworkOut, *json, *csv, *text, *graph, *stream, *mins, ro, New(settings), services.New(settings), output.New(settings))
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text | --graph)] [--stream] [-m] " + RangeSpec
+		}
+	},
+}
+
+var ServeSubCmd = models.Command{
+	Name:      "serve",
+	ShortHelp: "Serve environment metrics to Prometheus",
+	LongHelp: "<code>metrics serve</code> retrieves the CPU, memory, and network metrics of every service in your environment at a regular interval " +
+		"and serves the most recent values on <code>/metrics</code> in the OpenMetrics text format so they can be scraped by Prometheus. " +
+		"Every value is labeled with the environment name, the service label and type, and the ID of the job it belongs to. " +
+		"The <code>datica_scrape_success</code> metric is 0 when the metrics could not be retrieved. " +
+		"The command runs until it is interrupted. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" metrics serve\n" +
+		"datica -E \"<your_env_name>\" metrics serve --listen 127.0.0.1:9102 --interval 30\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(subCmd *cli.Cmd) {
+			listen := subCmd.StringOpt("listen", ":9102", "The address to serve metrics on")
+			interval := subCmd.IntOpt("interval", 60, "How many seconds to wait between retrieving metrics")
+			subCmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err := CmdServe(*listen, *interval, settings.EnvironmentName, New(settings))
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			subCmd.Spec = "[--listen] [--interval]"
+		}
+	},
+}
+
+var WatchSubCmd = models.Command{
+	Name:      "watch",
+	ShortHelp: "Alert when service metrics cross a threshold",
+	LongHelp: "<code>metrics watch</code> retrieves the metrics of your environment at a regular interval and checks them against one or more rules. " +
+		"A rule looks like <code>cpu > 80% for 5m</code> and is made up of a metric (<code>cpu</code>, <code>memory</code>, <code>network-in</code>, or <code>network-out</code>), " +
+		"a comparison (<code>></code>, <code>>=</code>, <code><</code>, or <code><=</code>), a threshold, and optionally how long the threshold must be crossed. " +
+		"CPU thresholds are in percent. Memory thresholds are a percent of the service's memory or a size such as <code>512MB</code>. " +
+		"Network thresholds are a size such as <code>500KB</code> received or transmitted per sample. " +
+		"CPU and memory are checked for the busiest job of a service, network data for all jobs of a service together. " +
+		"Every breach and recovery is printed. " +
+		"Use <code>--exit-on-breach</code> to exit with an error on the first breach, <code>--exec</code> to run a command with the event (<code>breach</code> or <code>recovery</code>) as its last argument and a JSON description of it on stdin, " +
+		"or <code>--webhook</code> to POST the same JSON to a URL. " +
+		"Commands and webhooks that take longer than the global <code>--timeout</code> are stopped so the watch keeps going. " +
+		"To watch every service in your environment, omit the <code>SERVICE_NAME</code> argument. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" metrics watch --rule \"cpu > 80% for 5m\" --rule \"memory > 90%\"\n" +
+		"datica -E \"<your_env_name>\" metrics watch app01 --rule \"network-out > 10MB\" --exit-on-breach\n" +
+		"datica -E \"<your_env_name>\" metrics watch app01 db01 --rule \"memory >= 95%\" --webhook https://example.com/alerts\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(subCmd *cli.Cmd) {
+			serviceNames := subCmd.StringsArg("SERVICE_NAME", nil, "The names of the services to watch")
+			rules := subCmd.StringsOpt("r rule", nil, "A rule to check, such as \"cpu > 80% for 5m\". May be given multiple times")
+			interval := subCmd.IntOpt("interval", 60, "How many seconds to wait between retrieving metrics")
+			exitOnBreach := subCmd.BoolOpt("exit-on-breach", false, "Exit with an error when a rule is breached")
+			command := subCmd.StringOpt("exec", "", "A command to run on every breach and recovery")
+			webhook := subCmd.StringOpt("webhook", "", "A URL to POST every breach and recovery to")
 			subCmd.Action = func() {
+				w := &Watcher{
+					EnvironmentName: settings.EnvironmentName,
+					ExitOnBreach:    *exitOnBreach,
+					Command:         *command,
+					Webhook:         *webhook,
+				}
+				for _, text := range *rules {
+					rule, err := ParseRule(text)
+					if err != nil {
+						logrus.Fatal(err.Error())
+					}
+					w.Rules = append(w.Rules, rule)
+				}
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdMetrics(*serviceName, NetworkOut, *json, *csv, *text, *stream, *mins, New(settings), services.New(settings))
+				client, err := network.Client(settings.Network)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				w.Client = client
+				w.Timeout = settings.HTTPTimeout
+				err = CmdWatch(*serviceNames, *interval, w, New(settings), services.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "[SERVICE_NAME] [(--json | --csv | --text)] [--stream] [-m]"
+			subCmd.Spec = "[SERVICE_NAME...] -r... [--interval] [--exit-on-breach] [--exec] [--webhook]"
 		}
 	},
 }
@@ -196,6 +346,8 @@ var NetworkOutSubCmd = models.Command{
 type IMetrics interface {
 	RetrieveEnvironmentMetrics(mins int) (*[]models.Metrics, error)
 	RetrieveServiceMetrics(mins int, svcID string) (*models.Metrics, error)
+	RetrieveEnvironmentMetricsRange(start, end time.Time) (*[]models.Metrics, error)
+	RetrieveServiceMetricsRange(svcID string, start, end time.Time) (*models.Metrics, error)
 }
 
 // SMetrics is a concrete implementation of IMetrics
@@ -216,3 +216,40 @@ func (csv *CSVTransformer) TransformSingleNetworkOut(metric *models.Metrics) {
 		logrus.Println(csv.Buffer.String())
 	}
 }
+
+// TransformSummary transforms the statistics of each service into csv format.
+func (csv *CSVTransformer) TransformSummary(summaries []Summary) {
+	csv.Writer.Write(append([]string{"service_name", "metric", "unit", "start", "end"}, statNames...))
+	for _, s := range summaries {
+		row := []string{s.ServiceName, s.Metric, s.Unit, fmt.Sprintf("%d", toTS(s.Start)), fmt.Sprintf("%d", toTS(s.End))}
+		for _, v := range s.values() {
+			row = append(row, fmt.Sprintf("%f", v))
+		}
+		csv.Writer.Write(row)
+	}
+	csv.Writer.Flush()
+	logrus.Println(csv.Buffer.String())
+}
+
+// TransformComparison transforms the statistics of each service before and
+// after a point in time, and the change between them, into csv format with a
+// row per statistic.
+func (csv *CSVTransformer) TransformComparison(comparisons []Comparison) {
+	csv.Writer.Write([]string{"service_name", "metric", "unit", "stat", "before", "after", "delta"})
+	for _, c := range comparisons {
+		before, after, delta := c.Before.values(), c.After.values(), c.Delta.values()
+		for i, name := range statNames {
+			csv.Writer.Write([]string{
+				c.After.ServiceName,
+				c.After.Metric,
+				c.After.Unit,
+				name,
+				fmt.Sprintf("%f", before[i]),
+				fmt.Sprintf("%f", after[i]),
+				fmt.Sprintf("%f", delta[i]),
+			})
+		}
+	}
+	csv.Writer.Flush()
+	logrus.Println(csv.Buffer.String())
+}
@@ -0,0 +1,433 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/models"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// sparks are the bars of a sparkline from lowest to highest
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// chartHeight is the number of rows of a single service chart
+const chartHeight = 10
+
+// maxGraphPoints is how many data points are kept per service while streaming
+const maxGraphPoints = 1440
+
+type graphPoint struct {
+	TS    int
+	Value float64
+}
+
+// GraphTransformer is a concrete implementation of Transformer transforming
+// data into sparklines for an entire environment and an ASCII chart for a
+// single service. When streaming, new data points are added to the ones
+// already shown and the previous output is redrawn in place.
+type GraphTransformer struct {
+	Stream bool
+	// Width is the number of columns to fill. Defaults to the width of the
+	// terminal, or 80 when the output is not a terminal.
+	Width int
+	// Writer defaults to the output of logrus.
+	Writer io.Writer
+
+	history    map[string][]graphPoint
+	linesDrawn int
+}
+
+// TransformGroupCPU transforms an entire environment's cpu data into a
+// sparkline per service.
+func (graph *GraphTransformer) TransformGroupCPU(metrics *[]models.Metrics) {
+	graph.group(metrics, CPU)
+}
+
+// TransformGroupMemory transforms an entire environment's memory data into a
+// sparkline per service.
+func (graph *GraphTransformer) TransformGroupMemory(metrics *[]models.Metrics) {
+	graph.group(metrics, Memory)
+}
+
+// TransformGroupNetworkIn transforms an entire environment's received network
+// data into a sparkline per service.
+func (graph *GraphTransformer) TransformGroupNetworkIn(metrics *[]models.Metrics) {
+	graph.group(metrics, NetworkIn)
+}
+
+// TransformGroupNetworkOut transforms an entire environment's transmitted
+// network data into a sparkline per service.
+func (graph *GraphTransformer) TransformGroupNetworkOut(metrics *[]models.Metrics) {
+	graph.group(metrics, NetworkOut)
+}
+
+// TransformSingleCPU transforms a single service's cpu data into a chart.
+func (graph *GraphTransformer) TransformSingleCPU(metric *models.Metrics) {
+	graph.single(metric, CPU)
+}
+
+// TransformSingleMemory transforms a single service's memory data into a
+// chart.
+func (graph *GraphTransformer) TransformSingleMemory(metric *models.Metrics) {
+	graph.single(metric, Memory)
+}
+
+// TransformSingleNetworkIn transforms a single service's received network data
+// into a chart.
+func (graph *GraphTransformer) TransformSingleNetworkIn(metric *models.Metrics) {
+	graph.single(metric, NetworkIn)
+}
+
+// TransformSingleNetworkOut transforms a single service's transmitted network
+// data into a chart.
+func (graph *GraphTransformer) TransformSingleNetworkOut(metric *models.Metrics) {
+	graph.single(metric, NetworkOut)
+}
+
+func (graph *GraphTransformer) group(metrics *[]models.Metrics, metricType MetricType) {
+	labelWidth := 0
+	for _, metric := range *metrics {
+		if _, ok := blacklist[metric.ServiceLabel]; !ok && len(metric.ServiceLabel) > labelWidth {
+			labelWidth = len(metric.ServiceLabel)
+		}
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s (%s)\n", metricsTypeToString(metricType), unit(metricType))
+	for _, metric := range *metrics {
+		if _, ok := blacklist[metric.ServiceLabel]; ok {
+			continue
+		}
+		points := graph.addPoints(&metric, metricType)
+		if len(points) == 0 {
+			fmt.Fprintf(buf, "%-*s  no data\n", labelWidth, metric.ServiceLabel)
+			continue
+		}
+		last := formatValue(points[len(points)-1].Value)
+		sparkWidth := graph.width() - labelWidth - len(last) - 4
+		fmt.Fprintf(buf, "%-*s  %s  %s\n", labelWidth, metric.ServiceLabel, sparkline(values(points), sparkWidth), last)
+	}
+	graph.draw(buf)
+}
+
+func (graph *GraphTransformer) single(metric *models.Metrics, metricType MetricType) {
+	points := graph.addPoints(metric, metricType)
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %s (%s)\n", metric.ServiceLabel, metricsTypeToString(metricType), unit(metricType))
+	if len(points) == 0 {
+		buf.WriteString("no data\n")
+	} else {
+		buf.WriteString(chart(points, graph.width(), chartHeight))
+	}
+	graph.draw(buf)
+}
+
+// addPoints merges the service's data points into the ones already shown and
+// returns all of them. Points of different jobs at the same time are added
+// together so the graph shows the service as a whole.
+func (graph *GraphTransformer) addPoints(metric *models.Metrics, metricType MetricType) []graphPoint {
+	if graph.history == nil {
+		graph.history = map[string][]graphPoint{}
+	}
+	key := metric.ServiceID + metric.ServiceLabel
+	points := graph.history[key]
+	if !graph.Stream {
+		points = nil
+	}
+	byTS := map[int]float64{}
+	for _, p := range metricPoints(metric, metricType) {
+		if len(points) == 0 || p.TS > points[len(points)-1].TS {
+			byTS[p.TS] += p.Value
+		}
+	}
+	newPoints := []graphPoint{}
+	for ts, value := range byTS {
+		newPoints = append(newPoints, graphPoint{TS: ts, Value: value})
+	}
+	sort.Slice(newPoints, func(i, j int) bool { return newPoints[i].TS < newPoints[j].TS })
+	points = append(points, newPoints...)
+	if len(points) > maxGraphPoints {
+		points = points[len(points)-maxGraphPoints:]
+	}
+	graph.history[key] = points
+	return points
+}
+
+// draw writes the output, first clearing the previous output when streaming.
+func (graph *GraphTransformer) draw(buf *bytes.Buffer) {
+	out := graph.Writer
+	if out == nil {
+		out = logrus.StandardLogger().Out
+	}
+	if graph.Stream && graph.linesDrawn > 0 {
+		fmt.Fprintf(out, "\033[%dA\033[J", graph.linesDrawn)
+	}
+	graph.linesDrawn = strings.Count(buf.String(), "\n")
+	out.Write(buf.Bytes())
+}
+
+func (graph *GraphTransformer) width() int {
+	if graph.Width > 0 {
+		return graph.Width
+	}
+	if width, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// metricPoints returns the value charted for the given metric type at each
+// point in time, in the units used by the text format.
+func metricPoints(metric *models.Metrics, metricType MetricType) []graphPoint {
+	points := []graphPoint{}
+	if metric.Data == nil {
+		return points
+	}
+	switch metricType {
+	case CPU:
+		if metric.Data.CPUUsage != nil {
+			for _, data := range *metric.Data.CPUUsage {
+				points = append(points, graphPoint{TS: data.TS, Value: data.CorePercent * 100.0})
+			}
+		}
+	case Memory:
+		if metric.Data.MemoryUsage != nil {
+			for _, data := range *metric.Data.MemoryUsage {
+				points = append(points, graphPoint{TS: data.TS, Value: data.AVG / 1024.0})
+			}
+		}
+	case NetworkIn:
+		if metric.Data.NetworkUsage != nil {
+			for _, data := range *metric.Data.NetworkUsage {
+				points = append(points, graphPoint{TS: data.TS, Value: data.RXKB})
+			}
+		}
+	case NetworkOut:
+		if metric.Data.NetworkUsage != nil {
+			for _, data := range *metric.Data.NetworkUsage {
+				points = append(points, graphPoint{TS: data.TS, Value: data.TXKB})
+			}
+		}
+	}
+	return points
+}
+
+func unit(metricType MetricType) string {
+	switch metricType {
+	case CPU:
+		return "%"
+	case Memory:
+		return "MB"
+	default:
+		return "KB"
+	}
+}
+
+func formatValue(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func values(points []graphPoint) []float64 {
+	v := make([]float64, len(points))
+	for i, p := range points {
+		v[i] = p.Value
+	}
+	return v
+}
+
+func maxValue(v []float64) float64 {
+	max := 0.0
+	for _, value := range v {
+		if value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+// sparkline renders the last width values as bars scaled from zero to the
+// highest value.
+func sparkline(v []float64, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	if len(v) > width {
+		v = v[len(v)-width:]
+	}
+	max := maxValue(v)
+	line := make([]rune, len(v))
+	for i, value := range v {
+		line[i] = sparks[scale(value, max, len(sparks))]
+	}
+	return string(line)
+}
+
+// scale maps a value between zero and max to one of n steps.
+func scale(value, max float64, n int) int {
+	if max <= 0 || value <= 0 {
+		return 0
+	}
+	step := int(value/max*float64(n-1) + 0.5)
+	if step >= n {
+		step = n - 1
+	}
+	return step
+}
+
+// chart renders the points as an ASCII chart with the values on the y-axis
+// and the local time on the x-axis. The points are stretched to fill the
+// width, or the most recent ones are shown when they do not fit.
+func chart(points []graphPoint, width, height int) string {
+	max := maxValue(values(points))
+	labels := []string{formatValue(max), formatValue(max / 2), formatValue(0)}
+	labelWidth := 0
+	for _, l := range labels {
+		if len(l) > labelWidth {
+			labelWidth = len(l)
+		}
+	}
+	plotWidth := width - labelWidth - 2
+	if plotWidth < 1 {
+		plotWidth = 1
+	}
+	if len(points) > plotWidth {
+		points = points[len(points)-plotWidth:]
+	}
+	columns := make([]int, plotWidth)
+	for col := range columns {
+		columns[col] = scale(points[col*len(points)/plotWidth].Value, max, height)
+	}
+
+	buf := &bytes.Buffer{}
+	for row := height - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case height - 1:
+			label = labels[0]
+		case (height - 1) / 2:
+			label = labels[1]
+		case 0:
+			label = labels[2]
+		}
+		fmt.Fprintf(buf, "%*s |", labelWidth, label)
+		line := make([]byte, plotWidth)
+		for col, value := range columns {
+			switch {
+			case value == row:
+				line[col] = '*'
+			case value > row:
+				line[col] = ':'
+			default:
+				line[col] = ' '
+			}
+		}
+		buf.Write(bytes.TrimRight(line, " "))
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(buf, "%*s +%s\n", labelWidth, "", strings.Repeat("-", plotWidth))
+	start := formatTime(points[0].TS)
+	end := formatTime(points[len(points)-1].TS)
+	gap := plotWidth - len(start) - len(end)
+	if gap < 1 {
+		fmt.Fprintf(buf, "%*s  %s\n", labelWidth, "", end)
+	} else {
+		fmt.Fprintf(buf, "%*s  %s%s%s\n", labelWidth, "", start, strings.Repeat(" ", gap), end)
+	}
+	return buf.String()
+}
+
+func formatTime(ts int) string {
+	return time.Unix(int64(ts/1000.0), 0).Format("15:04")
+}
+
+// TransformSummary transforms the statistics of each service into a bar per
+// service on a scale shared by all services. The bar spans from the min to
+// the max with the p50 to p95 range drawn as = and the p50 marked by |.
+func (graph *GraphTransformer) TransformSummary(summaries []Summary) {
+	if len(summaries) == 0 {
+		return
+	}
+	max, labelWidth := 0.0, 0
+	for _, s := range summaries {
+		max = math.Max(max, s.Max)
+		if len(s.ServiceName) > labelWidth {
+			labelWidth = len(s.ServiceName)
+		}
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s (%s) from %s to %s, 0 to %s\n", summaries[0].Metric, summaries[0].Unit, formatTimestamp(summaries[0].Start), formatTimestamp(summaries[0].End), formatValue(max))
+	barWidth := graph.width() - labelWidth - rangeSuffixWidth
+	for _, s := range summaries {
+		writeRange(buf, fmt.Sprintf("%-*s", labelWidth, s.ServiceName), s.Stats, max, barWidth, "")
+	}
+	graph.draw(buf)
+}
+
+// TransformComparison transforms the statistics of each service before and
+// after a point in time into two bars per service, drawn like
+// TransformSummary.
+func (graph *GraphTransformer) TransformComparison(comparisons []Comparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+	max, labelWidth := 0.0, 0
+	for _, c := range comparisons {
+		max = math.Max(max, math.Max(c.Before.Max, c.After.Max))
+		if len(c.After.ServiceName) > labelWidth {
+			labelWidth = len(c.After.ServiceName)
+		}
+	}
+	first := comparisons[0]
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s (%s) before and after %s, 0 to %s\n", first.After.Metric, first.After.Unit, formatTimestamp(first.After.Start), formatValue(max))
+	barWidth := graph.width() - labelWidth - len(" before") - rangeSuffixWidth
+	for _, c := range comparisons {
+		writeRange(buf, fmt.Sprintf("%-*s before", labelWidth, c.Before.ServiceName), c.Before.Stats, max, barWidth, "")
+		change := ""
+		if c.Before.P95 != 0 {
+			change = fmt.Sprintf(" (%+.1f%%)", c.Delta.P95/c.Before.P95*100.0)
+		}
+		writeRange(buf, fmt.Sprintf("%-*s after ", labelWidth, ""), c.After.Stats, max, barWidth, change)
+	}
+	graph.draw(buf)
+}
+
+// rangeSuffixWidth is the room left next to range bars for the p95
+const rangeSuffixWidth = 28
+
+func writeRange(buf *bytes.Buffer, label string, stats Stats, max float64, width int, suffix string) {
+	if stats.Samples == 0 {
+		fmt.Fprintf(buf, "%s  no data\n", label)
+		return
+	}
+	if width < 1 {
+		width = 1
+	}
+	fmt.Fprintf(buf, "%s  %s  p95 %s%s\n", label, rangeBar(stats, max, width), formatValue(stats.P95), suffix)
+}
+
+// rangeBar draws the range of the stats on a scale from zero to max.
+func rangeBar(stats Stats, max float64, width int) string {
+	pos := func(v float64) int {
+		if max <= 0 {
+			return 0
+		}
+		return int(v/max*float64(width-1) + 0.5)
+	}
+	bar := []byte(strings.Repeat(" ", width))
+	for i := pos(stats.Min); i <= pos(stats.Max); i++ {
+		bar[i] = '-'
+	}
+	for i := pos(stats.P50); i <= pos(stats.P95); i++ {
+		bar[i] = '='
+	}
+	bar[pos(stats.P50)] = '|'
+	return string(bar)
+}
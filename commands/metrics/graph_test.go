@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+func cpuMetrics(label string, percents ...float64) models.Metrics {
+	usage := []models.CPUUsage{}
+	for i, p := range percents {
+		usage = append(usage, models.CPUUsage{JobID: "job1", CorePercent: p, TS: (i + 1) * 60000})
+	}
+	return models.Metrics{ServiceID: label + "-id", ServiceLabel: label, Data: &models.MetricsData{CPUUsage: &usage}}
+}
+
+func TestSparkline(t *testing.T) {
+	test.AssertEquals(t, "▁▅█", sparkline([]float64{0, 0.5, 1}, 10))
+	test.AssertEquals(t, "▅█", sparkline([]float64{0, 0.5, 1}, 2))
+	test.AssertEquals(t, "▁▁", sparkline([]float64{0, 0}, 10))
+}
+
+func TestGraphGroup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	graph := &GraphTransformer{Width: 40, Writer: buf}
+	graph.TransformGroupCPU(&[]models.Metrics{
+		cpuMetrics("app01", 0.1, 0.2, 0.4),
+		cpuMetrics("logging", 0.5),
+		{ServiceLabel: "db01"},
+	})
+	test.AssertEquals(t, "CPU (%)\napp01  ▃▅█  40.00\ndb01   no data\n", buf.String())
+}
+
+func TestGraphSingle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	graph := &GraphTransformer{Width: 20, Writer: buf}
+	metric := cpuMetrics("app01", 0, 0.5, 1)
+	graph.TransformSingleCPU(&metric)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != chartHeight+3 {
+		t.Fatalf("Expected %d lines but got %q", chartHeight+3, buf.String())
+	}
+	test.AssertEquals(t, "app01 CPU (%)", lines[0])
+	test.AssertEquals(t, "100.00 |        ****", lines[1])
+	test.AssertEquals(t, "  0.00 |****::::::::", lines[chartHeight])
+	test.AssertEquals(t, "       +------------", lines[chartHeight+1])
+}
+
+func TestGraphStream(t *testing.T) {
+	buf := &bytes.Buffer{}
+	graph := &GraphTransformer{Stream: true, Width: 40, Writer: buf}
+	graph.TransformGroupCPU(&[]models.Metrics{cpuMetrics("app01", 0.1, 0.2)})
+	buf.Reset()
+	next := cpuMetrics("app01", 0.1, 0.2, 0.4)
+	graph.TransformGroupCPU(&[]models.Metrics{next})
+	test.AssertEquals(t, "\033[2A\033[JCPU (%)\napp01  ▃▅█  40.00\n", buf.String())
+}
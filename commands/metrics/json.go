@@ -1,15 +1,16 @@
 package metrics
 
 import (
-	"encoding/json"
-
-	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 )
 
 // JSONTransformer is a concrete implementation of Transformer transforming data
-// into pretty printed JSON format.
-type JSONTransformer struct{}
+// into pretty printed JSON format. When the global output format is YAML, the
+// same structures are rendered as YAML instead.
+type JSONTransformer struct {
+	Output output.IOutput
+}
 
 type cpu struct {
 	ServiceName string  `json:"service_name,omitempty"`
@@ -51,8 +52,7 @@ func (j *JSONTransformer) TransformGroupCPU(metrics *[]models.Metrics) {
 			}
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformGroupMemory transforms an entire environment's memory data into json
@@ -67,8 +67,7 @@ func (j *JSONTransformer) TransformGroupMemory(metrics *[]models.Metrics) {
 			}
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformGroupNetworkIn transforms an entire environment's received network
@@ -83,8 +82,7 @@ func (j *JSONTransformer) TransformGroupNetworkIn(metrics *[]models.Metrics) {
 			}
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformGroupNetworkOut transforms an entire environment's transmitted
@@ -99,8 +97,7 @@ func (j *JSONTransformer) TransformGroupNetworkOut(metrics *[]models.Metrics) {
 			}
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformSingleCPU transforms a single service's cpu data into json format.
@@ -111,8 +108,7 @@ func (j *JSONTransformer) TransformSingleCPU(metric *models.Metrics) {
 			data = append(data, cpu{TS: d.TS, Percentage: d.CorePercent})
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformSingleMemory transforms a single service's memory data into json
@@ -124,8 +120,7 @@ func (j *JSONTransformer) TransformSingleMemory(metric *models.Metrics) {
 			data = append(data, mem{TS: d.TS, Min: d.Min / 1024.0, Max: d.Max / 1024.0, AVG: d.AVG / 1024.0, Total: float64(metric.Size.RAM) * 1024.0})
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformSingleNetworkIn transforms a single service's received network data
@@ -137,8 +132,7 @@ func (j *JSONTransformer) TransformSingleNetworkIn(metric *models.Metrics) {
 			data = append(data, netin{TS: d.TS, RXKB: d.RXKB, RXPackets: d.RXPackets})
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
 }
 
 // TransformSingleNetworkOut transforms a single service's transmitted network
@@ -150,6 +144,17 @@ func (j *JSONTransformer) TransformSingleNetworkOut(metric *models.Metrics) {
 			data = append(data, netout{TS: d.TS, TXKB: d.TXKB, TXPackets: d.TXPackets})
 		}
 	}
-	b, _ := json.MarshalIndent(data, "", "    ")
-	logrus.Println(string(b))
+	j.Output.Render(data)
+}
+
+// TransformSummary transforms the statistics of each service into json
+// format.
+func (j *JSONTransformer) TransformSummary(summaries []Summary) {
+	j.Output.Render(summaries)
+}
+
+// TransformComparison transforms the statistics of each service before and
+// after a point in time, and the change between them, into json format.
+func (j *JSONTransformer) TransformComparison(comparisons []Comparison) {
+	j.Output.Render(comparisons)
 }
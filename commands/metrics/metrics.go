@@ -8,6 +8,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 )
 
@@ -29,20 +30,33 @@ type Transformer interface {
 	TransformSingleMemory(*models.Metrics)
 	TransformSingleNetworkIn(*models.Metrics)
 	TransformSingleNetworkOut(*models.Metrics)
+	TransformSummary([]Summary)
+	TransformComparison([]Comparison)
 }
 
 // CmdMetrics prints out metrics for a given service or if the service is not
-// specified, metrics for the entire environment are printed.
-func CmdMetrics(svcName string, metricType MetricType, jsonFlag, csvFlag, textFlag, streamFlag bool, mins int, im IMetrics, is services.IServices) error {
-	if streamFlag && (jsonFlag || csvFlag || mins != 1) {
+// specified, metrics for the entire environment are printed. When ro selects
+// a time range, the metrics of that range are printed instead of the last
+// mins minutes.
+func CmdMetrics(svcName string, metricType MetricType, jsonFlag, csvFlag, textFlag, graphFlag, streamFlag bool, mins int, ro RangeOptions, im IMetrics, is services.IServices, iout output.IOutput) error {
+	if streamFlag && (jsonFlag || csvFlag || iout.Structured() || (mins != 1 && !graphFlag)) {
 		return fmt.Errorf("--stream cannot be used with CSV or JSON formats and multiple records")
 	}
+	if ro.IsRange() && (streamFlag || mins != 1) {
+		return fmt.Errorf("--since and --compare cannot be used with --stream or --mins")
+	}
 	if mins > 1440 {
-		return fmt.Errorf("--mins cannot be greater than 1440")
+		return fmt.Errorf("--mins cannot be greater than 1440, the metrics API only returns the last 1440 minutes")
 	}
 	var mt Transformer
 	if jsonFlag {
-		mt = &JSONTransformer{}
+		mt = &JSONTransformer{
+			Output: &output.SOutput{Format: output.FormatJSON, Writer: logrus.StandardLogger().Out},
+		}
+	} else if iout.Structured() {
+		mt = &JSONTransformer{
+			Output: iout,
+		}
 	} else if csvFlag {
 		buffer := &bytes.Buffer{}
 		mt = &CSVTransformer{
@@ -51,6 +65,11 @@ func CmdMetrics(svcName string, metricType MetricType, jsonFlag, csvFlag, textFl
 			Buffer:         buffer,
 			Writer:         csv.NewWriter(buffer),
 		}
+	} else if graphFlag {
+		mt = &GraphTransformer{
+			Stream: streamFlag,
+			Writer: logrus.StandardLogger().Out,
+		}
 	} else if textFlag {
 		mt = &TextTransformer{}
 	}
@@ -62,8 +81,14 @@ func CmdMetrics(svcName string, metricType MetricType, jsonFlag, csvFlag, textFl
 		if service == nil {
 			return fmt.Errorf("Could not find a service with the label \"%s\"", svcName)
 		}
+		if ro.IsRange() {
+			return CmdRangeMetrics(metricType, ro, service, mt, im)
+		}
 		return CmdServiceMetrics(metricType, streamFlag, mins, service, mt, im)
 	}
+	if ro.IsRange() {
+		return CmdRangeMetrics(metricType, ro, nil, mt, im)
+	}
 	return CmdEnvironmentMetrics(metricType, streamFlag, mins, mt, im)
 }
 
@@ -0,0 +1,540 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daticahealth/cli/models"
+	"github.com/jault3/mow.cli"
+)
+
+// RangeSpec is the part of a command spec for the options added by RangeOpts
+const RangeSpec = "[--since] [--until] [--step] [--summary] [--compare] [--window]"
+
+// maxRange is how far back metrics can be retrieved, the metrics API returns
+// at most the last 1440 minutes
+const maxRange = 1440 * time.Minute
+
+// maxSamples is how many samples per job are printed at most, the step is
+// made larger to stay below it
+const maxSamples = 1440
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// RangeOptions select metrics between two points in time instead of the last
+// few minutes.
+type RangeOptions struct {
+	Since time.Time
+	Until time.Time
+	// Step is the length of the averaged samples that are printed
+	Step time.Duration
+	// Summary prints statistics per service instead of samples
+	Summary bool
+	// Compare is the point in time at which the Window before it is compared
+	// with the Window after it
+	Compare time.Time
+	Window  time.Duration
+}
+
+// IsRange returns whether a time range or a comparison was requested.
+func (ro RangeOptions) IsRange() bool {
+	return !ro.Since.IsZero() || !ro.Compare.IsZero()
+}
+
+// RangeOpts adds the --since options to a command. The returned function
+// parses the options once the command runs.
+func RangeOpts(cmd *cli.Cmd) func() (RangeOptions, error) {
+	since := cmd.StringOpt("since", "", "Retrieve metrics since this time, either a date and time such as \"2017-06-01 14:00\" or a duration before now such as 12h. Metrics can only be retrieved for the last 1440 minutes")
+	until := cmd.StringOpt("until", "", "Retrieve metrics until this time, defaults to now. Uses the same formats as --since")
+	step := cmd.StringOpt("step", "", "Average the metrics retrieved with --since into samples of this length, such as 15m or 1h")
+	summary := cmd.BoolOpt("summary", false, "Print the min, avg, max, p50, p95, and p99 of each service instead of samples")
+	compare := cmd.StringOpt("compare", "", "Compare the metrics of the --window before this time with the --window after it. Uses the same formats as --since")
+	window := cmd.StringOpt("window", "1h", "The length of the windows compared by --compare")
+	return func() (RangeOptions, error) {
+		now := time.Now()
+		ro := RangeOptions{Summary: *summary}
+		var err error
+		if ro.Since, err = parseTime("--since", *since, now); err != nil {
+			return ro, err
+		}
+		if ro.Until, err = parseTime("--until", *until, now); err != nil {
+			return ro, err
+		}
+		if ro.Compare, err = parseTime("--compare", *compare, now); err != nil {
+			return ro, err
+		}
+		if ro.Step, err = parseDuration("--step", *step); err != nil {
+			return ro, err
+		}
+		if ro.Window, err = parseDuration("--window", *window); err != nil {
+			return ro, err
+		}
+		if !ro.Since.IsZero() && !ro.Compare.IsZero() {
+			return ro, fmt.Errorf("--since cannot be used with --compare")
+		}
+		if (!ro.Until.IsZero() || ro.Step > 0 || ro.Summary) && ro.Since.IsZero() && ro.Compare.IsZero() {
+			return ro, fmt.Errorf("--until, --step, and --summary can only be used with --since")
+		}
+		if ro.Until.IsZero() {
+			ro.Until = now
+		}
+		if !ro.Since.IsZero() && !ro.Since.Before(ro.Until) {
+			return ro, fmt.Errorf("--since must be before --until")
+		}
+		if !ro.Compare.IsZero() && ro.Window <= 0 {
+			return ro, fmt.Errorf("--window must be longer than 0")
+		}
+		return ro, nil
+	}
+}
+
+// parseTime parses a date and time in the local time zone, a duration before
+// now, or "now". An empty value is the zero time.
+func parseTime(name, value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if value == "now" {
+		return now, nil
+	}
+	if d, err := parseDuration(name, value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid %s \"%s\". Use a date and time such as \"2017-06-01 14:00\" or a duration such as 12h", name, value)
+}
+
+// parseDuration parses a positive Go duration. An empty value is zero.
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid %s \"%s\". Use a duration such as 15m or 12h", name, value)
+	}
+	return d, nil
+}
+
+// RetrieveEnvironmentMetricsRange retrieves metrics data for all services in
+// the associated environment between start and end. The metrics API only
+// returns the most recent minutes, so start must be within the last 1440
+// minutes.
+func (m *SMetrics) RetrieveEnvironmentMetricsRange(start, end time.Time) (*[]models.Metrics, error) {
+	mins, err := rangeMins(start)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := m.RetrieveEnvironmentMetrics(mins)
+	if err != nil {
+		return nil, err
+	}
+	filtered := []models.Metrics{}
+	samples := 0
+	for _, metric := range *metrics {
+		f, n := filterMetrics(&metric, start, end)
+		filtered = append(filtered, *f)
+		samples += n
+	}
+	if samples == 0 {
+		return nil, noSamplesError(start, end)
+	}
+	return &filtered, nil
+}
+
+// RetrieveServiceMetricsRange retrieves metrics data for the given service
+// between start and end. Like RetrieveEnvironmentMetricsRange, start must be
+// within the last 1440 minutes.
+func (m *SMetrics) RetrieveServiceMetricsRange(svcID string, start, end time.Time) (*models.Metrics, error) {
+	mins, err := rangeMins(start)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := m.RetrieveServiceMetrics(mins, svcID)
+	if err != nil {
+		return nil, err
+	}
+	filtered, samples := filterMetrics(metric, start, end)
+	if samples == 0 {
+		return nil, noSamplesError(start, end)
+	}
+	return filtered, nil
+}
+
+// rangeMins returns how many minutes of metrics to retrieve to reach back to
+// start. A minute of slack keeps "--since 24h" from being refused for the
+// time that passed since it was parsed.
+func rangeMins(start time.Time) (int, error) {
+	since := time.Since(start)
+	if since > maxRange+time.Minute {
+		return 0, fmt.Errorf("Metrics can only be retrieved for the last %d minutes, %s is further back", int(maxRange.Minutes()), start.Format("2006-01-02 15:04"))
+	}
+	mins := int(math.Ceil(since.Minutes()))
+	if mins > int(maxRange.Minutes()) {
+		mins = int(maxRange.Minutes())
+	}
+	if mins < 1 {
+		mins = 1
+	}
+	return mins, nil
+}
+
+func noSamplesError(start, end time.Time) error {
+	return fmt.Errorf("No metrics were retrieved between %s and %s", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))
+}
+
+// filterMetrics returns a copy of metric with only the samples between start
+// and end, and how many samples were kept.
+func filterMetrics(metric *models.Metrics, start, end time.Time) (*models.Metrics, int) {
+	result := *metric
+	if metric.Data == nil {
+		return &result, 0
+	}
+	result.Data = &models.MetricsData{}
+	startTS, endTS := toTS(start), toTS(end)
+	inRange := func(ts int) bool { return ts >= startTS && ts < endTS }
+	samples := 0
+	if metric.Data.CPUUsage != nil {
+		usage := []models.CPUUsage{}
+		for _, d := range *metric.Data.CPUUsage {
+			if inRange(d.TS) {
+				usage = append(usage, d)
+			}
+		}
+		result.Data.CPUUsage = &usage
+		samples += len(usage)
+	}
+	if metric.Data.MemoryUsage != nil {
+		usage := []models.MemoryUsage{}
+		for _, d := range *metric.Data.MemoryUsage {
+			if inRange(d.TS) {
+				usage = append(usage, d)
+			}
+		}
+		result.Data.MemoryUsage = &usage
+		samples += len(usage)
+	}
+	if metric.Data.NetworkUsage != nil {
+		usage := []models.NetworkUsage{}
+		for _, d := range *metric.Data.NetworkUsage {
+			if inRange(d.TS) {
+				usage = append(usage, d)
+			}
+		}
+		result.Data.NetworkUsage = &usage
+		samples += len(usage)
+	}
+	return &result, samples
+}
+
+// downsample averages the samples of each job into samples of the given
+// step, aligned to start. The min and max of memory samples are the lowest
+// and highest in the step instead.
+func downsample(metric *models.Metrics, start time.Time, step time.Duration) *models.Metrics {
+	if metric.Data == nil || step <= time.Minute {
+		return metric
+	}
+	startTS, stepTS := toTS(start), int(step/time.Millisecond)
+	bucket := func(jobID string, ts int) (string, int) {
+		bucketTS := startTS + (ts-startTS)/stepTS*stepTS
+		return fmt.Sprintf("%s-%d", jobID, bucketTS), bucketTS
+	}
+	result := *metric
+	result.Data = &models.MetricsData{}
+	if metric.Data.CPUUsage != nil {
+		sums := map[string]*models.CPUUsage{}
+		counts := map[string]float64{}
+		usage := []models.CPUUsage{}
+		for _, d := range *metric.Data.CPUUsage {
+			key, ts := bucket(d.JobID, d.TS)
+			if _, ok := sums[key]; !ok {
+				sums[key] = &models.CPUUsage{JobID: d.JobID, TS: ts}
+			}
+			sums[key].CorePercent += d.CorePercent
+			counts[key]++
+		}
+		for key, sum := range sums {
+			sum.CorePercent /= counts[key]
+			usage = append(usage, *sum)
+		}
+		sort.Slice(usage, func(i, j int) bool {
+			return usage[i].TS < usage[j].TS || (usage[i].TS == usage[j].TS && usage[i].JobID < usage[j].JobID)
+		})
+		result.Data.CPUUsage = &usage
+	}
+	if metric.Data.MemoryUsage != nil {
+		sums := map[string]*models.MemoryUsage{}
+		counts := map[string]float64{}
+		usage := []models.MemoryUsage{}
+		for _, d := range *metric.Data.MemoryUsage {
+			key, ts := bucket(d.JobID, d.TS)
+			sum, ok := sums[key]
+			if !ok {
+				sum = &models.MemoryUsage{JobID: d.JobID, TS: ts, Min: d.Min, Max: d.Max}
+				sums[key] = sum
+			}
+			sum.Min = math.Min(sum.Min, d.Min)
+			sum.Max = math.Max(sum.Max, d.Max)
+			sum.AVG += d.AVG
+			sum.Total += d.Total
+			counts[key]++
+		}
+		for key, sum := range sums {
+			sum.AVG /= counts[key]
+			sum.Total /= counts[key]
+			usage = append(usage, *sum)
+		}
+		sort.Slice(usage, func(i, j int) bool {
+			return usage[i].TS < usage[j].TS || (usage[i].TS == usage[j].TS && usage[i].JobID < usage[j].JobID)
+		})
+		result.Data.MemoryUsage = &usage
+	}
+	if metric.Data.NetworkUsage != nil {
+		sums := map[string]*models.NetworkUsage{}
+		counts := map[string]float64{}
+		usage := []models.NetworkUsage{}
+		for _, d := range *metric.Data.NetworkUsage {
+			key, ts := bucket(d.JobID, d.TS)
+			sum, ok := sums[key]
+			if !ok {
+				sum = &models.NetworkUsage{JobID: d.JobID, TS: ts}
+				sums[key] = sum
+			}
+			sum.RXDropped += d.RXDropped
+			sum.RXErrors += d.RXErrors
+			sum.RXKB += d.RXKB
+			sum.RXPackets += d.RXPackets
+			sum.TXDropped += d.TXDropped
+			sum.TXErrors += d.TXErrors
+			sum.TXKB += d.TXKB
+			sum.TXPackets += d.TXPackets
+			counts[key]++
+		}
+		for key, sum := range sums {
+			n := counts[key]
+			sum.RXDropped /= n
+			sum.RXErrors /= n
+			sum.RXKB /= n
+			sum.RXPackets /= n
+			sum.TXDropped /= n
+			sum.TXErrors /= n
+			sum.TXKB /= n
+			sum.TXPackets /= n
+			usage = append(usage, *sum)
+		}
+		sort.Slice(usage, func(i, j int) bool {
+			return usage[i].TS < usage[j].TS || (usage[i].TS == usage[j].TS && usage[i].JobID < usage[j].JobID)
+		})
+		result.Data.NetworkUsage = &usage
+	}
+	return &result
+}
+
+// Stats are the summary statistics of the samples of a service. The values
+// are in the units of the text format.
+type Stats struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+}
+
+// Summary is the statistics of a metric of a service between two points in
+// time.
+type Summary struct {
+	ServiceName string    `json:"service_name"`
+	Metric      string    `json:"metric"`
+	Unit        string    `json:"unit"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Stats
+}
+
+// Comparison is the change in a metric of a service from one time range to
+// another.
+type Comparison struct {
+	Before Summary `json:"before"`
+	After  Summary `json:"after"`
+	// Delta is After minus Before
+	Delta Stats `json:"delta"`
+}
+
+// statNames are the statistics in the order they are printed
+var statNames = []string{"samples", "min", "avg", "max", "p50", "p95", "p99"}
+
+// values returns the statistics in the order of statNames.
+func (s Stats) values() []float64 {
+	return []float64{float64(s.Samples), s.Min, s.Avg, s.Max, s.P50, s.P95, s.P99}
+}
+
+// summarize computes the statistics of the samples of all jobs of the
+// service.
+func summarize(metric *models.Metrics, metricType MetricType, start, end time.Time) Summary {
+	summary := Summary{
+		ServiceName: metric.ServiceLabel,
+		Metric:      metricsTypeToString(metricType),
+		Unit:        unit(metricType),
+		Start:       start,
+		End:         end,
+	}
+	v := values(metricPoints(metric, metricType))
+	if len(v) == 0 {
+		return summary
+	}
+	sort.Float64s(v)
+	sum := 0.0
+	for _, value := range v {
+		sum += value
+	}
+	summary.Stats = Stats{
+		Samples: len(v),
+		Min:     v[0],
+		Avg:     sum / float64(len(v)),
+		Max:     v[len(v)-1],
+		P50:     percentile(v, 50),
+		P95:     percentile(v, 95),
+		P99:     percentile(v, 99),
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100.0 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func compare(before, after Summary) Comparison {
+	return Comparison{
+		Before: before,
+		After:  after,
+		Delta: Stats{
+			Samples: after.Samples - before.Samples,
+			Min:     after.Min - before.Min,
+			Avg:     after.Avg - before.Avg,
+			Max:     after.Max - before.Max,
+			P50:     after.P50 - before.P50,
+			P95:     after.P95 - before.P95,
+			P99:     after.P99 - before.P99,
+		},
+	}
+}
+
+// CmdRangeMetrics prints the metrics of a service, or of the environment
+// when service is nil, between two points in time, their summary, or a
+// comparison of the windows before and after a point in time.
+func CmdRangeMetrics(metricType MetricType, ro RangeOptions, service *models.Service, t Transformer, im IMetrics) error {
+	retrieve := func(start, end time.Time) (*[]models.Metrics, error) {
+		if service == nil {
+			return im.RetrieveEnvironmentMetricsRange(start, end)
+		}
+		metric, err := im.RetrieveServiceMetricsRange(service.ID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		return &[]models.Metrics{*metric}, nil
+	}
+	summarizeAll := func(metrics *[]models.Metrics, start, end time.Time) []Summary {
+		summaries := []Summary{}
+		for _, metric := range *metrics {
+			if _, ok := blacklist[metric.ServiceLabel]; !ok {
+				summaries = append(summaries, summarize(&metric, metricType, start, end))
+			}
+		}
+		return summaries
+	}
+
+	if !ro.Compare.IsZero() {
+		beforeStart, afterEnd := ro.Compare.Add(-ro.Window), ro.Compare.Add(ro.Window)
+		before, err := retrieve(beforeStart, ro.Compare)
+		if err != nil {
+			return err
+		}
+		afterMetrics, err := retrieve(ro.Compare, afterEnd)
+		if err != nil {
+			return err
+		}
+		// services that only have metrics on one side are compared with an
+		// empty summary
+		after := summarizeAll(afterMetrics, ro.Compare, afterEnd)
+		afterSummaries := map[string]Summary{}
+		for _, s := range after {
+			afterSummaries[s.ServiceName] = s
+		}
+		comparisons := []Comparison{}
+		for _, b := range summarizeAll(before, beforeStart, ro.Compare) {
+			a, ok := afterSummaries[b.ServiceName]
+			if !ok {
+				a = Summary{ServiceName: b.ServiceName, Metric: b.Metric, Unit: b.Unit, Start: ro.Compare, End: afterEnd}
+			}
+			delete(afterSummaries, b.ServiceName)
+			comparisons = append(comparisons, compare(b, a))
+		}
+		for _, a := range after {
+			if _, ok := afterSummaries[a.ServiceName]; ok {
+				comparisons = append(comparisons, compare(Summary{ServiceName: a.ServiceName, Metric: a.Metric, Unit: a.Unit, Start: beforeStart, End: ro.Compare}, a))
+			}
+		}
+		t.TransformComparison(comparisons)
+		return nil
+	}
+
+	metrics, err := retrieve(ro.Since, ro.Until)
+	if err != nil {
+		return err
+	}
+	if ro.Summary {
+		t.TransformSummary(summarizeAll(metrics, ro.Since, ro.Until))
+		return nil
+	}
+	step := ro.Step
+	if step == 0 {
+		step = time.Duration(math.Ceil(ro.Until.Sub(ro.Since).Minutes()/maxSamples)) * time.Minute
+	}
+	for i := range *metrics {
+		(*metrics)[i] = *downsample(&(*metrics)[i], ro.Since, step)
+	}
+	if service != nil {
+		metric := &(*metrics)[0]
+		switch metricType {
+		case CPU:
+			t.TransformSingleCPU(metric)
+		case Memory:
+			t.TransformSingleMemory(metric)
+		case NetworkIn:
+			t.TransformSingleNetworkIn(metric)
+		case NetworkOut:
+			t.TransformSingleNetworkOut(metric)
+		}
+		return nil
+	}
+	switch metricType {
+	case CPU:
+		t.TransformGroupCPU(metrics)
+	case Memory:
+		t.TransformGroupMemory(metrics)
+	case NetworkIn:
+		t.TransformGroupNetworkIn(metrics)
+	case NetworkOut:
+		t.TransformGroupNetworkOut(metrics)
+	}
+	return nil
+}
+
+func toTS(t time.Time) int {
+	return int(t.UnixNano() / int64(time.Millisecond))
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2017, 6, 10, 12, 0, 0, 0, time.Local)
+	for value, expected := range map[string]time.Time{
+		"":                 {},
+		"now":              now,
+		"36h":              now.Add(-36 * time.Hour),
+		"2017-06-01":       time.Date(2017, 6, 1, 0, 0, 0, 0, time.Local),
+		"2017-06-01 14:30": time.Date(2017, 6, 1, 14, 30, 0, 0, time.Local),
+	} {
+		actual, err := parseTime("--since", value, now)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", value, err)
+		} else if !actual.Equal(expected) {
+			t.Errorf("Expected %q to be %s but got %s", value, expected, actual)
+		}
+	}
+	for _, value := range []string{"last tuesday", "7d"} {
+		if _, err := parseTime("--since", value, now); err == nil {
+			t.Errorf("Expected an error for the invalid time %q", value)
+		}
+	}
+}
+
+// serveRange answers every request for the environment's metrics with a cpu
+// sample at every whole minute of the requested minutes. The core percent is
+// 0.1 before split and 0.3 after it.
+func serveRange(mux *http.ServeMux, split int, requests *[]string) {
+	mux.HandleFunc("/environments/"+test.EnvID+"/metrics",
+		func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, r.URL.Query().Get("time"))
+			mins, _ := strconv.Atoi(strings.TrimSuffix(r.URL.Query().Get("time"), "m"))
+			now := toTS(time.Now().Truncate(time.Minute))
+			usage := []models.CPUUsage{}
+			for ts := now - mins*60000; ts <= now; ts += 60000 {
+				percent := 0.1
+				if ts >= split {
+					percent = 0.3
+				}
+				usage = append(usage, models.CPUUsage{JobID: "job1", CorePercent: percent, TS: ts})
+			}
+			b, _ := json.Marshal([]models.Metrics{{ServiceID: test.SvcID, ServiceLabel: "app01", Data: &models.MetricsData{CPUUsage: &usage}}})
+			w.Write(b)
+		},
+	)
+}
+
+func TestRetrieveEnvironmentMetricsRange(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	requests := []string{}
+	serveRange(mux, 0, &requests)
+
+	// between whole minutes so exactly one sample per minute is in the range
+	start := time.Now().Truncate(time.Minute).Add(-5*time.Hour + 30*time.Second)
+	metrics, err := New(test.GetSettings(baseURL.String())).RetrieveEnvironmentMetricsRange(start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test.AssertEquals(t, "1", strconv.Itoa(len(requests)))
+	test.AssertEquals(t, "1", strconv.Itoa(len(*metrics)))
+	usage := *(*metrics)[0].Data.CPUUsage
+	test.AssertEquals(t, "120", strconv.Itoa(len(usage)))
+	first := toTS(start) + 30000
+	for i, d := range usage {
+		if d.TS != first+i*60000 {
+			t.Fatalf("Expected sample %d to be at %d but it is at %d", i, first+i*60000, d.TS)
+		}
+	}
+}
+
+func TestRetrieveEnvironmentMetricsRangeErrors(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	requests := []string{}
+	serveRange(mux, 0, &requests)
+	im := New(test.GetSettings(baseURL.String()))
+
+	if _, err := im.RetrieveEnvironmentMetricsRange(time.Now().Add(-25*time.Hour), time.Now()); err == nil {
+		t.Errorf("Expected an error for a range further back than 1440 minutes")
+	}
+	test.AssertEquals(t, "0", strconv.Itoa(len(requests)))
+	// the metrics API only returns samples until now
+	if _, err := im.RetrieveEnvironmentMetricsRange(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)); err == nil {
+		t.Errorf("Expected an error for a range without samples")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	usage := []models.CPUUsage{}
+	for i := 100; i > 0; i-- {
+		usage = append(usage, models.CPUUsage{JobID: "job1", CorePercent: float64(i) / 100.0, TS: i * 60000})
+	}
+	s := summarize(&models.Metrics{ServiceLabel: "app01", Data: &models.MetricsData{CPUUsage: &usage}}, CPU, time.Time{}, time.Time{})
+	test.AssertEquals(t, "CPU %", s.Metric+" "+s.Unit)
+	test.AssertEquals(t, "100 1 50.5 100 50 95 99", fmt.Sprintf("%d %g %g %g %g %g %g", s.Samples, s.Min, s.Avg, s.Max, s.P50, s.P95, s.P99))
+}
+
+func TestDownsample(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	usage := []models.MemoryUsage{}
+	for i := 0; i < 6; i++ {
+		usage = append(usage, models.MemoryUsage{JobID: "job1", Min: float64(i), Max: float64(10 + i), AVG: float64(2 * i), TS: toTS(start) + i*60000})
+	}
+	metric := downsample(&models.Metrics{Data: &models.MetricsData{MemoryUsage: &usage}}, start, 3*time.Minute)
+	data := *metric.Data.MemoryUsage
+	test.AssertEquals(t, "2", strconv.Itoa(len(data)))
+	test.AssertEquals(t, fmt.Sprintf("%d 0 12 2", toTS(start)), fmt.Sprintf("%d %g %g %g", data[0].TS, data[0].Min, data[0].Max, data[0].AVG))
+	test.AssertEquals(t, fmt.Sprintf("%d 3 15 8", toTS(start)+180000), fmt.Sprintf("%d %g %g %g", data[1].TS, data[1].Min, data[1].Max, data[1].AVG))
+}
+
+func TestCompare(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	requests := []string{}
+	deploy := time.Now().Truncate(time.Minute).Add(-2*time.Hour + 30*time.Second)
+	serveRange(mux, toTS(deploy), &requests)
+
+	buf := &bytes.Buffer{}
+	transformer := &JSONTransformer{Output: &output.SOutput{Format: output.FormatJSON, Writer: buf}}
+	err := CmdRangeMetrics(CPU, RangeOptions{Compare: deploy, Window: time.Hour}, nil, transformer, New(test.GetSettings(baseURL.String())))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var comparisons []Comparison
+	if err = json.Unmarshal(buf.Bytes(), &comparisons); err != nil {
+		t.Fatalf("Unexpected output %s: %s", buf.String(), err)
+	}
+	test.AssertEquals(t, "1", strconv.Itoa(len(comparisons)))
+	c := comparisons[0]
+	test.AssertEquals(t, "app01", c.Before.ServiceName)
+	test.AssertEquals(t, "60 60 0", fmt.Sprintf("%d %d %d", c.Before.Samples, c.After.Samples, c.Delta.Samples))
+	test.AssertEquals(t, "10 30 20", fmt.Sprintf("%.0f %.0f %.0f", c.Before.P95, c.After.P95, c.Delta.P95))
+}
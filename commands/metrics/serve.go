@@ -0,0 +1,254 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/models"
+)
+
+const (
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// family is a metric exposed by the exporter. Every family is a gauge.
+type family struct {
+	Name string
+	Help string
+}
+
+var (
+	cpuUsage         = family{"datica_cpu_usage_ratio", "Share of a CPU core used by the job."}
+	memoryUsage      = family{"datica_memory_usage_bytes", "Average memory used by the job."}
+	memoryMax        = family{"datica_memory_max_bytes", "Highest memory used by the job."}
+	memoryLimit      = family{"datica_memory_limit_bytes", "Memory available to each job of the service."}
+	networkRXBytes   = family{"datica_network_receive_bytes", "Bytes received by the job during the last sample."}
+	networkTXBytes   = family{"datica_network_transmit_bytes", "Bytes transmitted by the job during the last sample."}
+	networkRXPackets = family{"datica_network_receive_packets", "Packets received by the job during the last sample."}
+	networkTXPackets = family{"datica_network_transmit_packets", "Packets transmitted by the job during the last sample."}
+	networkRXErrors  = family{"datica_network_receive_errors", "Receive errors of the job during the last sample."}
+	networkTXErrors  = family{"datica_network_transmit_errors", "Transmit errors of the job during the last sample."}
+	networkRXDropped = family{"datica_network_receive_dropped", "Received packets dropped by the job during the last sample."}
+	networkTXDropped = family{"datica_network_transmit_dropped", "Transmitted packets dropped by the job during the last sample."}
+	scrapeSuccess    = family{"datica_scrape_success", "Whether the last scrape of the Datica metrics API succeeded."}
+	scrapeTime       = family{"datica_scrape_timestamp_seconds", "When the Datica metrics API was last scraped successfully."}
+
+	families = []family{cpuUsage, memoryUsage, memoryMax, memoryLimit, networkRXBytes, networkTXBytes, networkRXPackets,
+		networkTXPackets, networkRXErrors, networkTXErrors, networkRXDropped, networkTXDropped, scrapeSuccess, scrapeTime}
+)
+
+type sample struct {
+	Labels string
+	Value  float64
+	// TS is the time of the sample in milliseconds, zero for the time of the
+	// request
+	TS int
+}
+
+// Exporter periodically retrieves the metrics of every service in the
+// environment and exposes the most recent sample of each job in the
+// OpenMetrics text format.
+type Exporter struct {
+	EnvironmentName string
+	Interval        time.Duration
+
+	im          IMetrics
+	lock        sync.RWMutex
+	samples     map[string][]sample
+	lastSuccess time.Time
+	succeeded   bool
+}
+
+// NewExporter returns an Exporter that scrapes the environment of im every
+// interval.
+func NewExporter(envName string, interval time.Duration, im IMetrics) *Exporter {
+	return &Exporter{
+		EnvironmentName: envName,
+		Interval:        interval,
+		im:              im,
+		samples:         map[string][]sample{},
+	}
+}
+
+// CmdServe scrapes the environment's metrics and serves them on the given
+// address until the process is interrupted.
+func CmdServe(listen string, interval int, envName string, im IMetrics) error {
+	if interval < 1 {
+		return fmt.Errorf("--interval must be at least 1 second")
+	}
+	e := NewExporter(envName, time.Duration(interval)*time.Second, im)
+	e.Scrape()
+	go e.Run()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	logrus.Printf("Serving metrics of %s on http://%s/metrics", envName, listen)
+	return http.ListenAndServe(listen, mux)
+}
+
+// Run scrapes the metrics every interval. It never returns.
+func (e *Exporter) Run() {
+	for range time.Tick(e.Interval) {
+		e.Scrape()
+	}
+}
+
+// Scrape retrieves the last minute of metrics and replaces the samples that
+// are served. The previous samples are kept when the scrape fails.
+func (e *Exporter) Scrape() {
+	metrics, err := e.im.RetrieveEnvironmentMetrics(1)
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err != nil {
+		logrus.Warnf("Could not retrieve the metrics of %s: %s", e.EnvironmentName, err)
+		e.succeeded = false
+		return
+	}
+	e.samples = e.collect(metrics)
+	e.succeeded = true
+	e.lastSuccess = time.Now()
+}
+
+// collect turns the metrics into samples, keeping the latest sample of each
+// job.
+func (e *Exporter) collect(metrics *[]models.Metrics) map[string][]sample {
+	samples := map[string][]sample{}
+	add := func(f family, labels string, value float64, ts int) {
+		samples[f.Name] = append(samples[f.Name], sample{Labels: labels, Value: value, TS: ts})
+	}
+	for _, metric := range *metrics {
+		if _, ok := blacklist[metric.ServiceLabel]; ok {
+			continue
+		}
+		svcLabels := e.labels(&metric, "")
+		add(memoryLimit, svcLabels, float64(metric.Size.RAM)*1024*1024*1024, 0)
+		if metric.Data == nil {
+			continue
+		}
+		if metric.Data.CPUUsage != nil {
+			latest := map[string]models.CPUUsage{}
+			for _, data := range *metric.Data.CPUUsage {
+				if data.TS >= latest[data.JobID].TS {
+					latest[data.JobID] = data
+				}
+			}
+			for _, data := range latest {
+				add(cpuUsage, e.labels(&metric, data.JobID), data.CorePercent, data.TS)
+			}
+		}
+		if metric.Data.MemoryUsage != nil {
+			latest := map[string]models.MemoryUsage{}
+			for _, data := range *metric.Data.MemoryUsage {
+				if data.TS >= latest[data.JobID].TS {
+					latest[data.JobID] = data
+				}
+			}
+			for _, data := range latest {
+				labels := e.labels(&metric, data.JobID)
+				add(memoryUsage, labels, data.AVG*1024, data.TS)
+				add(memoryMax, labels, data.Max*1024, data.TS)
+			}
+		}
+		if metric.Data.NetworkUsage != nil {
+			latest := map[string]models.NetworkUsage{}
+			for _, data := range *metric.Data.NetworkUsage {
+				if data.TS >= latest[data.JobID].TS {
+					latest[data.JobID] = data
+				}
+			}
+			for _, data := range latest {
+				labels := e.labels(&metric, data.JobID)
+				add(networkRXBytes, labels, data.RXKB*1024, data.TS)
+				add(networkTXBytes, labels, data.TXKB*1024, data.TS)
+				add(networkRXPackets, labels, data.RXPackets, data.TS)
+				add(networkTXPackets, labels, data.TXPackets, data.TS)
+				add(networkRXErrors, labels, data.RXErrors, data.TS)
+				add(networkTXErrors, labels, data.TXErrors, data.TS)
+				add(networkRXDropped, labels, data.RXDropped, data.TS)
+				add(networkTXDropped, labels, data.TXDropped, data.TS)
+			}
+		}
+	}
+	for _, s := range samples {
+		sort.Slice(s, func(i, j int) bool { return s[i].Labels < s[j].Labels })
+	}
+	return samples
+}
+
+func (e *Exporter) labels(metric *models.Metrics, jobID string) string {
+	labels := fmt.Sprintf(`environment="%s",service="%s",service_type="%s"`, escapeLabel(e.EnvironmentName), escapeLabel(metric.ServiceLabel), escapeLabel(metric.ServiceType))
+	if jobID != "" {
+		labels += fmt.Sprintf(`,job_id="%s"`, escapeLabel(jobID))
+	}
+	return labels
+}
+
+// ServeHTTP writes the samples of the last scrape. The OpenMetrics format is
+// used when the scraper accepts it, otherwise the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", prometheusContentType)
+	}
+	e.Write(w, openMetrics)
+}
+
+// Write writes the samples of the last scrape in the OpenMetrics text format,
+// or the Prometheus text format when openMetrics is false. The formats only
+// differ in the unit of timestamps and the terminating # EOF line.
+func (e *Exporter) Write(w io.Writer, openMetrics bool) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	envLabels := fmt.Sprintf(`environment="%s"`, escapeLabel(e.EnvironmentName))
+	success := 0.0
+	if e.succeeded {
+		success = 1
+	}
+	samples := map[string][]sample{
+		scrapeSuccess.Name: {{Labels: envLabels, Value: success}},
+	}
+	if !e.lastSuccess.IsZero() {
+		samples[scrapeTime.Name] = []sample{{Labels: envLabels, Value: float64(e.lastSuccess.Unix())}}
+	}
+	for name, s := range e.samples {
+		samples[name] = s
+	}
+	for _, f := range families {
+		if len(samples[f.Name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", f.Name, f.Help, f.Name)
+		for _, s := range samples[f.Name] {
+			fmt.Fprintf(w, "%s{%s} %s", f.Name, s.Labels, formatSample(s.Value))
+			if s.TS > 0 {
+				if openMetrics {
+					fmt.Fprintf(w, " %s", formatSample(float64(s.TS)/1000.0))
+				} else {
+					fmt.Fprintf(w, " %d", s.TS)
+				}
+			}
+			fmt.Fprint(w, "\n")
+		}
+	}
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+func formatSample(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daticahealth/cli/test"
+)
+
+const environmentMetrics = `[{
+	"serviceLabel": "app01", "serviceType": "code", "serviceId": "svc1", "size": {"ram": 1},
+	"metrics": {
+		"cpu.usage": [{"job": "job1", "core_percent": 0.25, "ts": 1500000000000}, {"job": "job1", "core_percent": 0.5, "ts": 1500000060000}],
+		"memory.usage": [{"job": "job1", "ave": 2048, "max": 4096, "min": 1024, "ts": 1500000060000}],
+		"network.usage": [{"job": "job1", "rx_kb": 1, "tx_kb": 2, "rx_packets": 3, "tx_packets": 4, "ts": 1500000060000}]
+	}
+}, {"serviceLabel": "logging", "serviceType": "logging", "serviceId": "svc2", "size": {"ram": 1}}]`
+
+func TestServe(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	mux.HandleFunc("/environments/"+test.EnvID+"/metrics",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, "1m", r.URL.Query().Get("time"))
+			fmt.Fprint(w, environmentMetrics)
+		},
+	)
+
+	e := NewExporter(`cli "tests"`, time.Minute, New(test.GetSettings(baseURL.String())))
+	e.Scrape()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	test.AssertEquals(t, openMetricsContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	labels := `environment="cli \"tests\"",service="app01",service_type="code"`
+	for _, expected := range []string{
+		"# TYPE datica_cpu_usage_ratio gauge\n",
+		"datica_cpu_usage_ratio{" + labels + `,job_id="job1"} 0.5 1.50000006e+09` + "\n",
+		"datica_memory_usage_bytes{" + labels + `,job_id="job1"} 2.097152e+06 1.50000006e+09` + "\n",
+		"datica_memory_limit_bytes{" + labels + "} 1.073741824e+09\n",
+		"datica_network_transmit_bytes{" + labels + `,job_id="job1"} 2048 1.50000006e+09` + "\n",
+		`datica_scrape_success{environment="cli \"tests\""} 1` + "\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "logging") {
+		t.Errorf("Expected the logging service to be skipped:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("Expected the OpenMetrics output to end with # EOF:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	test.AssertEquals(t, prometheusContentType, w.Header().Get("Content-Type"))
+	if !strings.Contains(w.Body.String(), `,job_id="job1"} 0.5 1500000060000`+"\n") || strings.Contains(w.Body.String(), "# EOF") {
+		t.Errorf("Unexpected Prometheus output:\n%s", w.Body.String())
+	}
+}
+
+func TestServeScrapeFailure(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	mux.HandleFunc("/environments/"+test.EnvID+"/metrics",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+			fmt.Fprint(w, `{"title":"Error","description":"Metrics are unavailable","code":500}`)
+		},
+	)
+
+	e := NewExporter(test.EnvName, time.Minute, New(test.GetSettings(baseURL.String())))
+	e.Scrape()
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	test.AssertEquals(t, "# HELP datica_scrape_success Whether the last scrape of the Datica metrics API succeeded.\n"+
+		"# TYPE datica_scrape_success gauge\n"+
+		`datica_scrape_success{environment="cli-tests1"} 0`+"\n", w.Body.String())
+}
@@ -122,3 +122,43 @@ func (text *TextTransformer) TransformSingleNetworkOut(metric *models.Metrics) {
 		}
 	}
 }
+
+// TransformSummary transforms the statistics of each service into text
+// format.
+func (text *TextTransformer) TransformSummary(summaries []Summary) {
+	for _, s := range summaries {
+		logrus.Printf("%s %s (%s) from %s to %s:", s.ServiceName, s.Metric, s.Unit, formatTimestamp(s.Start), formatTimestamp(s.End))
+		if s.Samples == 0 {
+			logrus.Println("    no data")
+			continue
+		}
+		logrus.Printf("    Samples: %d | Min: %.2f | Avg: %.2f | Max: %.2f | p50: %.2f | p95: %.2f | p99: %.2f",
+			s.Samples, s.Min, s.Avg, s.Max, s.P50, s.P95, s.P99)
+	}
+}
+
+// TransformComparison transforms the statistics of each service before and
+// after a point in time, and the change between them, into text format.
+func (text *TextTransformer) TransformComparison(comparisons []Comparison) {
+	for _, c := range comparisons {
+		logrus.Printf("%s %s (%s) from %s to %s compared with %s to %s:", c.After.ServiceName, c.After.Metric, c.After.Unit,
+			formatTimestamp(c.Before.Start), formatTimestamp(c.Before.End), formatTimestamp(c.After.Start), formatTimestamp(c.After.End))
+		logrus.Printf("    %-7s  %12s  %12s  %12s  %8s", "", "Before", "After", "Delta", "Change")
+		before, after, delta := c.Before.values(), c.After.values(), c.Delta.values()
+		for i, name := range statNames {
+			change := ""
+			if before[i] != 0 {
+				change = fmt.Sprintf("%+.1f%%", delta[i]/before[i]*100.0)
+			}
+			precision := 2
+			if name == "samples" {
+				precision = 0
+			}
+			logrus.Printf("    %-7s  %12.*f  %12.*f  %+12.*f  %8s", name, precision, before[i], precision, after[i], precision, delta[i], change)
+		}
+	}
+}
+
+func formatTimestamp(ts time.Time) string {
+	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ts.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second())
+}
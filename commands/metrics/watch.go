@@ -0,0 +1,409 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/models"
+)
+
+// alertTimeout is how long the command and the webhook may take to handle an
+// alert when the Watcher has no Timeout
+const alertTimeout = 60 * time.Second
+
+var ruleRegex = regexp.MustCompile(`^(cpu|memory|network-in|network-out)\s*(>=|<=|>|<)\s*([0-9]*\.?[0-9]+)\s*(%|kb|mb|gb)?(?:\s+for\s+(\S+))?$`)
+
+var metricNames = map[string]MetricType{
+	"cpu":         CPU,
+	"memory":      Memory,
+	"network-in":  NetworkIn,
+	"network-out": NetworkOut,
+}
+
+// Rule is a threshold on a metric of a service, such as "cpu > 80% for 5m".
+type Rule struct {
+	Text   string
+	Metric MetricType
+	Op     string
+	// Threshold is in percent for cpu and for memory with Percent set, in MB
+	// for other memory rules, and in KB for network rules.
+	Threshold float64
+	Percent   bool
+	// For is how long the threshold must be crossed before the rule is
+	// breached.
+	For time.Duration
+}
+
+// ParseRule parses a rule in the form "<metric> <op> <value>[unit] [for
+// <duration>]". The metric is one of cpu, memory, network-in, or
+// network-out and the op is one of >, >=, <, or <=. CPU values are in
+// percent. Memory values are a percent of the service's memory or a size in
+// KB, MB (the default), or GB. Network values are a size in KB (the
+// default), MB, or GB received or transmitted per sample.
+func ParseRule(text string) (*Rule, error) {
+	text = strings.TrimSpace(text)
+	m := ruleRegex.FindStringSubmatch(strings.ToLower(text))
+	if m == nil {
+		return nil, fmt.Errorf("Invalid rule \"%s\". Rules look like \"cpu > 80%% for 5m\"", text)
+	}
+	rule := &Rule{Text: text, Metric: metricNames[m[1]], Op: m[2]}
+	rule.Threshold, _ = strconv.ParseFloat(m[3], 64)
+	unit := m[4]
+	switch rule.Metric {
+	case CPU:
+		if unit != "" && unit != "%" {
+			return nil, fmt.Errorf("Invalid rule \"%s\". CPU thresholds are in percent", text)
+		}
+		rule.Percent = true
+	case Memory:
+		switch unit {
+		case "%":
+			rule.Percent = true
+		case "kb":
+			rule.Threshold /= 1024
+		case "gb":
+			rule.Threshold *= 1024
+		}
+	default:
+		switch unit {
+		case "%":
+			return nil, fmt.Errorf("Invalid rule \"%s\". Network thresholds are a size such as 500KB", text)
+		case "mb":
+			rule.Threshold *= 1024
+		case "gb":
+			rule.Threshold *= 1024 * 1024
+		}
+	}
+	if m[5] != "" {
+		d, err := time.ParseDuration(m[5])
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("Invalid rule \"%s\". \"%s\" is not a duration such as 5m", text, m[5])
+		}
+		rule.For = d
+	}
+	return rule, nil
+}
+
+func (r *Rule) unit() string {
+	switch {
+	case r.Percent:
+		return "%"
+	case r.Metric == Memory:
+		return "MB"
+	default:
+		return "KB"
+	}
+}
+
+func (r *Rule) crossed(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	default:
+		return value <= r.Threshold
+	}
+}
+
+// values returns the value of the rule's metric for the service at each
+// point in time. CPU and memory are the highest of all jobs of the service,
+// network data is the sum of all jobs.
+func (r *Rule) values(metric *models.Metrics) map[int]float64 {
+	values := map[int]float64{}
+	if metric.Data == nil {
+		return values
+	}
+	max := func(ts int, v float64) {
+		if current, ok := values[ts]; !ok || v > current {
+			values[ts] = v
+		}
+	}
+	switch r.Metric {
+	case CPU:
+		if metric.Data.CPUUsage != nil {
+			for _, data := range *metric.Data.CPUUsage {
+				max(data.TS, data.CorePercent*100.0)
+			}
+		}
+	case Memory:
+		if metric.Data.MemoryUsage != nil {
+			for _, data := range *metric.Data.MemoryUsage {
+				if !r.Percent {
+					max(data.TS, data.AVG/1024.0)
+				} else if metric.Size.RAM > 0 {
+					max(data.TS, data.AVG/(float64(metric.Size.RAM)*1024.0*1024.0)*100.0)
+				}
+			}
+		}
+	case NetworkIn, NetworkOut:
+		if metric.Data.NetworkUsage != nil {
+			for _, data := range *metric.Data.NetworkUsage {
+				if r.Metric == NetworkIn {
+					values[data.TS] += data.RXKB
+				} else {
+					values[data.TS] += data.TXKB
+				}
+			}
+		}
+	}
+	return values
+}
+
+// Alert describes a rule that was breached or recovered. It is the JSON
+// payload sent to the --exec command and the --webhook.
+type Alert struct {
+	Event       string    `json:"event"`
+	Rule        string    `json:"rule"`
+	Environment string    `json:"environment"`
+	Service     string    `json:"service"`
+	ServiceID   string    `json:"serviceId"`
+	Metric      string    `json:"metric"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Unit        string    `json:"unit"`
+	Since       time.Time `json:"since"`
+	Time        time.Time `json:"time"`
+}
+
+const (
+	// AlertBreach is the event of an Alert when a rule is breached
+	AlertBreach = "breach"
+	// AlertRecovery is the event of an Alert when a breached rule recovers
+	AlertRecovery = "recovery"
+)
+
+type ruleState struct {
+	lastTS   int
+	since    time.Time
+	breached bool
+}
+
+// Watcher evaluates rules against the metrics of an environment and acts on
+// every breach and recovery.
+type Watcher struct {
+	EnvironmentName string
+	Rules           []*Rule
+	// ServiceIDs limits the services that are watched. Every service except
+	// the blacklisted ones is watched when it is empty.
+	ServiceIDs map[string]bool
+	// ExitOnBreach makes Check return an error on the first breach.
+	ExitOnBreach bool
+	// Command is run with the event as its last argument and the Alert as
+	// JSON on stdin.
+	Command string
+	// Webhook is sent the Alert as JSON in a POST request.
+	Webhook string
+	Client  *http.Client
+	// Timeout limits how long the command and the webhook may take so a hung
+	// one does not stop the watch. Defaults to alertTimeout.
+	Timeout time.Duration
+
+	states map[string]*ruleState
+}
+
+// Check evaluates the rules against every sample that has not been seen yet,
+// in the order they were taken.
+func (w *Watcher) Check(metrics *[]models.Metrics) error {
+	if w.states == nil {
+		w.states = map[string]*ruleState{}
+	}
+	for _, metric := range *metrics {
+		if len(w.ServiceIDs) > 0 && !w.ServiceIDs[metric.ServiceID] {
+			continue
+		}
+		if _, ok := blacklist[metric.ServiceLabel]; ok && len(w.ServiceIDs) == 0 {
+			continue
+		}
+		for i, rule := range w.Rules {
+			key := fmt.Sprintf("%d-%s", i, metric.ServiceID)
+			state, ok := w.states[key]
+			if !ok {
+				state = &ruleState{}
+				w.states[key] = state
+			}
+			values := rule.values(&metric)
+			timestamps := []int{}
+			for ts := range values {
+				if ts > state.lastTS {
+					timestamps = append(timestamps, ts)
+				}
+			}
+			sort.Ints(timestamps)
+			for _, ts := range timestamps {
+				state.lastTS = ts
+				if err := w.evaluate(rule, state, &metric, ts, values[ts]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) evaluate(rule *Rule, state *ruleState, metric *models.Metrics, ts int, value float64) error {
+	t := time.Unix(int64(ts/1000.0), 0)
+	alert := &Alert{
+		Rule:        rule.Text,
+		Environment: w.EnvironmentName,
+		Service:     metric.ServiceLabel,
+		ServiceID:   metric.ServiceID,
+		Metric:      strings.ToLower(strings.Replace(metricsTypeToString(rule.Metric), " ", "-", -1)),
+		Value:       value,
+		Threshold:   rule.Threshold,
+		Unit:        rule.unit(),
+		Time:        t,
+	}
+	if !rule.crossed(value) {
+		state.since = time.Time{}
+		if state.breached {
+			state.breached = false
+			alert.Event = AlertRecovery
+			w.act(alert)
+		}
+		return nil
+	}
+	if state.since.IsZero() {
+		state.since = t
+	}
+	if state.breached || t.Sub(state.since) < rule.For {
+		return nil
+	}
+	state.breached = true
+	alert.Event = AlertBreach
+	alert.Since = state.since
+	w.act(alert)
+	if w.ExitOnBreach {
+		return fmt.Errorf("%s breached \"%s\" with %.2f%s", metric.ServiceLabel, rule.Text, value, rule.unit())
+	}
+	return nil
+}
+
+// act prints the alert and sends it to the command and webhook. Failures to
+// send the alert are printed but do not stop the watch.
+func (w *Watcher) act(alert *Alert) {
+	if alert.Event == AlertBreach {
+		logrus.Warnf("[%s] %s breached \"%s\" with %.2f%s", alert.Time.Format("15:04:05"), alert.Service, alert.Rule, alert.Value, alert.Unit)
+	} else {
+		logrus.Printf("[%s] %s recovered from \"%s\" with %.2f%s", alert.Time.Format("15:04:05"), alert.Service, alert.Rule, alert.Value, alert.Unit)
+	}
+	if w.Command == "" && w.Webhook == "" {
+		return
+	}
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		logrus.Warnf("Could not encode the alert: %s", err)
+		return
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = alertTimeout
+	}
+	if args := strings.Fields(w.Command); len(args) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, args[0], append(args[1:], alert.Event)...)
+		cmd.Stdin = bytes.NewReader(payload)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err = cmd.Run(); ctx.Err() == context.DeadlineExceeded {
+			logrus.Warnf("The command \"%s\" did not finish within %s for the %s of %s", w.Command, timeout, alert.Event, alert.Service)
+		} else if err != nil {
+			logrus.Warnf("The command \"%s\" failed for the %s of %s: %s", w.Command, alert.Event, alert.Service, err)
+		}
+	}
+	if w.Webhook != "" {
+		client := http.Client{Timeout: timeout}
+		if w.Client != nil {
+			client = *w.Client
+			client.Timeout = timeout
+		}
+		resp, err := client.Post(w.Webhook, "application/json", bytes.NewReader(payload))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			logrus.Warnf("Could not send the %s of %s to %s: %s", alert.Event, alert.Service, w.Webhook, err)
+		}
+	}
+}
+
+// CmdWatch retrieves the metrics of the environment every interval and
+// evaluates the rules against them until the process is interrupted or, with
+// ExitOnBreach, a rule is breached.
+func CmdWatch(svcNames []string, interval int, w *Watcher, im IMetrics, is services.IServices) error {
+	if len(w.Rules) == 0 {
+		return fmt.Errorf("At least one --rule is required")
+	}
+	if interval < 1 {
+		return fmt.Errorf("--interval must be at least 1 second")
+	}
+	if len(svcNames) > 0 {
+		w.ServiceIDs = map[string]bool{}
+		for _, svcName := range svcNames {
+			service, err := is.RetrieveByLabel(svcName)
+			if err != nil {
+				return err
+			}
+			if service == nil {
+				return fmt.Errorf("Could not find a service with the label \"%s\"", svcName)
+			}
+			w.ServiceIDs[service.ID] = true
+		}
+	}
+	// the first retrieval covers the longest duration so rules that are
+	// already breached are reported right away
+	longest := time.Minute
+	for _, rule := range w.Rules {
+		if d := rule.For + time.Minute; d > longest {
+			longest = d
+		}
+	}
+	from := time.Now().Add(-longest)
+	for {
+		// retrievals cover everything since the last one that succeeded so
+		// the samples of a failed one are still checked
+		retrieved := time.Now()
+		metrics, err := im.RetrieveEnvironmentMetrics(lookBackMins(from, retrieved))
+		if err != nil {
+			logrus.Warnf("Could not retrieve the metrics of %s: %s", w.EnvironmentName, err)
+		} else {
+			from = retrieved
+			if err = w.Check(metrics); err != nil {
+				return err
+			}
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+// lookBackMins returns how many minutes of metrics reach back from now to
+// from, at least 1 and at most the 1440 the metrics API returns.
+func lookBackMins(from, now time.Time) int {
+	mins := int(math.Ceil(now.Sub(from).Minutes()))
+	if mins < 1 {
+		mins = 1
+	}
+	if mins > 1440 {
+		mins = 1440
+	}
+	return mins
+}
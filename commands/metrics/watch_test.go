@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+var parseRuleTests = []struct {
+	text      string
+	metric    MetricType
+	op        string
+	threshold float64
+	percent   bool
+	duration  time.Duration
+	expectErr bool
+}{
+	{"cpu > 80% for 5m", CPU, ">", 80, true, 5 * time.Minute, false},
+	{"CPU>=50", CPU, ">=", 50, true, 0, false},
+	{"memory > 90%", Memory, ">", 90, true, 0, false},
+	{"memory < 1.5GB", Memory, "<", 1536, false, 0, false},
+	{"network-out > 2MB for 30s", NetworkOut, ">", 2048, false, 30 * time.Second, false},
+	{"network-in <= 500", NetworkIn, "<=", 500, false, 0, false},
+	{"cpu > 10MB", CPU, "", 0, false, 0, true},
+	{"network-in > 10%", NetworkIn, "", 0, false, 0, true},
+	{"disk > 10%", CPU, "", 0, false, 0, true},
+	{"cpu > 80% for soon", CPU, "", 0, false, 0, true},
+}
+
+func TestParseRule(t *testing.T) {
+	for _, data := range parseRuleTests {
+		t.Logf("Data: %+v", data)
+		rule, err := ParseRule(data.text)
+		if err != nil != data.expectErr {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		if data.expectErr {
+			continue
+		}
+		if rule.Metric != data.metric || rule.Op != data.op || rule.Threshold != data.threshold || rule.Percent != data.percent || rule.For != data.duration {
+			t.Errorf("Unexpected rule %+v", rule)
+		}
+	}
+}
+
+func cpuSamples(svcID, label string, percents ...float64) models.Metrics {
+	m := cpuMetrics(label, percents...)
+	m.ServiceID = svcID
+	return m
+}
+
+func TestWatcher(t *testing.T) {
+	var alerts []Alert
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		json.NewDecoder(r.Body).Decode(&alert)
+		alerts = append(alerts, alert)
+	}))
+	defer webhook.Close()
+
+	rule, _ := ParseRule("cpu > 80% for 2m")
+	w := &Watcher{EnvironmentName: test.EnvName, Rules: []*Rule{rule}, Webhook: webhook.URL}
+	// samples are a minute apart, the rule is breached by the third sample
+	// above 80% and recovers with the last one
+	err := w.Check(&[]models.Metrics{
+		cpuSamples("svc1", "app01", 0.9, 0.95, 0.5, 0.85, 0.9, 0.99),
+		cpuSamples("svc2", "logging", 1, 1, 1, 1),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = w.Check(&[]models.Metrics{cpuSamples("svc1", "app01", 0.9, 0.95, 0.5, 0.85, 0.9, 0.99, 0.1)}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("Expected a breach and a recovery but got %+v", alerts)
+	}
+	test.AssertEquals(t, AlertBreach, alerts[0].Event)
+	test.AssertEquals(t, "app01", alerts[0].Service)
+	test.AssertEquals(t, "cpu", alerts[0].Metric)
+	test.AssertEquals(t, "cpu > 80% for 2m", alerts[0].Rule)
+	test.AssertEquals(t, test.EnvName, alerts[0].Environment)
+	test.AssertEquals(t, "2m0s", alerts[0].Time.Sub(alerts[0].Since).String())
+	if alerts[0].Value != 99 {
+		t.Errorf("Expected the breaching value to be 99 but got %f", alerts[0].Value)
+	}
+	test.AssertEquals(t, AlertRecovery, alerts[1].Event)
+}
+
+func TestWatcherTimeout(t *testing.T) {
+	done := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer webhook.Close()
+	defer close(done)
+
+	rule, _ := ParseRule("cpu > 80%")
+	w := &Watcher{Rules: []*Rule{rule}, Command: "sleep 10", Webhook: webhook.URL, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	if err := w.Check(&[]models.Metrics{cpuSamples("svc1", "app01", 0.9)}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command and webhook to time out but the alert took %s", elapsed)
+	}
+}
+
+func TestWatcherExitOnBreach(t *testing.T) {
+	rule, _ := ParseRule("cpu >= 50")
+	w := &Watcher{Rules: []*Rule{rule}, ServiceIDs: map[string]bool{"svc2": true}, ExitOnBreach: true}
+	metrics := &[]models.Metrics{cpuSamples("svc1", "app01", 0.1, 0.6), cpuSamples("svc2", "app02", 0.1, 0.2)}
+	if err := w.Check(metrics); err != nil {
+		t.Fatalf("Unexpected error for an unwatched service: %s", err)
+	}
+	metrics = &[]models.Metrics{cpuSamples("svc2", "app02", 0.1, 0.2, 0.7)}
+	err := w.Check(metrics)
+	if err == nil || !strings.Contains(err.Error(), "app02 breached") {
+		t.Fatalf("Expected a breach but got %v", err)
+	}
+}
+
+func TestLookBackMins(t *testing.T) {
+	now := time.Date(2017, 6, 10, 12, 0, 0, 0, time.UTC)
+	for from, expected := range map[time.Time]int{
+		now:                          1,
+		now.Add(-30 * time.Second):   1,
+		now.Add(-90 * time.Second):   2,
+		now.Add(-10 * time.Minute):   10,
+		now.Add(-48 * time.Hour):     1440,
+		now.Add(-1441 * time.Minute): 1440,
+	} {
+		test.AssertEquals(t, strconv.Itoa(expected), strconv.Itoa(lookBackMins(from, now)))
+	}
+}
@@ -2,11 +2,14 @@ package redeploy
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/deploy"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -23,11 +26,16 @@ var Cmd = models.Command{
 		"All other service types cannot be redeployed with this command. " +
 		"For service proxy redeploys, there will be approximately 5 minutes of downtime. " +
 		"For code service redeploys, there will be approximately 30 seconds of downtime. " +
-		"Here is a sample command\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" redeploy app01\n</pre>",
+		"Pass <code>--wait</code> to follow the new deploy jobs until they are running and exit with an error if they fail. " +
+		"<code>--health-check</code> works the same as for the deploy command. " +
+		"There is no <code>--rollback-on-failure</code> since the release that would be rolled back to is the one being redeployed. " +
+		"Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" redeploy app01\n" +
+		"datica -E \"<your_env_name>\" redeploy app01 --wait --health-check /health\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
 			serviceName := cmd.StringArg("SERVICE_NAME", "", "The name of the service to redeploy (e.g. 'app01')")
+			waitOpts := deploy.WaitOpts(cmd)
 			cmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -35,12 +43,16 @@ var Cmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdRedeploy(settings.EnvironmentID, *serviceName, jobs.New(settings), services.New(settings), environments.New(settings))
+				client, err := network.Client(settings.Network)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdRedeploy(settings.EnvironmentID, *serviceName, waitOpts(client), jobs.New(settings), services.New(settings), environments.New(settings), sites.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			cmd.Spec = "SERVICE_NAME"
+			cmd.Spec = "SERVICE_NAME " + deploy.WaitSpec
 		}
 	},
 }
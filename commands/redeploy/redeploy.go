@@ -4,12 +4,14 @@ import (
 	"fmt"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/deploy"
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/lib/jobs"
 )
 
-func CmdRedeploy(envID, svcName string, ij jobs.IJobs, is services.IServices, ie environments.IEnvironments) error {
+func CmdRedeploy(envID, svcName string, opts deploy.WaitOptions, ij jobs.IJobs, is services.IServices, ie environments.IEnvironments, isites sites.ISites) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -21,11 +23,22 @@ func CmdRedeploy(envID, svcName string, ij jobs.IJobs, is services.IServices, ie
 	if service == nil {
 		return fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.", svcName)
 	}
+	waiter, err := deploy.NewDeployWaiter(opts, service, ij, is, isites)
+	if err != nil {
+		return err
+	}
 	logrus.Printf("Redeploying service %s (ID = %s) in environment %s (ID = %s)", svcName, service.ID, env.Name, env.ID)
 	err = ij.Redeploy(service.ID)
 	if err != nil {
 		return err
 	}
+	if opts.Wait {
+		if err = waiter.Wait(); err != nil {
+			return err
+		}
+		logrus.Printf("Redeploy finished! %s is running", svcName)
+		return nil
+	}
 	logrus.Println("Redeploy successful! Check the status with \"datica status\" and your logging dashboard for updates")
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
@@ -26,7 +27,7 @@ func (rls SortedReleases) Less(i, j int) bool {
 	return rls[i].CreatedAt > rls[j].CreatedAt
 }
 
-func CmdList(svcName string, ir IReleases, is services.IServices) error {
+func CmdList(svcName string, ir IReleases, is services.IServices, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -40,6 +41,14 @@ func CmdList(svcName string, ir IReleases, is services.IServices) error {
 		return err
 	}
 
+	if iout.Structured() {
+		if rls == nil {
+			rls = &[]models.Release{}
+		}
+		sort.Sort(SortedReleases(*rls))
+		return iout.Render(rls)
+	}
+
 	if rls == nil || len(*rls) == 0 {
 		logrus.Println("No releases found")
 		return nil
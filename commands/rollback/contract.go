@@ -2,11 +2,14 @@ package rollback
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/deploy"
 	"github.com/daticahealth/cli/commands/releases"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -19,12 +22,16 @@ var Cmd = models.Command{
 	ShortHelp: "Rollback a code service to a specific release",
 	LongHelp: "<code>rollback</code> is a way to redeploy older versions of your code service. " +
 		"You must specify the name of the service to rollback and the name of an existing release to rollback to. " +
-		"Releases can be found with the releases list command. Here are some sample commands\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" rollback code-1 f93ced037f828dcaabccfc825e6d8d32cc5a1883\n</pre>",
+		"Releases can be found with the releases list command. " +
+		"Pass <code>--wait</code> to follow the new deploy jobs until they are running and exit with an error if they fail. " +
+		"<code>--health-check</code> and <code>--rollback-on-failure</code> work the same as for the deploy command. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" rollback code-1 f93ced037f828dcaabccfc825e6d8d32cc5a1883\n" +
+		"datica -E \"<your_env_name>\" rollback code-1 f93ced037f828dcaabccfc825e6d8d32cc5a1883 --wait\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
 			serviceName := cmd.StringArg("SERVICE_NAME", "", "The name of the service to rollback")
 			releaseName := cmd.StringArg("RELEASE_NAME", "", "The name of the release to rollback to")
+			waitOpts := deploy.RollbackWaitOpts(cmd)
 			cmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -32,12 +39,16 @@ var Cmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdRollback(*serviceName, *releaseName, jobs.New(settings), releases.New(settings), services.New(settings))
+				client, err := network.Client(settings.Network)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdRollback(*serviceName, *releaseName, waitOpts(client), jobs.New(settings), releases.New(settings), services.New(settings), sites.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			cmd.Spec = "SERVICE_NAME RELEASE_NAME"
+			cmd.Spec = "SERVICE_NAME RELEASE_NAME " + deploy.RollbackWaitSpec
 		}
 	},
 }
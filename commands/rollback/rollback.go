@@ -5,13 +5,15 @@ import (
 	"strings"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/deploy"
 	"github.com/daticahealth/cli/commands/releases"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/commands/sites"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/jobs"
 )
 
-func CmdRollback(svcName, releaseName string, ij jobs.IJobs, irs releases.IReleases, is services.IServices) error {
+func CmdRollback(svcName, releaseName string, opts deploy.WaitOptions, ij jobs.IJobs, irs releases.IReleases, is services.IServices, isites sites.ISites) error {
 	if strings.ContainsAny(releaseName, config.InvalidChars) {
 		return fmt.Errorf("Invalid release name. Names must not contain the following characters: %s", config.InvalidChars)
 	}
@@ -30,10 +32,21 @@ func CmdRollback(svcName, releaseName string, ij jobs.IJobs, irs releases.IRelea
 	if release == nil {
 		return fmt.Errorf("Could not find a release with the name \"%s\". You can list releases for this code service with the \"datica releases list %s\" command.", releaseName, svcName)
 	}
+	waiter, err := deploy.NewDeployWaiter(opts, service, ij, is, isites)
+	if err != nil {
+		return err
+	}
 	err = ij.DeployRelease(releaseName, service.ID)
 	if err != nil {
 		return err
 	}
+	if opts.Wait {
+		if err = waiter.Wait(); err != nil {
+			return err
+		}
+		logrus.Printf("Rollback finished! %s is running release %s", svcName, releaseName)
+		return nil
+	}
 	logrus.Println("Rollback successful! Check the status with \"datica status\" and your logging dashboard for updates.")
 	return nil
 }
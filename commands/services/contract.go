@@ -5,6 +5,7 @@ import (
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/lib/volumes"
 	"github.com/daticahealth/cli/models"
@@ -43,7 +44,7 @@ var ListSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdServices(New(settings), volumes.New(settings))
+				err := CmdServices(New(settings), volumes.New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
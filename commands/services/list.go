@@ -4,18 +4,43 @@ import (
 	"fmt"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/volumes"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
+// serviceOutput is the structured representation of a service and its volumes
+// used when a machine readable output format is selected.
+type serviceOutput struct {
+	models.Service
+	Volumes []models.Volume `json:"volumes"`
+}
+
 // CmdServices lists the names of all services for an environment.
-func CmdServices(is IServices, v volumes.IVolumes) error {
+func CmdServices(is IServices, v volumes.IVolumes, iout output.IOutput) error {
 	svcs, err := is.List()
 
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		out := []serviceOutput{}
+		if svcs != nil {
+			for _, s := range *svcs {
+				vols, err := v.List(s.ID)
+				if err != nil {
+					return err
+				}
+				so := serviceOutput{Service: s, Volumes: []models.Volume{}}
+				if vols != nil {
+					so.Volumes = *vols
+				}
+				out = append(out, so)
+			}
+		}
+		return iout.Render(out)
+	}
 	if svcs == nil || len(*svcs) == 0 {
 		logrus.Println("No services found")
 		return nil
@@ -6,6 +6,7 @@ import (
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -106,7 +107,7 @@ var ListSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdList(New(settings), services.New(settings), *downStream)
+				err := CmdList(New(settings), services.New(settings), output.New(settings), *downStream)
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -162,7 +163,7 @@ var ShowSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdShow(*name, New(settings), services.New(settings), *downStream)
+				err := CmdShow(*name, New(settings), services.New(settings), output.New(settings), *downStream)
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
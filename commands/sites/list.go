@@ -5,11 +5,12 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdList(is ISites, iservices services.IServices, downStream string) error {
+func CmdList(is ISites, iservices services.IServices, iout output.IOutput, downStream string) error {
 	serviceProxy, err := iservices.RetrieveByLabel(downStream)
 	if err != nil {
 		return err
@@ -18,6 +19,12 @@ func CmdList(is ISites, iservices services.IServices, downStream string) error {
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		if sites == nil {
+			sites = &[]models.Site{}
+		}
+		return iout.Render(sites)
+	}
 	if sites == nil || len(*sites) == 0 {
 		logrus.Println("No sites found")
 		return nil
@@ -5,11 +5,12 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/forana/simpletable"
 )
 
-func CmdShow(name string, is ISites, iservices services.IServices, downStream string) error {
+func CmdShow(name string, is ISites, iservices services.IServices, iout output.IOutput, downStream string) error {
 	serviceProxy, err := iservices.RetrieveByLabel(downStream)
 	if err != nil {
 		return err
@@ -32,6 +33,9 @@ func CmdShow(name string, is ISites, iservices services.IServices, downStream st
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		return iout.Render(site)
+	}
 	table, err := simpletable.New(simpletable.HeadersForType(models.Site{}), []models.Site{*site})
 	if err != nil {
 		return err
@@ -7,6 +7,7 @@ import (
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -31,7 +32,7 @@ var Cmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdStatus(settings.EnvironmentID, New(settings, jobs.New(settings)), environments.New(settings), services.New(settings), *historical)
+				err := CmdStatus(settings.EnvironmentID, New(settings, jobs.New(settings)), environments.New(settings), services.New(settings), output.New(settings), *historical)
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -43,7 +44,7 @@ var Cmd = models.Command{
 
 // IStatus
 type IStatus interface {
-	Status(env *models.Environment, services *[]models.Service, historical bool) error
+	Status(env *models.Environment, services *[]models.Service, iout output.IOutput, historical bool) error
 }
 
 // SStatus is a concrete implementation of IStatus
@@ -2,12 +2,15 @@ package status
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"text/tabwriter"
 	"time"
 
 	"github.com/daticahealth/cli/commands/environments"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/pmylund/sortutil"
 )
@@ -22,7 +25,24 @@ var historicalStatus = map[string]bool{
 	"unknown":     true,
 }
 
-func CmdStatus(envID string, is IStatus, ie environments.IEnvironments, iservices services.IServices, historical bool) error {
+// statusOutput is the structured representation of an environment's status
+// used when a machine readable output format is selected.
+type statusOutput struct {
+	Environment *models.Environment `json:"environment"`
+	Services    []serviceStatus     `json:"services"`
+}
+
+// serviceStatus holds the jobs reported for a single service in the
+// structured status output.
+type serviceStatus struct {
+	ID             string       `json:"id"`
+	Label          string       `json:"label"`
+	Type           string       `json:"type"`
+	ReleaseVersion string       `json:"release_version,omitempty"`
+	Jobs           []models.Job `json:"jobs"`
+}
+
+func CmdStatus(envID string, is IStatus, ie environments.IEnvironments, iservices services.IServices, iout output.IOutput, historical bool) error {
 	env, err := ie.Retrieve(envID)
 	if err != nil {
 		return err
@@ -31,21 +51,29 @@ func CmdStatus(envID string, is IStatus, ie environments.IEnvironments, iservice
 	if err != nil {
 		return err
 	}
-	return is.Status(env, svcs, historical)
+	return is.Status(env, svcs, iout, historical)
 }
 
 // Status prints out all of the non-utility services and their running jobs
-func (s *SStatus) Status(env *models.Environment, services *[]models.Service, historical bool) error {
+func (s *SStatus) Status(env *models.Environment, services *[]models.Service, iout output.IOutput, historical bool) error {
+	// the table is only printed as text, lines without cells would otherwise
+	// be flushed ahead of the structured output
+	var dest io.Writer = os.Stdout
+	if iout.Structured() {
+		dest = ioutil.Discard
+	}
 	w := &tabwriter.Writer{}
-	w.Init(os.Stdout, 0, 8, 4, '\t', 0)
+	w.Init(dest, 0, 8, 4, '\t', 0)
 
 	fmt.Fprintln(w, env.Name+" (environment ID = "+env.ID+"):")
 	fmt.Fprintln(w, "Label\tStatus\tCreated At")
 
 	sortutil.AscByField(*services, "Label")
 
+	out := statusOutput{Environment: env, Services: []serviceStatus{}}
 	for _, service := range *services {
 		if service.Type != "" {
+			svcStatus := serviceStatus{ID: service.ID, Label: service.Label, Type: service.Type, ReleaseVersion: service.ReleaseVersion, Jobs: []models.Job{}}
 			jobs, err := s.Jobs.List(service.ID, 1, 100)
 			if err != nil {
 				return err
@@ -66,6 +94,7 @@ func (s *SStatus) Status(env *models.Environment, services *[]models.Service, hi
 						if workerJob.Spec != nil && workerJob.Spec.Payload != nil && workerJob.Spec.Payload.Environment != nil {
 							if target, contains := workerJob.Spec.Payload.Environment["PROCFILE_TARGET"]; contains {
 								displayType = fmt.Sprintf("%s (%s: target=%s)", service.Label, job.Type, target)
+								job.Target = target
 							}
 						}
 					}
@@ -73,6 +102,7 @@ func (s *SStatus) Status(env *models.Environment, services *[]models.Service, hi
 					displayType = fmt.Sprintf("%s (git:%s)", service.Label, service.ReleaseVersion)
 				}
 
+				svcStatus.Jobs = append(svcStatus.Jobs, job)
 				t, _ := time.Parse(dateForm, job.CreatedAt)
 				fmt.Fprintln(w, displayType+"\t"+job.Status+"\t"+t.Local().Format(time.ANSIC))
 			}
@@ -88,14 +118,19 @@ func (s *SStatus) Status(env *models.Environment, services *[]models.Service, hi
 					if latestBuildJob.ID == "" {
 						fmt.Fprintln(w, "--------"+"\t"+service.Label+"\t"+"-------"+"\t"+"---------------")
 					} else if latestBuildJob.ID != "" {
+						svcStatus.Jobs = append(svcStatus.Jobs, latestBuildJob)
 						t, _ := time.Parse(dateForm, latestBuildJob.CreatedAt)
 						displayType := fmt.Sprintf("%s (%s)", service.Label, latestBuildJob.Type)
 						fmt.Fprintln(w, displayType+"\t"+latestBuildJob.Status+"\t"+t.Local().Format(time.ANSIC))
 					}
 				}
 			}
+			out.Services = append(out.Services, svcStatus)
 		}
 	}
+	if iout.Structured() {
+		return iout.Render(out)
+	}
 	w.Flush()
 	return nil
 }
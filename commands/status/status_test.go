@@ -0,0 +1,74 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestStatusStructured(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			if r.URL.Query().Get("type") == "build" {
+				fmt.Fprint(w, `[{"id":"build1","type":"build","status":"finished","created_at":"2017-01-02T15:04:05"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"id":"deploy1","type":"deploy","status":"running","created_at":"2017-01-02T15:04:05"}]`)
+		},
+	)
+	env := &models.Environment{ID: test.EnvID, Name: test.EnvName}
+	svcs := &[]models.Service{{ID: test.SvcID, Label: test.SvcLabel, Type: "code"}}
+
+	// nothing but the structured output may be written to stdout
+	stdout := os.Stdout
+	f, err := ioutil.TempFile("", "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+
+	for _, format := range []string{output.FormatJSON, output.FormatYAML} {
+		t.Logf("Format: %s", format)
+		buf := &bytes.Buffer{}
+
+		// test
+		err := New(settings, jobs.New(settings)).Status(env, svcs, &output.SOutput{Format: format, Writer: buf}, true)
+
+		// assert
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		var status statusOutput
+		if format == output.FormatJSON {
+			err = json.Unmarshal(buf.Bytes(), &status)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), &status)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s output: %s", format, err)
+		}
+		if len(status.Services) != 1 || len(status.Services[0].Jobs) != 2 || status.Services[0].Jobs[1].ID != "build1" {
+			t.Errorf("Unexpected %s output: %s", format, buf.String())
+		}
+	}
+	f.Close()
+	if b, _ := ioutil.ReadFile(f.Name()); len(b) > 0 {
+		t.Errorf("Expected nothing on stdout but got %q", b)
+	}
+}
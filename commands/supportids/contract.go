@@ -2,6 +2,7 @@ package supportids
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
 )
@@ -18,7 +19,7 @@ var Cmd = models.Command{
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(cmd *cli.Cmd) {
 			cmd.Action = func() {
-				err := CmdSupportIDs(New(settings))
+				err := CmdSupportIDs(New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -1,12 +1,23 @@
 package supportids
 
-import "github.com/Sirupsen/logrus"
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
+)
 
-func CmdSupportIDs(is ISupportIDs) error {
+func CmdSupportIDs(is ISupportIDs, iout output.IOutput) error {
 	envID, orgID, usersID, podID, err := is.SupportIDs()
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		return iout.Render(struct {
+			EnvironmentID string `json:"environmentId"`
+			OrgID         string `json:"organizationId"`
+			UsersID       string `json:"userId"`
+			PodID         string `json:"podId"`
+		}{envID, orgID, usersID, podID})
+	}
 	logrus.Printf(`EnvironmentID:  %s
 OrganizationID: %s
 UsersID:        %s
@@ -6,16 +6,24 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/invites"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
 )
 
-func CmdList(myUsersID string, iu IUsers, ii invites.IInvites) error {
+// userOutput is the structured representation of an org user and the groups
+// they belong to used when a machine readable output format is selected.
+type userOutput struct {
+	models.OrgUser
+	Groups []string `json:"groups"`
+}
+
+func CmdList(myUsersID string, iu IUsers, ii invites.IInvites, iout output.IOutput) error {
 	orgUsers, err := iu.List()
 	if err != nil {
 		return err
 	}
-	if orgUsers == nil || len(*orgUsers) == 0 {
+	if (orgUsers == nil || len(*orgUsers) == 0) && !iout.Structured() {
 		logrus.Println("No users found")
 		return nil
 	}
@@ -30,6 +38,19 @@ func CmdList(myUsersID string, iu IUsers, ii invites.IInvites) error {
 			members[member.Email] = append(members[member.Email], group.Name)
 		}
 	}
+	if iout.Structured() {
+		out := []userOutput{}
+		if orgUsers != nil {
+			for _, user := range *orgUsers {
+				groups := members[user.Email]
+				if groups == nil {
+					groups = []string{}
+				}
+				out = append(out, userOutput{user, groups})
+			}
+		}
+		return iout.Render(out)
+	}
 	data := [][]string{{"EMAIL", "GROUP(S)"}}
 	for _, user := range *orgUsers {
 		if val, ok := members[user.Email]; ok {
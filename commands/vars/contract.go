@@ -5,6 +5,8 @@ import (
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/config"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -21,6 +23,9 @@ var Cmd = models.Command{
 			cmd.CommandLong(ListSubCmd.Name, ListSubCmd.ShortHelp, ListSubCmd.LongHelp, ListSubCmd.CmdFunc(settings))
 			cmd.CommandLong(SetSubCmd.Name, SetSubCmd.ShortHelp, SetSubCmd.LongHelp, SetSubCmd.CmdFunc(settings))
 			cmd.CommandLong(UnsetSubCmd.Name, UnsetSubCmd.ShortHelp, UnsetSubCmd.LongHelp, UnsetSubCmd.CmdFunc(settings))
+			cmd.CommandLong(DiffSubCmd.Name, DiffSubCmd.ShortHelp, DiffSubCmd.LongHelp, DiffSubCmd.CmdFunc(settings))
+			cmd.CommandLong(CopySubCmd.Name, CopySubCmd.ShortHelp, CopySubCmd.LongHelp, CopySubCmd.CmdFunc(settings))
+			cmd.CommandLong(ExportSubCmd.Name, ExportSubCmd.ShortHelp, ExportSubCmd.LongHelp, ExportSubCmd.CmdFunc(settings))
 		}
 	},
 }
@@ -46,9 +51,9 @@ var ListSubCmd = models.Command{
 					logrus.Fatal(err.Error())
 				}
 				var formatter Formatter
-				if *json {
+				if *json || settings.OutputFormat == output.FormatJSON {
 					formatter = &JSONFormatter{}
-				} else if *yaml {
+				} else if *yaml || settings.OutputFormat == output.FormatYAML {
 					formatter = &YAMLFormatter{}
 				} else {
 					formatter = &PlainFormatter{}
@@ -69,8 +74,13 @@ var SetSubCmd = models.Command{
 	LongHelp: "<code>vars set</code> allows you to add new environment variables or update the value of an existing environment variable on the given code service. " +
 		"You can set/update 1 or more environment variables at a time with this command by repeating the <code>-v</code> option multiple times. " +
 		"Once new environment variables are added or values updated, a redeploy is required for the given code service to have access to the new values. " +
-		"The environment variables must be of the form <code><key>=<value></code>. Here is a sample command\n\n" +
-		"<pre>\ndatica -E \"<your_env_name>\" vars set code-1 -v AWS_ACCESS_KEY_ID=1234 -v AWS_SECRET_ACCESS_KEY=5678\n</pre>",
+		"The environment variables must be of the form <code><key>=<value></code>. " +
+		"You can also import environment variables from a file with the <code>-f</code> option. " +
+		"The file can be in JSON, YAML, or KEY=VALUE format, or be an encrypted secrets file created by <code>vars export --encrypt</code>. " +
+		"Encrypted secrets files are decrypted in memory with the key given by <code>--key-file</code>, which defaults to the <code>DATICA_SECRETS_KEY_FILE</code> environment variable or <code>~/.datica-secrets.key</code>. " +
+		"Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" vars set code-1 -v AWS_ACCESS_KEY_ID=1234 -v AWS_SECRET_ACCESS_KEY=5678\n" +
+		"datica -E \"<your_env_name>\" vars set code-1 -f ./secrets.enc --key-file ./secrets.key\n</pre>",
 	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
 		return func(subCmd *cli.Cmd) {
 			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service on which the environment variables will be set.")
@@ -80,7 +90,8 @@ var SetSubCmd = models.Command{
 				Desc:      "The env variable to set or update in the form \"<key>=<value>\"",
 				HideValue: true,
 			})
-			fileName := subCmd.StringOpt("f file", "", "The path to a file to import environment variables from. This file can be in JSON, YAML, or KEY=VALUE format")
+			fileName := subCmd.StringOpt("f file", "", "The path to a file to import environment variables from. This file can be in JSON, YAML, or KEY=VALUE format, or be an encrypted secrets file")
+			keyFile := subCmd.StringOpt("key-file", "", "The path to the key used to decrypt an encrypted secrets file")
 			subCmd.Action = func() {
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
@@ -88,12 +99,12 @@ var SetSubCmd = models.Command{
 				if err := config.CheckRequiredAssociation(settings); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdSet(*serviceName, *variables, *fileName, New(settings), services.New(settings))
+				err := CmdSet(*serviceName, *variables, *fileName, *keyFile, New(settings), services.New(settings), crypto.New())
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
 			}
-			subCmd.Spec = "SERVICE_NAME (-v... | -f)"
+			subCmd.Spec = "SERVICE_NAME (-v... | -f [--key-file])"
 		}
 	},
 }
@@ -133,6 +144,121 @@ var UnsetSubCmd = models.Command{
 	},
 }
 
+var DiffSubCmd = models.Command{
+	Name:      "diff",
+	ShortHelp: "Compare the environment variables of two services",
+	LongHelp: "<code>vars diff</code> compares the environment variables of a code service against another code service. " +
+		"The other service can be in another associated environment given with <code>--env</code> and can have another name given with <code>--service</code>. " +
+		"Variables that only exist in the given service are shown as added (<code>+</code>), variables that only exist in the other service as removed (<code>-</code>), and variables with different values as changed (<code>~</code>). " +
+		"Values are masked unless <code>--show-values</code> is given. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"staging\" vars diff code-1 --env production\n" +
+		"datica -E \"staging\" vars diff code-1 --service code-2 --show-values\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(subCmd *cli.Cmd) {
+			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to compare")
+			otherEnv := subCmd.StringOpt("env", "", "The name of the associated environment containing the other service. Defaults to the current environment")
+			otherService := subCmd.StringOpt("service", "", "The name of the other service. Defaults to SERVICE_NAME")
+			showValues := subCmd.BoolOpt("show-values", false, "Show the values of the environment variables instead of masking them")
+			subCmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				otherSettings, err := OtherEnvironment(settings, *otherEnv)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdDiff(*serviceName, settings.EnvironmentName, *otherService, otherSettings.EnvironmentName, *showValues, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), output.New(settings))
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			subCmd.Spec = "SERVICE_NAME [--env] [--service] [--show-values]"
+		}
+	},
+}
+
+var CopySubCmd = models.Command{
+	Name:      "copy",
+	ShortHelp: "Copy environment variables to another service",
+	LongHelp: "<code>vars copy</code> copies the environment variables of a code service to another code service, such as promoting configuration from a staging environment to production. " +
+		"The other service can be in another associated environment given with <code>--env</code> and can have another name given with <code>--service</code>. " +
+		"By default every variable that is added or changed according to <code>vars diff</code> is copied, use <code>-k</code> to only copy the given variables. " +
+		"Variables that only exist in the other service are never removed. " +
+		"The variables to copy are shown with masked values and you are asked to confirm before they are copied. " +
+		"Once copied, a redeploy is required for the other service to have access to the new values. Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"staging\" vars copy code-1 --env production\n" +
+		"datica -E \"staging\" vars copy code-1 --env production -k AWS_ACCESS_KEY_ID -k AWS_SECRET_ACCESS_KEY\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(subCmd *cli.Cmd) {
+			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service to copy environment variables from")
+			otherEnv := subCmd.StringOpt("env", "", "The name of the associated environment containing the service to copy to. Defaults to the current environment")
+			otherService := subCmd.StringOpt("service", "", "The name of the service to copy to. Defaults to SERVICE_NAME")
+			keys := subCmd.Strings(cli.StringsOpt{
+				Name:      "k key",
+				Value:     []string{},
+				Desc:      "The name of an environment variable to copy. Defaults to all added and changed variables",
+				HideValue: true,
+			})
+			force := subCmd.BoolOpt("f force", false, "Copy the environment variables without prompting to confirm")
+			showValues := subCmd.BoolOpt("show-values", false, "Show the values of the environment variables instead of masking them")
+			subCmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				otherSettings, err := OtherEnvironment(settings, *otherEnv)
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err = CmdCopy(*serviceName, settings.EnvironmentName, *otherService, otherSettings.EnvironmentName, *keys, *force, *showValues, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), prompts.New())
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			subCmd.Spec = "SERVICE_NAME [--env] [--service] [-k...] [-f] [--show-values]"
+		}
+	},
+}
+
+var ExportSubCmd = models.Command{
+	Name:      "export",
+	ShortHelp: "Export environment variables to a file, optionally encrypted",
+	LongHelp: "<code>vars export</code> writes all environment variables of the given code service to a YAML file that can be imported again with <code>vars set -f</code>. " +
+		"With <code>--encrypt</code> the file is encrypted with a local key so it can be safely stored or shared, and only decrypted in memory by <code>vars set -f</code>. " +
+		"The key is read from <code>--key-file</code>, which defaults to the <code>DATICA_SECRETS_KEY_FILE</code> environment variable or <code>~/.datica-secrets.key</code>. " +
+		"If no key exists at that path, a new one is generated. Keep the key safe, without it the encrypted file cannot be imported. " +
+		"Here are some sample commands\n\n" +
+		"<pre>\ndatica -E \"<your_env_name>\" vars export code-1 ./vars.yml\n" +
+		"datica -E \"<your_env_name>\" vars export code-1 ./secrets.enc --encrypt --key-file ./secrets.key\n</pre>",
+	CmdFunc: func(settings *models.Settings) func(cmd *cli.Cmd) {
+		return func(subCmd *cli.Cmd) {
+			serviceName := subCmd.StringArg("SERVICE_NAME", "", "The name of the service containing the environment variables.")
+			filePath := subCmd.StringArg("FILEPATH", "", "The location to save the exported environment variables.")
+			encrypt := subCmd.BoolOpt("encrypt", false, "Encrypt the exported file with a local key")
+			keyFile := subCmd.StringOpt("key-file", "", "The path to the key used to encrypt the file. A new key is generated if none exists")
+			force := subCmd.BoolOpt("f force", false, "If a file previously exists at \"filepath\", overwrite it")
+			subCmd.Action = func() {
+				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				if err := config.CheckRequiredAssociation(settings); err != nil {
+					logrus.Fatal(err.Error())
+				}
+				err := CmdExport(*serviceName, *filePath, *encrypt, *keyFile, *force, New(settings), services.New(settings), crypto.New())
+				if err != nil {
+					logrus.Fatal(err.Error())
+				}
+			}
+			subCmd.Spec = "SERVICE_NAME FILEPATH [--encrypt] [--key-file] [-f]"
+		}
+	},
+}
+
 // IVars
 type IVars interface {
 	List(svcID string) (map[string]string, error)
@@ -0,0 +1,67 @@
+package vars
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/prompts"
+)
+
+func CmdCopy(svcName, envName, otherSvcName, otherEnvName string, keys []string, force, showValues bool, iv, otherIV IVars, is, otherIS services.IServices, ip prompts.IPrompts) error {
+	if otherSvcName == "" {
+		otherSvcName = svcName
+	}
+	if otherEnvName == "" {
+		otherEnvName = envName
+	}
+	if otherSvcName == svcName && otherEnvName == envName {
+		return fmt.Errorf("Specify another environment with --env or another service with --service to copy the environment variables of %s to", svcName)
+	}
+	_, envVars, err := retrieveVars(svcName, envName, iv, is)
+	if err != nil {
+		return err
+	}
+	otherService, otherEnvVars, err := retrieveVars(otherSvcName, otherEnvName, otherIV, otherIS)
+	if err != nil {
+		return err
+	}
+	selected := map[string]bool{}
+	for _, key := range keys {
+		if _, ok := envVars[key]; !ok {
+			return fmt.Errorf("%s does not have an environment variable named %s", svcName, key)
+		}
+		selected[key] = true
+	}
+
+	// only added and changed variables are copied, variables that only exist
+	// in the other service are left alone
+	diffs := []VarDiff{}
+	toSet := map[string]string{}
+	for _, d := range diffVars(envVars, otherEnvVars) {
+		if d.Change == diffRemoved || (len(selected) > 0 && !selected[d.Key]) {
+			continue
+		}
+		diffs = append(diffs, d)
+		toSet[d.Key] = d.Value
+	}
+	if len(toSet) == 0 {
+		logrus.Printf("There are no environment variables to copy, %s (%s) already has the same values", otherSvcName, otherEnvName)
+		return nil
+	}
+	if !showValues {
+		maskDiffs(diffs)
+	}
+	logrus.Printf("Copying from %s (%s) to %s (%s)", svcName, envName, otherSvcName, otherEnvName)
+	printDiffs(diffs, showValues)
+	if !force {
+		if err = ip.YesNo("", "Would you like to copy these environment variables? (y/n) "); err != nil {
+			return err
+		}
+	}
+	if err = otherIV.Set(otherService.ID, toSet); err != nil {
+		return err
+	}
+	logrus.Printf("Copied %d environment variables. For these environment variables to take effect, you will need to redeploy %s in %s with \"datica -E %s redeploy %s\"", len(toSet), otherSvcName, otherEnvName, otherEnvName, otherSvcName)
+	return nil
+}
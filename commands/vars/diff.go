@@ -0,0 +1,170 @@
+package vars
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/models"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+
+	maskedValue = "********"
+)
+
+// VarDiff is a single environment variable that differs between two services.
+// Value is the value in the compared service and OtherValue the value in the
+// other service.
+type VarDiff struct {
+	Key        string `json:"key"`
+	Change     string `json:"change"`
+	Value      string `json:"value,omitempty"`
+	OtherValue string `json:"otherValue,omitempty"`
+}
+
+// OtherEnvironment returns a copy of the given settings pointed at another
+// associated environment. If no environment name is given, the settings are
+// returned as is.
+func OtherEnvironment(settings *models.Settings, envName string) (*models.Settings, error) {
+	if envName == "" {
+		return settings, nil
+	}
+	other := *settings
+	other.EnvironmentID = ""
+	config.SetGivenEnv(envName, &other)
+	if other.EnvironmentID == "" {
+		return nil, fmt.Errorf("No environment with the name \"%s\" has been associated. You can list associated environments with the \"datica environments list\" command.", envName)
+	}
+	return &other, nil
+}
+
+// retrieveVars finds a service by label and lists its environment variables.
+func retrieveVars(svcName, envName string, iv IVars, is services.IServices) (*models.Service, map[string]string, error) {
+	service, err := is.RetrieveByLabel(svcName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if service == nil {
+		return nil, nil, fmt.Errorf("Could not find a service with the label \"%s\" in the environment \"%s\". You can list services with the \"datica services list\" command.", svcName, envName)
+	}
+	envVars, err := iv.List(service.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service, envVars, nil
+}
+
+// diffVars compares the environment variables of a service against those of
+// another service. Keys only in the service are added, keys only in the other
+// service are removed, and keys with different values are changed.
+func diffVars(envVars, otherEnvVars map[string]string) []VarDiff {
+	diffs := []VarDiff{}
+	for k, v := range envVars {
+		otherValue, ok := otherEnvVars[k]
+		if !ok {
+			diffs = append(diffs, VarDiff{Key: k, Change: diffAdded, Value: v})
+		} else if otherValue != v {
+			diffs = append(diffs, VarDiff{Key: k, Change: diffChanged, Value: v, OtherValue: otherValue})
+		}
+	}
+	for k, v := range otherEnvVars {
+		if _, ok := envVars[k]; !ok {
+			diffs = append(diffs, VarDiff{Key: k, Change: diffRemoved, OtherValue: v})
+		}
+	}
+	sort.Sort(SortedVarDiffs(diffs))
+	return diffs
+}
+
+// maskDiffs hides the values of the given differences.
+func maskDiffs(diffs []VarDiff) {
+	for i := range diffs {
+		if diffs[i].Value != "" {
+			diffs[i].Value = maskedValue
+		}
+		if diffs[i].OtherValue != "" {
+			diffs[i].OtherValue = maskedValue
+		}
+	}
+}
+
+func printDiffs(diffs []VarDiff, showValues bool) {
+	for _, d := range diffs {
+		switch d.Change {
+		case diffAdded:
+			if showValues {
+				logrus.Printf("+ %s=%s", d.Key, d.Value)
+			} else {
+				logrus.Printf("+ %s", d.Key)
+			}
+		case diffRemoved:
+			if showValues {
+				logrus.Printf("- %s=%s", d.Key, d.OtherValue)
+			} else {
+				logrus.Printf("- %s", d.Key)
+			}
+		case diffChanged:
+			if showValues {
+				logrus.Printf("~ %s: %s -> %s", d.Key, d.OtherValue, d.Value)
+			} else {
+				logrus.Printf("~ %s", d.Key)
+			}
+		}
+	}
+}
+
+func CmdDiff(svcName, envName, otherSvcName, otherEnvName string, showValues bool, iv, otherIV IVars, is, otherIS services.IServices, iout output.IOutput) error {
+	if otherSvcName == "" {
+		otherSvcName = svcName
+	}
+	if otherEnvName == "" {
+		otherEnvName = envName
+	}
+	if otherSvcName == svcName && otherEnvName == envName {
+		return fmt.Errorf("Specify another environment with --env or another service with --service to compare %s against", svcName)
+	}
+	_, envVars, err := retrieveVars(svcName, envName, iv, is)
+	if err != nil {
+		return err
+	}
+	_, otherEnvVars, err := retrieveVars(otherSvcName, otherEnvName, otherIV, otherIS)
+	if err != nil {
+		return err
+	}
+	diffs := diffVars(envVars, otherEnvVars)
+	if !showValues {
+		maskDiffs(diffs)
+	}
+	if iout.Structured() {
+		return iout.Render(diffs)
+	}
+	if len(diffs) == 0 {
+		logrus.Printf("The environment variables of %s (%s) and %s (%s) are the same", svcName, envName, otherSvcName, otherEnvName)
+		return nil
+	}
+	logrus.Printf("Comparing %s (%s) against %s (%s)", svcName, envName, otherSvcName, otherEnvName)
+	printDiffs(diffs, showValues)
+	return nil
+}
+
+// SortedVarDiffs is a wrapper for VarDiff arrays in order to sort them by key
+type SortedVarDiffs []VarDiff
+
+func (s SortedVarDiffs) Len() int {
+	return len(s)
+}
+
+func (s SortedVarDiffs) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s SortedVarDiffs) Less(i, j int) bool {
+	return s[i].Key < s[j].Key
+}
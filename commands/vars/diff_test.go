@@ -0,0 +1,145 @@
+package vars
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/output"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+// setupOtherEnvironment mocks a code service with the same label in two
+// environments and returns the settings of the other environment along with
+// the variables set in it.
+func setupOtherEnvironment(t *testing.T, mux *http.ServeMux, settings *models.Settings) (*models.Settings, *map[string]string) {
+	settings.Environments[test.AliasAlt] = models.AssociatedEnvV2{
+		Name:          test.EnvNameAlt,
+		EnvironmentID: test.EnvIDAlt,
+		Pod:           test.PodAlt,
+		OrgID:         test.OrgIDAlt,
+	}
+	otherSettings, err := OtherEnvironment(settings, test.EnvNameAlt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	set := map[string]string{}
+	mux.HandleFunc("/environments/"+test.EnvID+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s"}]`, test.SvcID, test.SvcLabel))
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvIDAlt+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s"}]`, test.SvcIDAlt, test.SvcLabel))
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/env",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, `{"SAME":"value","CHANGED":"staging","ADDED":"value"}`)
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvIDAlt+"/services/"+test.SvcIDAlt+"/env",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				b, _ := ioutil.ReadAll(r.Body)
+				json.Unmarshal(b, &set)
+				fmt.Fprint(w, `{}`)
+				return
+			}
+			fmt.Fprint(w, `{"SAME":"value","CHANGED":"production","REMOVED":"value"}`)
+		},
+	)
+	return otherSettings, &set
+}
+
+func TestOtherEnvironment(t *testing.T) {
+	settings := test.GetSettings("")
+	if _, err := OtherEnvironment(settings, "unknown-env"); err == nil {
+		t.Fatalf("Expected an error for an environment that is not associated")
+	}
+	same, err := OtherEnvironment(settings, "")
+	if err != nil || same != settings {
+		t.Fatalf("Expected the same settings when no environment is given")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	otherSettings, _ := setupOtherEnvironment(t, mux, settings)
+	if otherSettings.EnvironmentID != test.EnvIDAlt || settings.EnvironmentID != test.EnvID {
+		t.Fatalf("Expected the other settings to point at %s without changing the current settings", test.EnvIDAlt)
+	}
+
+	_, envVars, _ := retrieveVars(test.SvcLabel, test.EnvName, New(settings), services.New(settings))
+	_, otherEnvVars, _ := retrieveVars(test.SvcLabel, test.EnvNameAlt, New(otherSettings), services.New(otherSettings))
+	diffs := diffVars(envVars, otherEnvVars)
+	expected := []VarDiff{
+		{Key: "ADDED", Change: diffAdded, Value: "value"},
+		{Key: "CHANGED", Change: diffChanged, Value: "staging", OtherValue: "production"},
+		{Key: "REMOVED", Change: diffRemoved, OtherValue: "value"},
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences but got %d: %+v", len(expected), len(diffs), diffs)
+	}
+	for i := range diffs {
+		if diffs[i] != expected[i] {
+			t.Errorf("Expected %+v but got %+v", expected[i], diffs[i])
+		}
+	}
+	maskDiffs(diffs)
+	if diffs[1].Value != maskedValue || diffs[1].OtherValue != maskedValue || diffs[0].OtherValue != "" {
+		t.Errorf("Expected the values to be masked but got %+v", diffs)
+	}
+
+	err := CmdDiff(test.SvcLabel, test.EnvName, "", test.EnvName, false, New(settings), New(settings), services.New(settings), services.New(settings), output.New(settings))
+	if err == nil {
+		t.Errorf("Expected an error comparing a service against itself")
+	}
+	err = CmdDiff(test.SvcLabel, test.EnvName, "", test.EnvNameAlt, false, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), output.New(settings))
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	otherSettings, set := setupOtherEnvironment(t, mux, settings)
+
+	// test
+	err := CmdCopy(test.SvcLabel, test.EnvName, "", test.EnvNameAlt, []string{}, true, false, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), &test.FakePrompts{})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(*set) != 2 || (*set)["ADDED"] != "value" || (*set)["CHANGED"] != "staging" {
+		t.Fatalf("Expected ADDED and CHANGED to be copied but got %v", *set)
+	}
+
+	// only the selected variables are copied
+	*set = map[string]string{}
+	err = CmdCopy(test.SvcLabel, test.EnvName, "", test.EnvNameAlt, []string{"CHANGED"}, true, false, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), &test.FakePrompts{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(*set) != 1 || (*set)["CHANGED"] != "staging" {
+		t.Fatalf("Expected only CHANGED to be copied but got %v", *set)
+	}
+
+	err = CmdCopy(test.SvcLabel, test.EnvName, "", test.EnvNameAlt, []string{"MISSING"}, true, false, New(settings), New(otherSettings), services.New(settings), services.New(otherSettings), &test.FakePrompts{})
+	if err == nil {
+		t.Fatalf("Expected an error copying a variable that does not exist")
+	}
+}
@@ -0,0 +1,54 @@
+package vars
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/crypto"
+	"gopkg.in/yaml.v2"
+)
+
+func CmdExport(svcName, filePath string, encrypt bool, keyFile string, force bool, iv IVars, is services.IServices, ic crypto.ICrypto) error {
+	if _, err := os.Stat(filePath); err == nil && !force {
+		return fmt.Errorf("A file already exists at path '%s'. Specify `-f` to overwrite it", filePath)
+	}
+	service, err := is.RetrieveByLabel(svcName)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return fmt.Errorf("Could not find a service with the label \"%s\". You can list services with the \"datica services list\" command.", svcName)
+	}
+	envVars, err := iv.List(service.ID)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(envVars)
+	if err != nil {
+		return err
+	}
+	generated := false
+	if encrypt {
+		keyFile = secretsKeyPath(keyFile)
+		var key []byte
+		key, generated, err = loadSecretsKey(keyFile, true, ic)
+		if err != nil {
+			return err
+		}
+		data, err = encryptSecrets(data, key, ic)
+		if err != nil {
+			return err
+		}
+	}
+	if err = ioutil.WriteFile(filePath, data, 0600); err != nil {
+		return err
+	}
+	if generated {
+		logrus.Printf("Generated a new secrets key at %s. Keep this key safe, it is required to import the exported file", keyFile)
+	}
+	logrus.Printf("Exported %d environment variables to %s", len(envVars), filePath)
+	return nil
+}
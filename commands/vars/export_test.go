@@ -0,0 +1,79 @@
+package vars
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/test"
+)
+
+const (
+	exportPath = "datica-vars-export-test.enc"
+	keyPath    = "datica-vars-export-test.key"
+)
+
+func TestExportEncrypted(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	settings := test.GetSettings(baseURL.String())
+	set := map[string]string{}
+	mux.HandleFunc("/environments/"+test.EnvID+"/services",
+		func(w http.ResponseWriter, r *http.Request) {
+			test.AssertEquals(t, r.Method, "GET")
+			fmt.Fprint(w, fmt.Sprintf(`[{"id":"%s","label":"%s"}]`, test.SvcID, test.SvcLabel))
+		},
+	)
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/env",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				b, _ := ioutil.ReadAll(r.Body)
+				json.Unmarshal(b, &set)
+				fmt.Fprint(w, `{}`)
+				return
+			}
+			fmt.Fprint(w, `{"DATABASE_URL":"postgres://user:secret@db:5432/app","API_KEY":"1234"}`)
+		},
+	)
+	defer os.Remove(exportPath)
+	defer os.Remove(keyPath)
+
+	// test
+	err := CmdExport(test.SvcLabel, exportPath, true, keyPath, false, New(settings), services.New(settings), crypto.New())
+
+	// assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err = os.Stat(keyPath); err != nil {
+		t.Fatalf("Expected a key to be generated at %s", keyPath)
+	}
+	b, _ := ioutil.ReadFile(exportPath)
+	if !isEncryptedSecrets(b) {
+		t.Fatalf("Expected an encrypted secrets file but got %s", string(b))
+	}
+	if err = CmdExport(test.SvcLabel, exportPath, true, keyPath, false, New(settings), services.New(settings), crypto.New()); err == nil {
+		t.Fatalf("Expected an error overwriting an existing file without -f")
+	}
+
+	// the encrypted file can be imported with the same key
+	err = CmdSet(test.SvcLabel, []string{}, exportPath, keyPath, New(settings), services.New(settings), crypto.New())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(set) != 2 || set["DATABASE_URL"] != "postgres://user:secret@db:5432/app" || set["API_KEY"] != "1234" {
+		t.Fatalf("Expected the exported variables to be set but got %v", set)
+	}
+
+	// but not with another key
+	ioutil.WriteFile(keyPath, []byte("0000000000000000000000000000000000000000000000000000000000000000\n"), 0600)
+	err = CmdSet(test.SvcLabel, []string{}, exportPath, keyPath, New(settings), services.New(settings), crypto.New())
+	if err == nil {
+		t.Fatalf("Expected an error decrypting with the wrong key")
+	}
+}
@@ -66,7 +66,7 @@ func CmdList(svcName string, formatter Formatter, iv IVars, is services.IService
 	if err != nil {
 		return err
 	}
-	if len(envVars) == 0 {
+	if _, ok := formatter.(*PlainFormatter); ok && len(envVars) == 0 {
 		logrus.Println("No environment variables found")
 		return nil
 	}
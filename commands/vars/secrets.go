@@ -0,0 +1,82 @@
+package vars
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/crypto"
+)
+
+// secretsHeader is the first line of every encrypted secrets file. The rest of
+// the file is the base64 encoded output of ICrypto.EncryptBytes.
+const secretsHeader = "DATICA-SECRETS-V1"
+
+// isEncryptedSecrets reports whether the given file contents are an encrypted
+// secrets file.
+func isEncryptedSecrets(fileData []byte) bool {
+	return bytes.HasPrefix(fileData, []byte(secretsHeader+"\n"))
+}
+
+// encryptSecrets encrypts the given plaintext into the contents of a secrets
+// file.
+func encryptSecrets(plaintext, key []byte, ic crypto.ICrypto) ([]byte, error) {
+	encrypted, err := ic.EncryptBytes(plaintext, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s\n%s\n", secretsHeader, base64.StdEncoding.EncodeToString(encrypted))), nil
+}
+
+// decryptSecrets decrypts the contents of a secrets file in memory.
+func decryptSecrets(fileData, key []byte, ic crypto.ICrypto) ([]byte, error) {
+	encoded := strings.TrimSpace(strings.TrimPrefix(string(fileData), secretsHeader+"\n"))
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("Invalid secrets file. The encrypted contents are not valid base64")
+	}
+	return ic.DecryptBytes(encrypted, key)
+}
+
+// secretsKeyPath returns the given key file path or the default one if none
+// was given.
+func secretsKeyPath(keyFile string) string {
+	if keyFile == "" {
+		return config.SecretsKeyPath()
+	}
+	return keyFile
+}
+
+// loadSecretsKey reads the hex encoded key used for secrets files. If create
+// is true and no key exists at the given path, a new one is generated. The
+// returned bool reports whether a new key was generated.
+func loadSecretsKey(keyFile string, create bool, ic crypto.ICrypto) ([]byte, bool, error) {
+	keyData, err := ioutil.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, false, fmt.Errorf("No secrets key exists at '%s'. Specify the key used to encrypt the file with the --key-file option", keyFile)
+		}
+		key := make([]byte, crypto.KeySize)
+		if _, err = rand.Read(key); err != nil {
+			return nil, false, err
+		}
+		if err = ioutil.WriteFile(keyFile, append(ic.Hex(key, crypto.KeySize*2), '\n'), 0600); err != nil {
+			return nil, false, err
+		}
+		return key, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(keyData)))
+	if err != nil || len(key) != crypto.KeySize {
+		return nil, false, fmt.Errorf("Invalid secrets key at '%s'. The key must be %d hex encoded bytes", keyFile, crypto.KeySize)
+	}
+	return key, false, nil
+}
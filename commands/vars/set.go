@@ -13,9 +13,10 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
+	"github.com/daticahealth/cli/lib/crypto"
 )
 
-func CmdSet(svcName string, variables []string, fileName string, iv IVars, is services.IServices) error {
+func CmdSet(svcName string, variables []string, fileName, keyFile string, iv IVars, is services.IServices, ic crypto.ICrypto) error {
 	var envVarsMap map[string]string
 	var err error
 	if fileName != "" {
@@ -26,6 +27,16 @@ func CmdSet(svcName string, variables []string, fileName string, iv IVars, is se
 		if err != nil {
 			return err
 		}
+		if isEncryptedSecrets(fileData) {
+			key, _, err := loadSecretsKey(secretsKeyPath(keyFile), false, ic)
+			if err != nil {
+				return err
+			}
+			fileData, err = decryptSecrets(fileData, key, ic)
+			if err != nil {
+				return err
+			}
+		}
 		envVarsMap, err = parseFileData(fileData)
 		if err != nil {
 			return err
@@ -3,6 +3,7 @@ package whoami
 import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/lib/auth"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/jault3/mow.cli"
@@ -22,7 +23,7 @@ var Cmd = models.Command{
 				if _, err := auth.New(settings, prompts.New()).Signin(); err != nil {
 					logrus.Fatal(err.Error())
 				}
-				err := CmdWhoAmI(New(settings))
+				err := CmdWhoAmI(New(settings), output.New(settings))
 				if err != nil {
 					logrus.Fatal(err.Error())
 				}
@@ -1,12 +1,20 @@
 package whoami
 
-import "github.com/Sirupsen/logrus"
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/output"
+)
 
-func CmdWhoAmI(w IWhoAmI) error {
+func CmdWhoAmI(w IWhoAmI, iout output.IOutput) error {
 	usersID, err := w.WhoAmI()
 	if err != nil {
 		return err
 	}
+	if iout.Structured() {
+		return iout.Render(struct {
+			UsersID string `json:"user_id"`
+		}{usersID})
+	}
 	logrus.Printf("user ID = %s", usersID)
 	return nil
 }
@@ -6,11 +6,28 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/commands/services"
 	"github.com/daticahealth/cli/lib/jobs"
+	"github.com/daticahealth/cli/lib/output"
 	"github.com/daticahealth/cli/models"
 	"github.com/olekukonko/tablewriter"
+	"github.com/pmylund/sortutil"
 )
 
-func CmdList(svcName string, iw IWorker, is services.IServices, ij jobs.IJobs) error {
+// workerOutput is the structured representation of a single worker target
+// used when a machine readable output format is selected.
+type workerOutput struct {
+	Target  string `json:"target"`
+	Scale   int    `json:"scale"`
+	Running int    `json:"running"`
+}
+
+// workersOutput is the structured representation of all worker targets for a
+// service used when a machine readable output format is selected.
+type workersOutput struct {
+	Limit   int            `json:"worker_limit"`
+	Workers []workerOutput `json:"workers"`
+}
+
+func CmdList(svcName string, iw IWorker, is services.IServices, ij jobs.IJobs, iout output.IOutput) error {
 	service, err := is.RetrieveByLabel(svcName)
 	if err != nil {
 		return err
@@ -35,7 +52,7 @@ func CmdList(svcName string, iw IWorker, is services.IServices, ij jobs.IJobs) e
 	for target, scale := range workers.Workers {
 		workerJobs[target] = &workerJob{scale, 0}
 	}
-	if len(workerJobs) == 0 {
+	if len(workerJobs) == 0 && !iout.Structured() {
 		logrus.Printf("No running workers found for %s", svcName)
 		logrus.Printf("\nYou are using 0 out of your available %d workers for %s", service.WorkerScale, svcName)
 		return nil
@@ -49,6 +66,15 @@ func CmdList(svcName string, iw IWorker, is services.IServices, ij jobs.IJobs) e
 		}
 	}
 
+	if iout.Structured() {
+		out := workersOutput{Limit: service.WorkerScale, Workers: []workerOutput{}}
+		for target, wj := range workerJobs {
+			out.Workers = append(out.Workers, workerOutput{target, wj.scale, wj.running})
+		}
+		sortutil.AscByField(out.Workers, "Target")
+		return iout.Render(out)
+	}
+
 	data := [][]string{{"TARGET", "SCALE", "RUNNING JOBS"}}
 	total := 0
 	for target, wj := range workerJobs {
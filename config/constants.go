@@ -25,6 +25,10 @@ const (
 	LogLevel = logrus.InfoLevel
 	// JobPollTime is the amount of time in seconds to wait between polls for a job status
 	JobPollTime = 5
+	// JobMaxPollTime is the most time in seconds the poll interval for a job status backs off to
+	JobMaxPollTime = 30
+	// JobAppearTimeout is the amount of time in minutes to wait for a job, such as a snapshot backup, to show up
+	JobAppearTimeout = 60
 	// LogPollTime is the amount of time in seconds to wait between polls for new logs
 	LogPollTime = 3
 
@@ -42,6 +46,8 @@ const (
 	DaticaEmailEnvVar = "DATICA_EMAIL"
 	// DaticaPasswordEnvVar is the env variable used to override the passowrd
 	DaticaPasswordEnvVar = "DATICA_PASSWORD"
+	// ProfileEnvVar is the env variable used to select the settings profile
+	ProfileEnvVar = "DATICA_PROFILE"
 	// DaticaEnvironmentEnvVar is the env variable used to override the environment used in the current command
 	DaticaEnvironmentEnvVar = "DATICA_ENV"
 	// LogLevelEnvVar is the env variable used to override the logging level used
@@ -50,6 +56,40 @@ const (
 	SkipVerifyEnvVar = "SKIP_VERIFY"
 	// DaticaConfigFile points the CLI at a .datica file
 	DaticaConfigFile = "DATICA_CONFIG_FILE"
+	// OutputFormatEnvVar is the env variable used to set the output format of list and show commands
+	OutputFormatEnvVar = "DATICA_OUTPUT"
+	// SecretsKeyFileEnvVar points the CLI at the key used to encrypt and decrypt secrets files
+	SecretsKeyFileEnvVar = "DATICA_SECRETS_KEY_FILE"
+	// HTTPTimeoutEnvVar is the env variable used to set the timeout of each request
+	HTTPTimeoutEnvVar = "DATICA_HTTP_TIMEOUT"
+	// HTTPRetriesEnvVar is the env variable used to set how many times a failed request is retried
+	HTTPRetriesEnvVar = "DATICA_HTTP_RETRIES"
+	// TraceEnvVar is the env variable used to print a redacted trace of every request
+	TraceEnvVar = "DATICA_TRACE"
+	// JobTimeoutEnvVar is the env variable used to set how long to wait for a job
+	JobTimeoutEnvVar = "DATICA_JOB_TIMEOUT"
+	// JobPollIntervalEnvVar is the env variable used to set the time between the first polls for a job status
+	JobPollIntervalEnvVar = "DATICA_POLL_INTERVAL"
+	// TokenStoreEnvVar is the env variable used to choose where the session token is kept
+	TokenStoreEnvVar = "DATICA_TOKEN_STORE"
+	// CredentialHelperEnvVar is the env variable used to set the credential helper program
+	CredentialHelperEnvVar = "DATICA_CREDENTIAL_HELPER"
+	// VaultFileEnvVar is the env variable used to override the location of the credential vault
+	VaultFileEnvVar = "DATICA_VAULT_FILE"
+	// VaultKeyFileEnvVar is the env variable used to unlock the credential vault with a key file
+	VaultKeyFileEnvVar = "DATICA_VAULT_KEY_FILE"
+	// VaultPassphraseEnvVar is the env variable used to unlock the credential vault with a passphrase
+	VaultPassphraseEnvVar = "DATICA_VAULT_PASSPHRASE"
+	// ProxyEnvVar is the env variable used to set the proxy for every connection
+	ProxyEnvVar = "DATICA_PROXY"
+	// NoProxyEnvVar is the env variable used to list the hosts that bypass the proxy
+	NoProxyEnvVar = "DATICA_NO_PROXY"
+	// CABundleEnvVar is the env variable used to trust extra certificate authorities
+	CABundleEnvVar = "DATICA_CA_BUNDLE"
+	// ClientCertEnvVar is the env variable used to present a client certificate
+	ClientCertEnvVar = "DATICA_CLIENT_CERT"
+	// ClientKeyEnvVar is the env variable used to set the private key of the client certificate
+	ClientKeyEnvVar = "DATICA_CLIENT_KEY"
 
 	// DaticaUsernameEnvVarDeprecated is the deprecated env variable used to override the username
 	DaticaUsernameEnvVarDeprecated = "DATICA_USERNAME"
@@ -0,0 +1,32 @@
+// +build windows
+
+package config
+
+import (
+	"syscall"
+)
+
+// errorSharingViolation is returned by CreateFile when another process has
+// the file open without sharing it.
+const errorSharingViolation syscall.Errno = 32
+
+// tryLockFile takes an exclusive lock on the file at path without blocking by
+// opening it without sharing. The lock is released by calling the returned
+// function or when the process exits. ok is false if another process holds the
+// lock.
+func tryLockFile(path string) (unlock func(), ok bool, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, false, err
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if err == errorSharingViolation {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return func() {
+		syscall.CloseHandle(h)
+	}, true, nil
+}
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/models"
@@ -16,9 +21,16 @@ import (
 const (
 	settingsFormatV1 = "v1"
 	settingsFormatV2 = "v2"
+	settingsFormatV3 = "v3"
 
 	OldSettingsFile = ".catalyze"
-	currentFormat   = settingsFormatV2
+	currentFormat   = settingsFormatV3
+
+	// DefaultProfile is the name of the profile used when none is given and
+	// the settings file does not name a default
+	DefaultProfile = "default"
+
+	settingsLockTimeout = 10 * time.Second
 )
 
 var SettingsFile = resolveSettingsPath()
@@ -35,65 +47,90 @@ func resolveSettingsPath() string {
 	return settingsPath
 }
 
+// SecretsKeyPath returns the path of the key used to encrypt and decrypt
+// secrets files when no path is given.
+func SecretsKeyPath() string {
+	keyPath := os.Getenv(SecretsKeyFileEnvVar)
+	if len(keyPath) == 0 {
+		home, err := homedir.Dir()
+		if err != nil {
+			panic(err)
+		}
+		keyPath = filepath.Join(home, ".datica-secrets.key")
+	}
+	return keyPath
+}
+
 // SettingsRetriever defines an interface for a class responsible for generating
 // a settings object used for most commands in the CLI. Some examples might be
 // for retrieving settings based on the settings file or generating a settings
 // object based on a directly entered environment ID and service ID.
 type SettingsRetriever interface {
-	GetSettings(string, string, string, string, string, string, string, string, string) (*models.Settings, error)
+	GetSettings(string, string, string, string, string, string, string, string, string, string) (*models.Settings, error)
 }
 
 // FileSettingsRetriever reads in data from the SettingsFile and generates a
 // settings object.
 type FileSettingsRetriever struct{}
 
-// GetSettings returns a Settings object for the current context
-func (s FileSettingsRetriever) GetSettings(envName, svcName, accountsHost, authHost, ignoreAuthHostVersion, paasHost, ignorePaasHostVersion, email, password string) (*models.Settings, error) {
-	home, err := homedir.Dir()
+// GetSettings returns a Settings object for the current context. The given
+// profile is used, or the default profile of the settings file if it is
+// empty. Hosts that are not given fall back to the hosts of the profile and
+// then to the production Datica hosts.
+func (s FileSettingsRetriever) GetSettings(profile, envName, svcName, accountsHost, authHost, ignoreAuthHostVersion, paasHost, ignorePaasHostVersion, email, password string) (*models.Settings, error) {
+	unlock, err := lockSettings()
 	if err != nil {
 		return nil, err
 	}
-	if _, err = os.Stat(filepath.Join(home, OldSettingsFile)); err == nil {
-		logrus.Debugln("Migrating settings file from .catalyze to .datica")
-		err = os.Rename(filepath.Join(home, OldSettingsFile), SettingsFile)
-		if err != nil {
-			return nil, fmt.Errorf("Error encountered migrating the settings file from .catalyze to .datica: %s. To fix this, please run \"mv %s %s\".", err, filepath.Join(home, OldSettingsFile), SettingsFile)
-		}
-	}
-
-	file, err := os.Open(SettingsFile)
-	if os.IsNotExist(err) {
-		file, err = os.Create(SettingsFile)
-	}
-	defer file.Close()
+	settingsFile, err := readSettingsFile()
+	unlock()
 	if err != nil {
 		return nil, err
 	}
-	var settings models.Settings
-	json.NewDecoder(file).Decode(&settings)
-	if settings.Format != currentFormat {
-		if settings.Format == "" {
-			settings.Format = "v1"
-		}
-		file.Seek(0, 0)
-		settings, err = migrateSettings(file, settings.Format, currentFormat)
-		if err != nil {
-			return nil, err
+	if profile == "" {
+		profile = settingsFile.DefaultProfile
+	}
+	p, ok := settingsFile.Profiles[profile]
+	if !ok {
+		// a mistyped profile would otherwise be created with the production
+		// hosts, so one is only created when signing in or given hosts
+		if profile != settingsFile.DefaultProfile && email == "" && accountsHost == "" && authHost == "" && paasHost == "" {
+			return nil, fmt.Errorf("The profile \"%s\" does not exist. The existing profiles are %s. To create it, sign in with --email or set its hosts with %s, %s, and %s", profile, strings.Join(profileNames(settingsFile), ", "), AccountsHostEnvVar, AuthHostEnvVar, PaasHostEnvVar)
 		}
+		logrus.Debugf("Profile %s does not exist yet and will be created", profile)
+	}
+	settings := models.Settings{
+		Profile:        profile,
+		PrivateKeyPath: p.PrivateKeyPath,
+		SessionToken:   p.SessionToken,
+		UsersID:        p.UsersID,
+		Environments:   p.Environments,
+		Pods:           p.Pods,
+		PodCheck:       p.PodCheck,
+		Format:         currentFormat,
 	}
 	if settings.Environments == nil {
 		settings.Environments = make(map[string]models.AssociatedEnvV2)
 	}
+	// an unreadable session only fails the commands that sign in, so it can
+	// still be cleared
+	if err = loadSession(profile, p, &settings); err != nil {
+		logrus.Debugf("Could not load the session of profile %s: %s", profile, err)
+		settings.SessionError = err
+	}
 
 	// try and set the given env first, if it exists
 	if envName != "" {
 		SetGivenEnv(envName, &settings)
 	}
 
+	accountsHost = firstNonEmpty(accountsHost, p.AccountsHost, AccountsHost)
+	authHost = firstNonEmpty(authHost, p.AuthHost, AuthHost)
+	paasHost = firstNonEmpty(paasHost, p.PaasHost, PaasHost)
 	settings.AccountsHost = accountsHost
 	settings.AuthHost = authHost
 	settings.PaasHost = paasHost
-	settings.Email = email
+	settings.Email = firstNonEmpty(email, p.Email)
 	settings.Password = password
 
 	authHostVersion := os.Getenv(AuthHostVersionEnvVar)
@@ -108,6 +145,7 @@ func (s FileSettingsRetriever) GetSettings(envName, svcName, accountsHost, authH
 	}
 	settings.PaasHostVersion = paasHostVersion
 
+	logrus.Debugf("Profile: %s", profile)
 	logrus.Debugf("Accounts Host: %s", accountsHost)
 	logrus.Debugf("Auth Host: %s", authHost)
 	logrus.Debugf("Paas Host: %s", paasHost)
@@ -134,17 +172,164 @@ func StoreEnvironments(envs *[]models.Environment, settings *models.Settings) {
 	}
 }
 
-func migrateSettings(file *os.File, oldFormat, newFormat string) (models.Settings, error) {
-	if oldFormat == "v1" {
-		return migrateFromV1(file)
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func nonDefault(value, defaultValue string) string {
+	if value == defaultValue {
+		return ""
+	}
+	return value
+}
+
+// lockSettings takes the lock that serializes access to the settings file
+// between datica commands running in parallel. The returned function releases
+// it.
+func lockSettings() (func(), error) {
+	deadline := time.Now().Add(settingsLockTimeout)
+	for {
+		unlock, ok, err := tryLockFile(SettingsFile + ".lock")
+		if err != nil {
+			return nil, fmt.Errorf("Could not lock the settings file %s: %s", SettingsFile, err)
+		}
+		if ok {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out after %s waiting for another datica command to finish using the settings file %s", settingsLockTimeout, SettingsFile)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func backupSettingsPath() string {
+	return SettingsFile + ".bak"
+}
+
+// readSettingsFile reads the settings file and migrates older formats to the
+// current one in memory. A missing file results in empty settings. A corrupt
+// file is replaced by the backup taken before the last successful save. The
+// caller must hold the settings lock.
+func readSettingsFile() (*models.SettingsV3, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(filepath.Join(home, OldSettingsFile)); err == nil {
+		logrus.Debugln("Migrating settings file from .catalyze to .datica")
+		err = os.Rename(filepath.Join(home, OldSettingsFile), SettingsFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error encountered migrating the settings file from .catalyze to .datica: %s. To fix this, please run \"mv %s %s\".", err, filepath.Join(home, OldSettingsFile), SettingsFile)
+		}
+	}
+
+	b, err := ioutil.ReadFile(SettingsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		enforcePermissions(SettingsFile)
+	}
+	settingsFile, err := parseSettings(b)
+	_, backupErr := os.Stat(backupSettingsPath())
+	if err == nil && len(bytes.TrimSpace(b)) == 0 && backupErr == nil {
+		// a truncated file looks like a new one but a backup shows otherwise
+		err = errors.New("the file is empty")
+	}
+	if err != nil {
+		settingsFile, err = restoreSettingsBackup(b, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if settingsFile.DefaultProfile == "" {
+		settingsFile.DefaultProfile = DefaultProfile
+	}
+	if settingsFile.Profiles == nil {
+		settingsFile.Profiles = map[string]models.ProfileV3{}
+	}
+	return &settingsFile, nil
+}
+
+// restoreSettingsBackup replaces a corrupt settings file with its backup. The
+// corrupt contents are kept next to it for troubleshooting.
+func restoreSettingsBackup(corrupt []byte, cause error) (models.SettingsV3, error) {
+	backup, err := ioutil.ReadFile(backupSettingsPath())
+	if err == nil && len(bytes.TrimSpace(backup)) == 0 {
+		err = errors.New("the backup is empty")
+	}
+	var settingsFile models.SettingsV3
+	if err == nil {
+		settingsFile, err = parseSettings(backup)
+	}
+	if err != nil {
+		return models.SettingsV3{}, fmt.Errorf("The settings file %s is corrupt (%s) and could not be restored from the backup %s (%s). Please fix or remove the settings file and sign in again", SettingsFile, cause, backupSettingsPath(), err)
+	}
+	corruptPath := SettingsFile + ".corrupt"
+	if err = ioutil.WriteFile(corruptPath, corrupt, 0600); err != nil {
+		logrus.Debugf("Could not keep the corrupt settings file: %s", err)
+	}
+	if err = writeFileAtomic(SettingsFile, backup, 0600); err != nil {
+		return models.SettingsV3{}, err
+	}
+	logrus.Warnf("The settings file %s was corrupt (%s) and has been restored from the backup %s. The corrupt file was saved to %s", SettingsFile, cause, backupSettingsPath(), corruptPath)
+	return settingsFile, nil
+}
+
+// parseSettings decodes the contents of a settings file of any format into
+// the current format. Empty contents are treated as a new settings file.
+func parseSettings(b []byte) (models.SettingsV3, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return migrateFromV2(models.Settings{}), nil
+	}
+	var format struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(b, &format); err != nil {
+		return models.SettingsV3{}, err
+	}
+	if format.Format == "" {
+		format.Format = settingsFormatV1
+	}
+	return migrateSettings(b, format.Format, currentFormat)
+}
+
+func migrateSettings(b []byte, oldFormat, newFormat string) (models.SettingsV3, error) {
+	switch oldFormat {
+	case settingsFormatV1:
+		settings, err := migrateFromV1(b)
+		if err != nil {
+			return models.SettingsV3{}, err
+		}
+		return migrateFromV2(settings), nil
+	case settingsFormatV2:
+		var settings models.Settings
+		if err := json.Unmarshal(b, &settings); err != nil {
+			return models.SettingsV3{}, err
+		}
+		return migrateFromV2(settings), nil
+	case settingsFormatV3:
+		var settingsFile models.SettingsV3
+		if err := json.Unmarshal(b, &settingsFile); err != nil {
+			return models.SettingsV3{}, err
+		}
+		return settingsFile, nil
 	}
-	return models.Settings{}, fmt.Errorf("Invalid or corrupt settings file. Please fix the %s file in your home directory or contact Datica support", SettingsFile)
+	return models.SettingsV3{}, fmt.Errorf("unknown settings format \"%s\"", oldFormat)
 }
 
-func migrateFromV1(file *os.File) (models.Settings, error) {
-	logrus.Debugf("Migrating settings from %s to %s", settingsFormatV1, currentFormat)
+func migrateFromV1(b []byte) (models.Settings, error) {
+	logrus.Debugf("Migrating settings from %s to %s", settingsFormatV1, settingsFormatV2)
 	var oldSettings models.SettingsV1
-	json.NewDecoder(file).Decode(&oldSettings)
+	if err := json.Unmarshal(b, &oldSettings); err != nil {
+		return models.Settings{}, err
+	}
 	newSettings := models.Settings{
 		PrivateKeyPath: oldSettings.PrivateKeyPath,
 		SessionToken:   oldSettings.SessionToken,
@@ -152,7 +337,7 @@ func migrateFromV1(file *os.File) (models.Settings, error) {
 		Environments:   map[string]models.AssociatedEnvV2{},
 		Pods:           oldSettings.Pods,
 		PodCheck:       oldSettings.PodCheck,
-		Format:         currentFormat,
+		Format:         settingsFormatV2,
 	}
 	for _, env := range oldSettings.Environments {
 		newSettings.Environments[env.EnvironmentID] = models.AssociatedEnvV2{
@@ -165,10 +350,127 @@ func migrateFromV1(file *os.File) (models.Settings, error) {
 	return newSettings, nil
 }
 
-// SaveSettings persists the settings to disk
+// migrateFromV2 moves the single set of v2 settings into the default
+// profile. Hosts are left empty so they keep coming from the env variables or
+// the production defaults.
+func migrateFromV2(oldSettings models.Settings) models.SettingsV3 {
+	logrus.Debugf("Migrating settings from %s to %s", settingsFormatV2, settingsFormatV3)
+	return models.SettingsV3{
+		Format:         settingsFormatV3,
+		DefaultProfile: DefaultProfile,
+		Profiles: map[string]models.ProfileV3{
+			DefaultProfile: models.ProfileV3{
+				PrivateKeyPath: oldSettings.PrivateKeyPath,
+				SessionToken:   oldSettings.SessionToken,
+				UsersID:        oldSettings.UsersID,
+				Environments:   oldSettings.Environments,
+				Pods:           oldSettings.Pods,
+				PodCheck:       oldSettings.PodCheck,
+			},
+		},
+	}
+}
+
+// profileNames returns the sorted names of the profiles in the settings file.
+func profileNames(settingsFile *models.SettingsV3) []string {
+	var names []string
+	for name := range settingsFile.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SaveSettings persists the settings of the current profile to disk. Other
+// profiles in the settings file are left untouched. A profile saved for the
+// first time keeps any hosts that differ from the production Datica hosts,
+// such as those given through the ACCOUNTS_HOST, AUTH_HOST, and PAAS_HOST env
+// variables.
 func SaveSettings(settings *models.Settings) error {
-	b, _ := json.Marshal(&settings)
-	return ioutil.WriteFile(SettingsFile, b, 0644)
+	unlock, err := lockSettings()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	settingsFile, err := readSettingsFile()
+	if err != nil {
+		return err
+	}
+	name := settings.Profile
+	if name == "" {
+		name = settingsFile.DefaultProfile
+	}
+	p, ok := settingsFile.Profiles[name]
+	if !ok {
+		p.AccountsHost = nonDefault(settings.AccountsHost, AccountsHost)
+		p.AuthHost = nonDefault(settings.AuthHost, AuthHost)
+		p.PaasHost = nonDefault(settings.PaasHost, PaasHost)
+	}
+	p.Email = settings.Email
+	p.PrivateKeyPath = settings.PrivateKeyPath
+	if err = saveSession(name, &p, settings); err != nil {
+		return err
+	}
+	p.Environments = settings.Environments
+	p.Pods = settings.Pods
+	p.PodCheck = settings.PodCheck
+	settingsFile.Profiles[name] = p
+	settingsFile.Format = currentFormat
+	b, err := json.Marshal(settingsFile)
+	if err != nil {
+		return err
+	}
+	// keep the last good file so a corrupt one can be restored
+	if current, err := ioutil.ReadFile(SettingsFile); err == nil && len(bytes.TrimSpace(current)) > 0 {
+		if _, err = parseSettings(current); err == nil {
+			if err = writeFileAtomic(backupSettingsPath(), current, 0600); err != nil {
+				logrus.Debugf("Could not back up the settings file: %s", err)
+			}
+		}
+	}
+	return writeFileAtomic(SettingsFile, b, 0600)
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it over
+// path so readers never see a partially written file.
+func writeFileAtomic(path string, b []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// enforcePermissions makes the settings file readable only by its owner since
+// it holds session tokens.
+func enforcePermissions(path string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0077 == 0 {
+		return
+	}
+	logrus.Debugf("Restricting the permissions of %s to 0600", path)
+	if err = os.Chmod(path, 0600); err != nil {
+		logrus.Debugf("Could not restrict the permissions of %s: %s", path, err)
+	}
 }
 
 // SetGivenEnv takes the given env name and finds it in the env list
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daticahealth/cli/models"
+)
+
+// useSettingsFile points the settings file at a temporary file with the given
+// contents and returns a function that restores it.
+func useSettingsFile(t *testing.T, contents string) func() {
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSettingsFile := SettingsFile
+	SettingsFile = filepath.Join(dir, ".datica")
+	if err = ioutil.WriteFile(SettingsFile, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		SettingsFile = oldSettingsFile
+		os.RemoveAll(dir)
+	}
+}
+
+var migrateTests = []struct {
+	contents string
+	token    string
+	envName  string
+}{
+	{``, "", ""},
+	{`{"token":"v1token","user_id":"1","environments":{"alias":{"environmentId":"env1","name":"prod"}}}`, "v1token", "prod"},
+	{`{"format":"v2","token":"v2token","user_id":"1","environments":{"env1":{"environmentId":"env1","name":"prod"}}}`, "v2token", "prod"},
+	{`{"format":"v3","default_profile":"work","profiles":{"work":{"token":"v3token","environments":{"env1":{"environmentId":"env1","name":"prod"}}}}}`, "v3token", "prod"},
+}
+
+func TestMigrateSettings(t *testing.T) {
+	for _, data := range migrateTests {
+		t.Logf("Data: %+v", data)
+		restore := useSettingsFile(t, data.contents)
+		settings, err := FileSettingsRetriever{}.GetSettings("", "", "", "", "", "", "", "", "", "")
+		restore()
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+			continue
+		}
+		if settings.SessionToken != data.token {
+			t.Errorf("Expected token %s but got %s", data.token, settings.SessionToken)
+		}
+		if data.envName != "" && settings.Environments["env1"].Name != data.envName {
+			t.Errorf("Expected environment %s but got %+v", data.envName, settings.Environments)
+		}
+		if settings.Format != settingsFormatV3 {
+			t.Errorf("Expected format %s but got %s", settingsFormatV3, settings.Format)
+		}
+	}
+}
+
+func TestMigrateSettingsCorrupt(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v9"}`)()
+	if _, err := (FileSettingsRetriever{}).GetSettings("", "", "", "", "", "", "", "", "", ""); err == nil {
+		t.Errorf("Expected an error for an unknown settings format")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"prodtoken","user_id":"1"}`)()
+	r := FileSettingsRetriever{}
+
+	staging, err := r.GetSettings("staging", "", "", "", "", "", "https://paas.staging.example.com", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staging.SessionToken != "" {
+		t.Errorf("Expected a new profile to have no session but got %s", staging.SessionToken)
+	}
+	staging.SessionToken = "stagingtoken"
+	staging.Email = "contractor@example.com"
+	if err = SaveSettings(staging); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default profile keeps the migrated v2 session and the default hosts
+	settings, err := r.GetSettings("", "", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Profile != DefaultProfile || settings.SessionToken != "prodtoken" || settings.PaasHost != PaasHost {
+		t.Errorf("Unexpected default profile settings %+v", settings)
+	}
+
+	// the staging profile remembers its session, email, and host
+	staging, err = r.GetSettings("staging", "", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := models.Settings{SessionToken: "stagingtoken", Email: "contractor@example.com", PaasHost: "https://paas.staging.example.com"}
+	if staging.SessionToken != expected.SessionToken || staging.Email != expected.Email || staging.PaasHost != expected.PaasHost {
+		t.Errorf("Expected %+v but got %+v", expected, staging)
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"prodtoken","user_id":"1"}`)()
+	r := FileSettingsRetriever{}
+	_, err := r.GetSettings("stagign", "", "", "", "", "", "", "", "", "")
+	if err == nil || !strings.Contains(err.Error(), `The profile "stagign" does not exist. The existing profiles are default.`) {
+		t.Errorf("Expected an error for the unknown profile but got %v", err)
+	}
+	if _, err = r.GetSettings("staging", "", "", "", "", "", "", "", "contractor@example.com", ""); err != nil {
+		t.Errorf("Expected signing in to create the profile but got %s", err)
+	}
+}
+
+func TestSaveSettingsPermissionsAndBackup(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"first"}`)()
+	os.Chmod(SettingsFile, 0644)
+	settings, err := FileSettingsRetriever{}.GetSettings("", "", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.SessionToken = "second"
+	if err = SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(SettingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 but got %s", info.Mode().Perm())
+	}
+	backup, err := ioutil.ReadFile(backupSettingsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != `{"format":"v2","token":"first"}` {
+		t.Errorf("Expected the previous file to be backed up but got %s", backup)
+	}
+}
+
+func TestCorruptSettingsRestored(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v3","profiles":{"default":{"token":"good"}}}`)()
+	r := FileSettingsRetriever{}
+	settings, err := r.GetSettings("", "", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, corrupt := range []string{`{"format":"v3","profiles":{"defa`, ``} {
+		ioutil.WriteFile(SettingsFile, []byte(corrupt), 0600)
+		settings, err = r.GetSettings("", "", "", "", "", "", "", "", "", "")
+		if err != nil {
+			t.Fatalf("Expected the backup to be restored but got %s", err)
+		}
+		if settings.SessionToken != "good" {
+			t.Errorf("Expected the token from the backup but got %s", settings.SessionToken)
+		}
+	}
+
+	os.Remove(backupSettingsPath())
+	ioutil.WriteFile(SettingsFile, []byte(`not json`), 0600)
+	if _, err = r.GetSettings("", "", "", "", "", "", "", "", "", ""); err == nil {
+		t.Errorf("Expected an error for a corrupt settings file without a backup")
+	}
+}
+
+func TestConcurrentSaveSettings(t *testing.T) {
+	defer useSettingsFile(t, ``)()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			profile := fmt.Sprintf("profile%d", i)
+			settings, err := FileSettingsRetriever{}.GetSettings(profile, "", "", "", "", "", "", "", profile+"@example.com", "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			settings.SessionToken = profile
+			if err = SaveSettings(settings); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	unlock, err := lockSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settingsFile, err := readSettingsFile()
+	unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		profile := fmt.Sprintf("profile%d", i)
+		if settingsFile.Profiles[profile].SessionToken != profile {
+			t.Errorf("Expected profile %s to be saved but got %+v", profile, settingsFile.Profiles[profile])
+		}
+	}
+}
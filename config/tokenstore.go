@@ -0,0 +1,404 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/credentials"
+	"github.com/daticahealth/cli/lib/crypto"
+	"github.com/daticahealth/cli/models"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// The places a profile's session can be kept
+const (
+	// TokenStoreFile keeps the session in the settings file
+	TokenStoreFile = "file"
+	// TokenStoreVault keeps the session in a local file encrypted with a
+	// passphrase or key file
+	TokenStoreVault = "vault"
+	// TokenStoreHelper hands the session to an external credential helper
+	TokenStoreHelper = "helper"
+)
+
+const (
+	vaultHeader     = "DATICA-VAULT-V1"
+	vaultIterations = 100000
+	vaultSaltSize   = 16
+)
+
+// ValidateTokenStore checks that the token store is supported and has what
+// it needs to run.
+func ValidateTokenStore(store, helper string) error {
+	switch store {
+	case "", TokenStoreFile, TokenStoreVault:
+		return nil
+	case TokenStoreHelper:
+		if strings.TrimSpace(helper) == "" {
+			return fmt.Errorf("The helper token store requires a credential helper. Set one with --credential-helper or %s", CredentialHelperEnvVar)
+		}
+		return nil
+	}
+	return fmt.Errorf("Invalid token store \"%s\". Use one of %s, %s, or %s", store, TokenStoreFile, TokenStoreVault, TokenStoreHelper)
+}
+
+// storedSession is what a token store keeps for a profile
+type storedSession struct {
+	Token   string `json:"token"`
+	UsersID string `json:"user_id"`
+}
+
+type tokenStore interface {
+	Get(profile, ref string) (storedSession, error)
+	Store(profile, ref string, session storedSession) error
+	Erase(profile, ref string) error
+}
+
+var (
+	storesMutex sync.Mutex
+	stores      = map[string]tokenStore{}
+	// loadedSessions remembers the session loaded for each profile so it is
+	// only written back to its store when it changes
+	loadedSessions = map[string]storedSession{}
+	// forgottenSessions holds the profiles whose unreadable session was
+	// cleared and should no longer be referenced
+	forgottenSessions = map[string]bool{}
+)
+
+// openTokenStore returns the store with the given name. Stores are reused for
+// the life of the process so a vault passphrase is only asked for once.
+func openTokenStore(store, helper string) (tokenStore, error) {
+	storesMutex.Lock()
+	defer storesMutex.Unlock()
+	key := store + ":" + helper
+	if s, ok := stores[key]; ok {
+		return s, nil
+	}
+	var s tokenStore
+	switch store {
+	case TokenStoreVault:
+		s = &vaultStore{path: vaultPath()}
+	case TokenStoreHelper:
+		s = &helperStore{helper: &credentials.Helper{Program: helper}}
+	default:
+		return nil, fmt.Errorf("Invalid token store \"%s\"", store)
+	}
+	stores[key] = s
+	return s, nil
+}
+
+// loadSession fills in the session of the given profile from its token store.
+// Profiles that keep their session in the settings file are left untouched.
+func loadSession(name string, p models.ProfileV3, settings *models.Settings) error {
+	settings.TokenStore = p.TokenStore
+	settings.CredentialHelper = p.CredentialHelper
+	if p.TokenStore == "" || p.TokenRef == "" {
+		return nil
+	}
+	s, err := openTokenStore(p.TokenStore, p.CredentialHelper)
+	if err != nil {
+		return err
+	}
+	session, err := s.Get(name, p.TokenRef)
+	if err != nil {
+		return err
+	}
+	settings.SessionToken = session.Token
+	settings.UsersID = session.UsersID
+	storesMutex.Lock()
+	loadedSessions[name] = session
+	storesMutex.Unlock()
+	return nil
+}
+
+// ClearSession signs the settings out of their session. A session that could
+// not be loaded from its token store is dropped from the profile without
+// reading the store again.
+func ClearSession(settings *models.Settings) {
+	settings.SessionToken = ""
+	settings.UsersID = ""
+	if settings.SessionError != nil {
+		storesMutex.Lock()
+		forgottenSessions[settings.Profile] = true
+		storesMutex.Unlock()
+	}
+}
+
+// saveSession moves the session of the settings into the token store they
+// ask for and leaves only a reference to it in the profile. A session that
+// was signed out of, or that moved to another store, is erased from the
+// store it was in.
+func saveSession(name string, p *models.ProfileV3, settings *models.Settings) error {
+	if settings.SessionError != nil {
+		storesMutex.Lock()
+		forgotten := forgottenSessions[name]
+		storesMutex.Unlock()
+		if !forgotten {
+			// keep the session for when its store can be read again
+			return nil
+		}
+		// erasing it would mean unlocking the store again
+		logrus.Warnf("The session could not be erased from the %s token store, only the reference to it was removed", p.TokenStore)
+		p.TokenRef = ""
+	}
+	session := storedSession{Token: settings.SessionToken, UsersID: settings.UsersID}
+	store := settings.TokenStore
+	if store == TokenStoreFile {
+		store = ""
+	}
+	if p.TokenStore != "" && p.TokenRef != "" && (p.TokenStore != store || p.CredentialHelper != settings.CredentialHelper || session.Token == "") {
+		if s, err := openTokenStore(p.TokenStore, p.CredentialHelper); err == nil {
+			if err = s.Erase(name, p.TokenRef); err != nil {
+				logrus.Warnf("Could not erase the session from the %s token store: %s", p.TokenStore, err)
+			}
+		}
+		p.TokenRef = ""
+	}
+	p.TokenStore = store
+	p.CredentialHelper = settings.CredentialHelper
+	if store == "" {
+		p.SessionToken = session.Token
+		p.UsersID = session.UsersID
+		return nil
+	}
+	p.SessionToken = ""
+	p.UsersID = ""
+	if session.Token == "" {
+		return nil
+	}
+	storesMutex.Lock()
+	loaded, ok := loadedSessions[name]
+	storesMutex.Unlock()
+	if p.TokenRef != "" && ok && loaded == session {
+		return nil
+	}
+	if p.TokenRef == "" {
+		ref := make([]byte, 16)
+		if _, err := rand.Read(ref); err != nil {
+			return err
+		}
+		p.TokenRef = hex.EncodeToString(ref)
+	}
+	s, err := openTokenStore(store, settings.CredentialHelper)
+	if err != nil {
+		return err
+	}
+	if err = s.Store(name, p.TokenRef, session); err != nil {
+		return err
+	}
+	storesMutex.Lock()
+	loadedSessions[name] = session
+	storesMutex.Unlock()
+	return nil
+}
+
+// helperStore keeps sessions with an external credential helper. The helper
+// is called with the get-session, store-session, and erase-session actions
+// and a request holding the profile name, the reference, and for
+// store-session the token and user ID.
+type helperStore struct {
+	helper *credentials.Helper
+}
+
+type helperSessionRequest struct {
+	Profile string `json:"profile"`
+	Ref     string `json:"ref"`
+	Token   string `json:"token,omitempty"`
+	UsersID string `json:"user_id,omitempty"`
+}
+
+func (h *helperStore) Get(profile, ref string) (storedSession, error) {
+	var session storedSession
+	err := h.helper.Run("get-session", helperSessionRequest{Profile: profile, Ref: ref}, &session)
+	return session, err
+}
+
+func (h *helperStore) Store(profile, ref string, session storedSession) error {
+	return h.helper.Run("store-session", helperSessionRequest{Profile: profile, Ref: ref, Token: session.Token, UsersID: session.UsersID}, nil)
+}
+
+func (h *helperStore) Erase(profile, ref string) error {
+	return h.helper.Run("erase-session", helperSessionRequest{Profile: profile, Ref: ref}, nil)
+}
+
+func vaultPath() string {
+	path := os.Getenv(VaultFileEnvVar)
+	if len(path) == 0 {
+		home, err := homedir.Dir()
+		if err != nil {
+			panic(err)
+		}
+		path = filepath.Join(home, ".datica-vault")
+	}
+	return path
+}
+
+// vaultStore keeps sessions in a local file encrypted with AES-GCM. The key is
+// read from the file named by DATICA_VAULT_KEY_FILE or derived from a
+// passphrase with PBKDF2. The vault file is made of a header line, the hex
+// encoded salt, and the base64 encoded encrypted JSON of all sessions keyed
+// by their reference.
+type vaultStore struct {
+	path string
+	key  []byte
+	salt []byte
+}
+
+func (v *vaultStore) Get(profile, ref string) (storedSession, error) {
+	sessions, err := v.read()
+	if err != nil {
+		return storedSession{}, err
+	}
+	return sessions[ref], nil
+}
+
+func (v *vaultStore) Store(profile, ref string, session storedSession) error {
+	sessions, err := v.read()
+	if err != nil {
+		return err
+	}
+	sessions[ref] = session
+	return v.write(sessions)
+}
+
+func (v *vaultStore) Erase(profile, ref string) error {
+	sessions, err := v.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[ref]; !ok {
+		return nil
+	}
+	delete(sessions, ref)
+	return v.write(sessions)
+}
+
+func (v *vaultStore) read() (map[string]storedSession, error) {
+	sessions := map[string]storedSession{}
+	b, err := ioutil.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		return sessions, nil
+	} else if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(b), "\n", 3)
+	if len(parts) != 3 || parts[0] != vaultHeader {
+		return nil, fmt.Errorf("The credential vault %s is corrupt", v.path)
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("The credential vault %s is corrupt", v.path)
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return nil, fmt.Errorf("The credential vault %s is corrupt", v.path)
+	}
+	key, err := v.unlock(salt, false)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := crypto.New().DecryptBytes(encrypted, key)
+	if err != nil {
+		v.key = nil
+		return nil, fmt.Errorf("Could not unlock the credential vault %s. The passphrase or key is incorrect", v.path)
+	}
+	if err = json.Unmarshal(plaintext, &sessions); err != nil {
+		return nil, fmt.Errorf("The credential vault %s is corrupt", v.path)
+	}
+	return sessions, nil
+}
+
+func (v *vaultStore) write(sessions map[string]storedSession) error {
+	salt := v.salt
+	if salt == nil {
+		salt = make([]byte, vaultSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+	key, err := v.unlock(salt, true)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+	encrypted, err := crypto.New().EncryptBytes(plaintext, key)
+	if err != nil {
+		return err
+	}
+	b := fmt.Sprintf("%s\n%s\n%s\n", vaultHeader, hex.EncodeToString(salt), base64.StdEncoding.EncodeToString(encrypted))
+	return writeFileAtomic(v.path, []byte(b), 0600)
+}
+
+// unlock returns the vault key for the given salt, asking for the passphrase
+// if there is no key file. A new vault asks for the passphrase twice.
+func (v *vaultStore) unlock(salt []byte, create bool) ([]byte, error) {
+	if v.key != nil && bytes.Equal(v.salt, salt) {
+		return v.key, nil
+	}
+	if keyFile := os.Getenv(VaultKeyFileEnvVar); keyFile != "" {
+		b, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read the vault key file %s: %s", keyFile, err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil || len(key) != crypto.KeySize {
+			return nil, fmt.Errorf("The vault key file %s must contain a %d byte hex encoded key", keyFile, crypto.KeySize)
+		}
+		v.key, v.salt = key, salt
+		return key, nil
+	}
+	passphrase := os.Getenv(VaultPassphraseEnvVar)
+	if passphrase == "" {
+		var err error
+		if passphrase, err = promptVaultPassphrase(v.path, create && v.salt == nil); err != nil {
+			return nil, err
+		}
+	}
+	v.key = pbkdf2.Key([]byte(passphrase), salt, vaultIterations, crypto.KeySize, sha256.New)
+	v.salt = salt
+	return v.key, nil
+}
+
+func promptVaultPassphrase(path string, confirm bool) (string, error) {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("The credential vault %s needs a passphrase. Set %s or %s when not running in a terminal", path, VaultPassphraseEnvVar, VaultKeyFileEnvVar)
+	}
+	fmt.Fprint(os.Stderr, "Credential vault passphrase: ")
+	b, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr, "")
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", errors.New("The credential vault passphrase cannot be empty")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm the passphrase: ")
+		again, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr, "")
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(b, again) {
+			return "", errors.New("The passphrases do not match")
+		}
+	}
+	return string(b), nil
+}
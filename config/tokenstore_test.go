@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/daticahealth/cli/models"
+)
+
+// forgetSessions drops the cached stores and sessions the way a new CLI
+// process starts out.
+func forgetSessions() {
+	stores = map[string]tokenStore{}
+	loadedSessions = map[string]storedSession{}
+	forgottenSessions = map[string]bool{}
+}
+
+func getDefaultSettings(t *testing.T) *models.Settings {
+	settings, err := FileSettingsRetriever{}.GetSettings("", "", "", "", "", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestVaultTokenStore(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"secret-token","user_id":"user1"}`)()
+	defer forgetSessions()
+	vault := filepath.Join(filepath.Dir(SettingsFile), "vault")
+	os.Setenv(VaultFileEnvVar, vault)
+	os.Setenv(VaultPassphraseEnvVar, "correct horse")
+	defer os.Unsetenv(VaultFileEnvVar)
+	defer os.Unsetenv(VaultPassphraseEnvVar)
+
+	settings := getDefaultSettings(t)
+	settings.TokenStore = TokenStoreVault
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{SettingsFile, vault} {
+		b, _ := ioutil.ReadFile(path)
+		if strings.Contains(string(b), "secret-token") || strings.Contains(string(b), "user1") {
+			t.Errorf("Expected %s to not contain the session in plaintext: %s", path, b)
+		}
+	}
+
+	forgetSessions()
+	settings = getDefaultSettings(t)
+	if settings.SessionToken != "secret-token" || settings.UsersID != "user1" || settings.TokenStore != TokenStoreVault {
+		t.Errorf("Expected the session to be loaded from the vault but got %+v", settings)
+	}
+
+	forgetSessions()
+	os.Setenv(VaultPassphraseEnvVar, "wrong")
+	locked := getDefaultSettings(t)
+	if locked.SessionError == nil || locked.SessionToken != "" {
+		t.Errorf("Expected an error unlocking the vault with the wrong passphrase but got %+v", locked)
+	}
+	// other commands leave the session alone
+	if err := SaveSettings(locked); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(VaultPassphraseEnvVar, "correct horse")
+	forgetSessions()
+	if settings = getDefaultSettings(t); settings.SessionToken != "secret-token" {
+		t.Errorf("Expected the session to be kept but got %+v", settings)
+	}
+
+	// clear --session wipes the vault entry
+	ClearSession(settings)
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := (&vaultStore{path: vault}).read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected the vault to be empty but got %+v", sessions)
+	}
+}
+
+func TestClearLockedVault(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"secret-token","user_id":"user1"}`)()
+	defer forgetSessions()
+	vault := filepath.Join(filepath.Dir(SettingsFile), "vault")
+	os.Setenv(VaultFileEnvVar, vault)
+	os.Setenv(VaultPassphraseEnvVar, "correct horse")
+	defer os.Unsetenv(VaultFileEnvVar)
+	defer os.Unsetenv(VaultPassphraseEnvVar)
+
+	settings := getDefaultSettings(t)
+	settings.TokenStore = TokenStoreVault
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	forgetSessions()
+	os.Setenv(VaultPassphraseEnvVar, "forgotten")
+	settings = getDefaultSettings(t)
+	ClearSession(settings)
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	forgetSessions()
+	settings = getDefaultSettings(t)
+	if settings.SessionError != nil || settings.SessionToken != "" {
+		t.Errorf("Expected the session to be cleared without unlocking the vault but got %+v", settings)
+	}
+}
+
+func TestVaultKeyFile(t *testing.T) {
+	defer useSettingsFile(t, `{"format":"v2","token":"secret-token"}`)()
+	defer forgetSessions()
+	dir := filepath.Dir(SettingsFile)
+	keyFile := filepath.Join(dir, "vault.key")
+	ioutil.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)+"\n"), 0600)
+	os.Setenv(VaultFileEnvVar, filepath.Join(dir, "vault"))
+	os.Setenv(VaultKeyFileEnvVar, keyFile)
+	defer os.Unsetenv(VaultFileEnvVar)
+	defer os.Unsetenv(VaultKeyFileEnvVar)
+
+	settings := getDefaultSettings(t)
+	settings.TokenStore = TokenStoreVault
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	forgetSessions()
+	if settings = getDefaultSettings(t); settings.SessionToken != "secret-token" {
+		t.Errorf("Expected the session to be loaded from the vault but got %s", settings.SessionToken)
+	}
+}
+
+func TestHelperTokenStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test helper is a shell script")
+	}
+	defer useSettingsFile(t, `{"format":"v2","token":"secret-token","user_id":"user1"}`)()
+	defer forgetSessions()
+	dir := filepath.Dir(SettingsFile)
+	helper := filepath.Join(dir, "helper.sh")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+get-session) cat %[1]s/session 2>/dev/null || echo '{}' ;;
+store-session) cat > %[1]s/session ;;
+erase-session) rm %[1]s/session ;;
+esac
+`, dir)
+	ioutil.WriteFile(helper, []byte(script), 0700)
+
+	settings := getDefaultSettings(t)
+	settings.TokenStore = TokenStoreHelper
+	settings.CredentialHelper = helper
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := ioutil.ReadFile(filepath.Join(dir, "session"))
+	if !strings.Contains(string(stored), `"token":"secret-token"`) {
+		t.Errorf("Expected the helper to receive the session but got %s", stored)
+	}
+
+	forgetSessions()
+	settings = getDefaultSettings(t)
+	if settings.SessionToken != "secret-token" || settings.UsersID != "user1" {
+		t.Errorf("Expected the session to be loaded from the helper but got %+v", settings)
+	}
+
+	// moving back to the settings file erases the session from the helper
+	settings.TokenStore = TokenStoreFile
+	if err := SaveSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "session")); !os.IsNotExist(err) {
+		t.Errorf("Expected the helper to erase the session")
+	}
+	forgetSessions()
+	if settings = getDefaultSettings(t); settings.SessionToken != "secret-token" {
+		t.Errorf("Expected the session to be back in the settings file but got %s", settings.SessionToken)
+	}
+}
+
+func TestValidateTokenStore(t *testing.T) {
+	if err := ValidateTokenStore("keychain", ""); err == nil {
+		t.Errorf("Expected an error for an unknown token store")
+	}
+	if err := ValidateTokenStore(TokenStoreHelper, ""); err == nil {
+		t.Errorf("Expected an error for a helper token store without a helper")
+	}
+	if err := ValidateTokenStore(TokenStoreVault, ""); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
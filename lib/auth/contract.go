@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"github.com/daticahealth/cli/lib/credentials"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 )
@@ -19,7 +20,16 @@ type SAuth struct {
 	Prompts  prompts.IPrompts
 }
 
+// New returns an IAuth. When the settings name a credential helper, it is
+// asked for credentials and one-time passwords before the user is prompted.
 func New(settings *models.Settings, prompts prompts.IPrompts) IAuth {
+	if settings.CredentialHelper != "" {
+		prompts = &helperPrompts{
+			IPrompts: prompts,
+			settings: settings,
+			helper:   &credentials.Helper{Program: settings.CredentialHelper},
+		}
+	}
 	return &SAuth{
 		Settings: settings,
 		Prompts:  prompts,
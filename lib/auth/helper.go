@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/credentials"
+	"github.com/daticahealth/cli/lib/prompts"
+	"github.com/daticahealth/cli/models"
+)
+
+// credentialRequest is sent to the credential helper with the get-credentials
+// action. Need lists the values the helper should answer with: email and
+// password when signing in or otp for two-factor authentication.
+type credentialRequest struct {
+	Profile          string   `json:"profile"`
+	Host             string   `json:"host"`
+	Email            string   `json:"email,omitempty"`
+	MFAPreferredMode string   `json:"mfaPreferredMode,omitempty"`
+	Need             []string `json:"need"`
+}
+
+// credentialResponse is the answer of the credential helper. Values it leaves
+// empty are asked for interactively.
+type credentialResponse struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	OTP      string `json:"otp"`
+}
+
+// helperPrompts asks the credential helper for sign in credentials and one
+// time passwords before falling back to the interactive prompts. A helper
+// that fails is treated like one that answered with nothing.
+type helperPrompts struct {
+	prompts.IPrompts
+	settings *models.Settings
+	helper   *credentials.Helper
+}
+
+func (h *helperPrompts) request(email, mfaPreferredMode string, need ...string) (*credentialResponse, error) {
+	resp := &credentialResponse{}
+	err := h.helper.Run("get-credentials", credentialRequest{
+		Profile:          h.settings.Profile,
+		Host:             h.settings.AuthHost,
+		Email:            email,
+		MFAPreferredMode: mfaPreferredMode,
+		Need:             need,
+	}, resp)
+	return resp, err
+}
+
+// EmailPassword only asks the helper for the values that were not given
+// through the global options or env variables.
+func (h *helperPrompts) EmailPassword(existingEmail, existingPassword string) (string, string, error) {
+	var need []string
+	if existingEmail == "" {
+		need = append(need, "email")
+	}
+	if existingPassword == "" {
+		need = append(need, "password")
+	}
+	resp, err := h.request(existingEmail, "", need...)
+	if err != nil {
+		logrus.Debugf("Could not get the email and password from the credential helper: %s", err)
+	} else {
+		if existingEmail == "" {
+			existingEmail = resp.Email
+		}
+		if existingPassword == "" {
+			existingPassword = resp.Password
+		}
+	}
+	if existingEmail == "" || existingPassword == "" {
+		return h.IPrompts.EmailPassword(existingEmail, existingPassword)
+	}
+	return existingEmail, existingPassword, nil
+}
+
+// OTP asks the helper for a one-time password, for example generated from a
+// TOTP secret kept in a password manager.
+func (h *helperPrompts) OTP(preferredMode string) string {
+	resp, err := h.request(h.settings.Email, preferredMode, "otp")
+	if err != nil {
+		logrus.Debugf("Could not get a one-time password from the credential helper: %s", err)
+	} else if resp.OTP != "" {
+		return resp.OTP
+	}
+	return h.IPrompts.OTP(preferredMode)
+}
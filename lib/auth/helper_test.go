@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/credentials"
+	"github.com/daticahealth/cli/models"
+	"github.com/daticahealth/cli/test"
+)
+
+// failPrompts fails the test if the user would have been prompted
+type failPrompts struct {
+	test.FakePrompts
+	t *testing.T
+}
+
+func (f *failPrompts) EmailPassword(e, p string) (string, string, error) {
+	f.t.Errorf("Expected the credential helper to provide the email and password")
+	return e, p, nil
+}
+
+func (f *failPrompts) OTP(string) string {
+	f.t.Errorf("Expected the credential helper to provide the one-time password")
+	return ""
+}
+
+func TestSigninWithCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test helper is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldSettingsFile := config.SettingsFile
+	config.SettingsFile = filepath.Join(dir, ".datica")
+	defer func() { config.SettingsFile = oldSettingsFile }()
+
+	// the helper records each request and answers with an otp when asked for
+	// one or with an email and password otherwise
+	helper := filepath.Join(dir, "helper.sh")
+	script := fmt.Sprintf(`#!/bin/sh
+request=$(cat)
+echo "$1 $request" >> %[1]s/requests
+case "$request" in
+*'"otp"'*) echo '{"otp":"654321"}' ;;
+*) echo '{"email":"ci@example.com","password":"secret"}' ;;
+esac
+`, dir)
+	ioutil.WriteFile(helper, []byte(script), 0700)
+
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	mux.HandleFunc("/auth/verify", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		fmt.Fprint(w, `{"title":"Unauthorized","description":"Invalid session","code":401}`)
+	})
+	mux.HandleFunc("/auth/signin", func(w http.ResponseWriter, r *http.Request) {
+		var login models.Login
+		json.NewDecoder(r.Body).Decode(&login)
+		if login.Identifier != "ci@example.com" || login.Password != "secret" {
+			t.Errorf("Unexpected login %+v", login)
+		}
+		fmt.Fprint(w, `{"mfaID":"mfa1","mfaPreferredType":"authenticator"}`)
+	})
+	mux.HandleFunc("/auth/signin/mfa/mfa1", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OTP string `json:"otp"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		test.AssertEquals(t, "654321", body.OTP)
+		fmt.Fprint(w, `{"id":"user1","email":"ci@example.com","sessionToken":"session1"}`)
+	})
+
+	settings := test.GetSettings(baseURL.String())
+	settings.AuthHost = baseURL.String()
+	settings.PrivateKeyPath = ""
+	settings.Profile = "ci"
+	settings.CredentialHelper = helper
+	user, err := New(settings, &failPrompts{t: t}).Signin()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test.AssertEquals(t, "session1", user.SessionToken)
+
+	requests, _ := ioutil.ReadFile(filepath.Join(dir, "requests"))
+	expected := fmt.Sprintf(`get-credentials {"profile":"ci","host":"%[1]s","need":["email","password"]}
+get-credentials {"profile":"ci","host":"%[1]s","email":"ci@example.com","mfaPreferredMode":"authenticator","need":["otp"]}
+`, baseURL.String())
+	test.AssertEquals(t, expected, string(requests))
+}
+
+func TestFailingCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test helper is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	helper := filepath.Join(dir, "helper.sh")
+	ioutil.WriteFile(helper, []byte("#!/bin/sh\nexit 1\n"), 0700)
+
+	settings := test.GetSettings("")
+	settings.CredentialHelper = helper
+	h := &helperPrompts{IPrompts: &test.FakePrompts{}, settings: settings, helper: &credentials.Helper{Program: helper}}
+	email, password, err := h.EmailPassword("", "")
+	if err != nil {
+		t.Fatalf("Expected to fall back to the prompt but got %s", err)
+	}
+	test.AssertEquals(t, "email", email)
+	test.AssertEquals(t, "password", password)
+}
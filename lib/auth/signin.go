@@ -1,24 +1,23 @@
 package auth
 
 import (
-	"crypto"
-	"crypto/rand"
-	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
-	"errors"
 	"fmt"
-	"io/ioutil"
+
+	"golang.org/x/crypto/ssh"
 
 	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/sshkeys"
 	"github.com/daticahealth/cli/models"
 )
 
 // Signin signs in a user and returns the representative user model. If an
 // error occurs, nil is returned for the user and the error field is populated.
 func (a *SAuth) Signin() (*models.User, error) {
+	if a.Settings.SessionError != nil {
+		return nil, a.Settings.SessionError
+	}
 	// if we're already signed in with a valid session, don't sign in again
 	if user, err := a.Verify(); err == nil {
 		return user, nil
@@ -83,6 +82,7 @@ func (a *SAuth) signInWithCredentials() (*signinResponse, error) {
 			Identifier: email,
 			Password:   password,
 		}
+		a.Settings.Email = email
 	}
 
 	b, err := json.Marshal(login)
@@ -104,36 +104,18 @@ func (a *SAuth) signInWithKey() (*signinResponse, error) {
 		Signature string `json:"signature"`
 	}{}
 
-	bytes, err := ioutil.ReadFile(a.Settings.PrivateKeyPath)
-	if err != nil {
-		return nil, err
-	}
-	block, _ := pem.Decode(bytes)
-	if block == nil {
-		return nil, errors.New("Private key is not PEM-encoded")
-	}
-	bytes = block.Bytes
-	if x509.IsEncryptedPEMBlock(block) {
-		passphrase := a.Prompts.KeyPassphrase(a.Settings.PrivateKeyPath)
-		bytes, err = x509.DecryptPEMBlock(block, []byte(passphrase))
-		if err != nil {
-			return nil, err
-		}
-	}
-	privateKey, err := x509.ParsePKCS1PrivateKey(bytes)
-	if err != nil {
-		return nil, err
-	}
-	publicKey, err := ioutil.ReadFile(a.Settings.PrivateKeyPath + ".pub")
+	// the ssh-agent signs if it holds the key, otherwise the key file is read
+	signer, err := sshkeys.NewSigner(a.Settings.PrivateKeyPath, func() string {
+		return a.Prompts.KeyPassphrase(a.Settings.PrivateKeyPath)
+	})
 	if err != nil {
 		return nil, err
 	}
-	body.PublicKey = string(publicKey)
+	body.PublicKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
 
 	headers := a.Settings.HTTPManager.GetHeaders(a.Settings.SessionToken, a.Settings.Version, a.Settings.Pod, a.Settings.UsersID)
 	message := fmt.Sprintf("%s&%s", headers["X-Request-Nonce"][0], headers["X-Request-Timestamp"][0])
-	hashedMessage := sha256.Sum256([]byte(message))
-	signature, err := privateKey.Sign(rand.Reader, hashedMessage[:], crypto.SHA256)
+	signature, err := signer.Sign([]byte(message))
 	if err != nil {
 		return nil, err
 	}
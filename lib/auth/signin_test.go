@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/daticahealth/cli/lib/sshkeys"
+	"github.com/daticahealth/cli/test"
+)
+
+func TestSigninWithEd25519Key(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	mux.HandleFunc("/auth/verify", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		fmt.Fprint(w, `{"title":"Unauthorized","description":"Invalid session","code":401}`)
+	})
+	mux.HandleFunc("/auth/signin/key", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			PublicKey string `json:"publicKey"`
+			Signature string `json:"signature"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		key, err := sshkeys.ParsePublicKey([]byte(body.PublicKey))
+		if err != nil {
+			t.Fatalf("Unexpected public key %s: %s", body.PublicKey, err)
+		}
+		expected, _ := sshkeys.NewPublicKey(pub)
+		test.AssertEquals(t, string(ssh.MarshalAuthorizedKey(expected)), string(ssh.MarshalAuthorizedKey(key)))
+		sig, _ := base64.StdEncoding.DecodeString(body.Signature)
+		message := fmt.Sprintf("%s&%s", r.Header.Get("X-Request-Nonce"), r.Header.Get("X-Request-Timestamp"))
+		if !ed25519.Verify(pub, []byte(message), sig) {
+			t.Errorf("Invalid signature")
+		}
+		fmt.Fprint(w, `{"id":"user1","email":"ci@example.com","sessionToken":"session1"}`)
+	})
+
+	settings := test.GetSettings(baseURL.String())
+	settings.AuthHost = baseURL.String()
+	settings.PrivateKeyPath = keyPath
+	user, err := New(settings, &test.FakePrompts{}).Signin()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test.AssertEquals(t, "session1", user.SessionToken)
+}
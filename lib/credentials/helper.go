@@ -0,0 +1,53 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Helper runs an external credential helper program such as a wrapper around
+// a password manager or a CI secret store. The program is invoked with the
+// action as its last argument, receives a JSON request on stdin, and writes a
+// JSON response to stdout. Anything it writes to stderr is shown to the user
+// so helpers can prompt on the terminal. A non-zero exit status fails the
+// action.
+type Helper struct {
+	// Program is the helper executable followed by any arguments, separated
+	// by spaces
+	Program string
+}
+
+// Run invokes the helper with the given action. The request is sent as JSON
+// and, if response is not nil, the output of the helper is decoded into it.
+func (h *Helper) Run(action string, request, response interface{}) error {
+	args := strings.Fields(h.Program)
+	if len(args) == 0 {
+		return fmt.Errorf("No credential helper is configured")
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], append(args[1:], action)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("The credential helper \"%s\" failed to %s: %s", h.Program, action, err)
+	}
+	if response == nil {
+		return nil
+	}
+	if len(bytes.TrimSpace(output.Bytes())) == 0 {
+		return nil
+	}
+	if err = json.Unmarshal(output.Bytes(), response); err != nil {
+		return fmt.Errorf("The credential helper \"%s\" returned an invalid response to %s: %s", h.Program, action, err)
+	}
+	return nil
+}
@@ -8,9 +8,12 @@ import (
 
 // ICrypto
 type ICrypto interface {
+	DecryptBytes(encrypted, key []byte) ([]byte, error)
 	DecryptFile(encryptedFilePath, key, iv, outputFilePath string) error
+	EncryptBytes(plaintext, key []byte) ([]byte, error)
 	EncryptFile(plainFilePath string, key, iv []byte) (string, error)
 	NewEncryptReader(reader io.Reader, key, iv []byte) (*gcm.EncryptReader, error)
+	NewEncryptReaderAt(reader io.Reader, key, iv []byte, chunk int64) (*gcm.EncryptReader, error)
 	NewDecryptWriteCloser(writeCloser io.WriteCloser, key, iv string) (*gcm.DecryptWriteCloser, error)
 	Hex(src []byte, maxLen int) []byte
 	Unhex(src []byte, maxLen int) []byte
@@ -1,6 +1,9 @@
 package crypto
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/catalyzeio/gcm/gcm"
@@ -19,3 +22,35 @@ func (c *SCrypto) DecryptFile(encryptedFilePath, key, iv, outputFilePath string)
 func (c *SCrypto) NewDecryptWriteCloser(writeCloser io.WriteCloser, key, iv string) (*gcm.DecryptWriteCloser, error) {
 	return gcm.NewDecryptWriteCloser(writeCloser, c.Unhex([]byte(key), KeySize), c.Unhex([]byte(iv), IVSize), c.Unhex([]byte(gcm.AAD), AADSize))
 }
+
+// DecryptBytes decrypts data produced by EncryptBytes in memory. The passed in
+// key should *NOT* be base64 encoded or hex encoded.
+func (c *SCrypto) DecryptBytes(encrypted, key []byte) ([]byte, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("Invalid key length. Keys must be %d bytes", KeySize)
+	}
+	if len(encrypted) < IVSize {
+		return nil, errors.New("The encrypted data is too short")
+	}
+	plaintext := &bufferCloser{}
+	dwc, err := gcm.NewDecryptWriteCloser(plaintext, key, encrypted[:IVSize], c.Unhex([]byte(gcm.AAD), AADSize))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = dwc.Write(encrypted[IVSize:]); err == nil {
+		err = dwc.Close()
+	}
+	if err != nil {
+		return nil, errors.New("Failed to decrypt the data. The key is incorrect or the data is corrupt")
+	}
+	return plaintext.Bytes(), nil
+}
+
+// bufferCloser is a bytes.Buffer that satisfies io.WriteCloser
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
@@ -15,6 +15,12 @@ const (
 	// AADSize is the size in bytes of the Additional Authenticated Data for the
 	// GCM encryption
 	AADSize = 16
+	// ChunkSize is the size in bytes of each plaintext chunk that is sealed
+	// separately by the GCM encryption. This must match the gcm package.
+	ChunkSize = 1024 * 1024
+	// TagSize is the size in bytes of the authentication tag appended to each
+	// sealed chunk
+	TagSize = 16
 )
 
 // Hex encode bytes
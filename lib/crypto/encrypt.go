@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -44,3 +46,45 @@ func (c *SCrypto) NewEncryptReader(reader io.Reader, key, iv []byte) (*gcm.Encry
 	}
 	return gcm.NewEncryptReader(reader, key, iv, c.Unhex([]byte(gcm.AAD), AADSize))
 }
+
+// NewEncryptReaderAt takes in a Reader positioned at the start of the given
+// chunk of a plaintext file and wraps it in a type that will encrypt the
+// Reader as its read. Every chunk of ChunkSize bytes is sealed with its own
+// IV, so the output of each reader can be concatenated in order to produce
+// the exact same result as encrypting the whole file with NewEncryptReader.
+// The passed in key and iv should *NOT* be base64 encoded or hex encoded.
+func (c *SCrypto) NewEncryptReaderAt(reader io.Reader, key, iv []byte, chunk int64) (*gcm.EncryptReader, error) {
+	if len(iv) != IVSize {
+		return nil, fmt.Errorf("Invalid IV length. IVs must be %d bytes", IVSize)
+	}
+	chunkIV := make([]byte, len(iv))
+	copy(chunkIV, iv)
+	// add the chunk number to the IV as a big endian integer the same way the
+	// gcm package increments it after each chunk
+	carry := uint64(chunk)
+	for i := len(chunkIV) - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(chunkIV[i]) + carry&0xff
+		chunkIV[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	return c.NewEncryptReader(reader, key, chunkIV)
+}
+
+// EncryptBytes encrypts the given plaintext in memory with a random IV. The IV
+// is prepended to the returned ciphertext so that DecryptBytes only needs the
+// key. The passed in key should *NOT* be base64 encoded or hex encoded.
+func (c *SCrypto) EncryptBytes(plaintext, key []byte) ([]byte, error) {
+	iv := make([]byte, IVSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	encryptReader, err := c.NewEncryptReader(bytes.NewReader(plaintext), key, iv)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ioutil.ReadAll(encryptReader)
+	if err != nil {
+		return nil, err
+	}
+	return append(iv, ciphertext...), nil
+}
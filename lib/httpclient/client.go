@@ -2,48 +2,84 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	mathrand "math/rand"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/updater"
 	"github.com/daticahealth/cli/models"
 )
 
 const defaultRedirectLimit = 10
 
+// RetryPolicy controls the timeout of every request and how failed requests
+// are retried. Requests are retried after network errors and 5xx responses if
+// the method is idempotent, and after 429 responses for any method. The delay
+// between attempts grows exponentially from BaseDelay up to MaxDelay with
+// random jitter, unless the response asks for a specific delay through a
+// Retry-After header. A zero Timeout means requests never time out.
+type RetryPolicy struct {
+	Timeout    time.Duration
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by the CLI unless it is
+// configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	Timeout:    60 * time.Second,
+	MaxRetries: 4,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
 type TLSHTTPManager struct {
 	client *http.Client
+	policy RetryPolicy
+	sleep  func(time.Duration)
+	trace  io.Writer
 }
 
 // NewTLSHTTPManager constructs and returns a new instance of HTTPManager
-// with TLSv1.2 and redirect support.
-func NewTLSHTTPManager(skipVerify bool) models.HTTPManager {
-	var tr = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		},
-	}
-	if skipVerify {
-		tr.TLSClientConfig.InsecureSkipVerify = true
+// with TLSv1.2, redirect, and retry support.
+func NewTLSHTTPManager(skipVerify bool, policy RetryPolicy) (models.HTTPManager, error) {
+	return NewHTTPManager(&models.NetworkConfig{SkipVerify: skipVerify}, policy, nil)
+}
+
+// NewHTTPManager constructs an HTTPManager that sends requests through the
+// proxy and with the TLS settings of the given network configuration. If trace
+// is not nil, a redacted summary of every request and response is written to
+// it.
+func NewHTTPManager(nc *models.NetworkConfig, policy RetryPolicy, trace io.Writer) (models.HTTPManager, error) {
+	tr, err := network.Transport(nc)
+	if err != nil {
+		return nil, err
 	}
+	tr.ResponseHeaderTimeout = policy.Timeout
 	return &TLSHTTPManager{
 		client: &http.Client{
 			Transport:     tr,
 			CheckRedirect: redirectPolicyFunc,
 		},
-	}
+		policy: policy,
+		sleep:  time.Sleep,
+		trace:  trace,
+	}, nil
 }
 
 func redirectPolicyFunc(req *http.Request, via []*http.Request) error {
@@ -87,7 +123,7 @@ func (m *TLSHTTPManager) GetHeaders(sessionToken, version, pod, userID string) m
 // interface. ALWAYS PASS A POINTER INTO THIS METHOD. If you don't pass a struct
 // pointer your original object will be nil or an empty struct.
 func (m *TLSHTTPManager) ConvertResp(b []byte, statusCode int, s interface{}) error {
-	logrus.Debugf("%d resp: %s", statusCode, string(b))
+	logrus.Debugf("%d resp: %d bytes", statusCode, len(b))
 	if m.isError(statusCode) {
 		return m.convertError(b, statusCode)
 	}
@@ -136,14 +172,12 @@ func (m *TLSHTTPManager) convertError(b []byte, statusCode int) error {
 
 // Get performs a GET request
 func (m *TLSHTTPManager) Get(body []byte, url string, headers map[string][]string) ([]byte, int, error) {
-	reader := bytes.NewReader(body)
-	return m.makeRequest("GET", url, reader, headers)
+	return m.makeRequest("GET", url, body, headers)
 }
 
 // Post performs a POST request
 func (m *TLSHTTPManager) Post(body []byte, url string, headers map[string][]string) ([]byte, int, error) {
-	reader := bytes.NewReader(body)
-	return m.makeRequest("POST", url, reader, headers)
+	return m.makeRequest("POST", url, body, headers)
 }
 
 // PostFile uploads a file with a POST
@@ -156,59 +190,156 @@ func (m *TLSHTTPManager) PutFile(filepath string, url string, headers map[string
 	return m.uploadFile("PUT", filepath, url, headers)
 }
 
+// uploadFile sends a file as the body of a request. Files can be large, so the
+// upload itself is not bound by the policy's timeout, only the wait for a
+// response once the file has been sent is.
 func (m *TLSHTTPManager) uploadFile(method, filepath, url string, headers map[string][]string) ([]byte, int, error) {
 	logrus.Debugf("%s %s", method, url)
-	logrus.Debugf("%+v", headers)
-	logrus.Debugf("%s", filepath)
-	file, err := os.Open(filepath)
-	defer file.Close()
-	if err != nil {
-		return nil, 0, err
+	if info, err := os.Stat(filepath); err == nil {
+		m.traceRequest(method, url, headers, nil, info.Size())
 	}
-	info, _ := file.Stat()
-	req, _ := http.NewRequest(method, url, file)
-	req.ContentLength = info.Size()
-
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	return respBody, resp.StatusCode, nil
+	return m.do(method, url, false, func() (io.ReadCloser, int64, error) {
+		file, err := os.Open(filepath)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		return file, info.Size(), nil
+	}, headers)
 }
 
 // Put performs a PUT request
 func (m *TLSHTTPManager) Put(body []byte, url string, headers map[string][]string) ([]byte, int, error) {
-	reader := bytes.NewReader(body)
-	return m.makeRequest("PUT", url, reader, headers)
+	return m.makeRequest("PUT", url, body, headers)
 }
 
 // Delete performs a DELETE request
 func (m *TLSHTTPManager) Delete(body []byte, url string, headers map[string][]string) ([]byte, int, error) {
-	reader := bytes.NewReader(body)
-	return m.makeRequest("DELETE", url, reader, headers)
+	return m.makeRequest("DELETE", url, body, headers)
 }
 
 // MakeRequest is a generic HTTP runner that performs a request and returns
 // the result body as a byte array. It's up to the caller to transform them
 // into an object.
-func (m *TLSHTTPManager) makeRequest(method string, url string, body io.Reader, headers map[string][]string) ([]byte, int, error) {
+func (m *TLSHTTPManager) makeRequest(method string, url string, body []byte, headers map[string][]string) ([]byte, int, error) {
 	logrus.Debugf("%s %s", method, url)
-	logrus.Debugf("%+v", headers)
-	logrus.Debugf("%s", body)
-	req, _ := http.NewRequest(method, url, body)
+	m.traceRequest(method, url, headers, body, int64(len(body)))
+	return m.do(method, url, true, func() (io.ReadCloser, int64, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), int64(len(body)), nil
+	}, headers)
+}
+
+// do performs a request according to the retry policy. The body is opened
+// again for every attempt. If timeoutBody is false, the policy's timeout only
+// applies to waiting for the response headers through the transport and not
+// to sending the body.
+func (m *TLSHTTPManager) do(method, url string, timeoutBody bool, openBody func() (io.ReadCloser, int64, error), headers map[string][]string) ([]byte, int, error) {
+	for attempt := 0; ; attempt++ {
+		respBody, statusCode, retryAfter, err := m.attempt(method, url, timeoutBody, openBody, headers)
+		if statusCode == 412 {
+			updater.AutoUpdater.ForcedUpgrade()
+			return nil, 0, fmt.Errorf("A required update has been applied. Please re-run this command.")
+		}
+		if attempt >= m.policy.MaxRetries || !m.shouldRetry(method, statusCode, err) {
+			if err != nil {
+				return nil, 0, err
+			}
+			return respBody, statusCode, nil
+		}
+		delay := m.backoff(attempt, retryAfter)
+		if err != nil {
+			logrus.Debugf("%s %s failed, retrying in %s: %s", method, url, delay, err)
+		} else {
+			logrus.Debugf("%s %s returned %d, retrying in %s", method, url, statusCode, delay)
+		}
+		m.sleep(delay)
+	}
+}
+
+// attempt performs a single request. The returned retryAfter is the delay
+// requested through a Retry-After header, if any.
+func (m *TLSHTTPManager) attempt(method, url string, timeoutBody bool, openBody func() (io.ReadCloser, int64, error), headers map[string][]string) ([]byte, int, time.Duration, error) {
+	body, contentLength, err := openBody()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer body.Close()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 	req.Header = headers
+	req.ContentLength = contentLength
+	if contentLength == 0 {
+		req.Body = http.NoBody
+	}
+
+	if m.policy.Timeout > 0 && timeoutBody {
+		ctx, cancel := context.WithTimeout(context.Background(), m.policy.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
 
+	start := time.Now()
 	resp, err := m.client.Do(req)
 	if err != nil {
-		return nil, 0, err
+		m.traceResponse(method, url, 0, nil, time.Since(start), err)
+		return nil, 0, 0, err
 	}
 	defer resp.Body.Close()
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode == 412 {
-		updater.AutoUpdater.ForcedUpgrade()
-		return nil, 0, fmt.Errorf("A required update has been applied. Please re-run this command.")
+	respBody, err := ioutil.ReadAll(resp.Body)
+	m.traceResponse(method, url, resp.StatusCode, respBody, time.Since(start), err)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return respBody, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")), nil
+}
+
+// shouldRetry decides whether a failed attempt is worth retrying.
+func (m *TLSHTTPManager) shouldRetry(method string, statusCode int, err error) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	idempotent := method == "GET" || method == "HEAD" || method == "PUT" || method == "DELETE" || method == "OPTIONS"
+	return idempotent && (err != nil || statusCode >= 500)
+}
+
+// backoff returns the delay before the next attempt. Without a Retry-After
+// delay, a random delay between half and all of the exponentially growing
+// limit is chosen so that many clients failing at the same time do not all
+// retry at once.
+func (m *TLSHTTPManager) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if m.policy.MaxDelay > 0 && retryAfter > m.policy.MaxDelay {
+			return m.policy.MaxDelay
+		}
+		return retryAfter
+	}
+	limit := m.policy.BaseDelay << uint(attempt)
+	if limit <= 0 || (m.policy.MaxDelay > 0 && limit > m.policy.MaxDelay) {
+		limit = m.policy.MaxDelay
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return limit/2 + time.Duration(mathrand.Int63n(int64(limit/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
 	}
-	return respBody, resp.StatusCode, nil
+	return 0
 }
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a TLSHTTPManager that records the delays between
+// attempts instead of sleeping.
+func newTestManager(policy RetryPolicy) (*TLSHTTPManager, *[]time.Duration) {
+	hm, err := NewTLSHTTPManager(false, policy)
+	if err != nil {
+		panic(err)
+	}
+	m := hm.(*TLSHTTPManager)
+	delays := []time.Duration{}
+	m.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+	return m, &delays
+}
+
+func TestRetry(t *testing.T) {
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts[r.Method+" "+r.URL.Path]++
+		switch r.URL.Path {
+		case "/flaky":
+			if attempts[r.Method+" "+r.URL.Path] < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		case "/throttled":
+			if attempts[r.Method+" "+r.URL.Path] == 1 {
+				w.Header().Set("Retry-After", "2")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	m, delays := newTestManager(RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 4 * time.Second})
+
+	// idempotent requests are retried after 5xx responses
+	_, statusCode, err := m.Get(nil, server.URL+"/flaky", nil)
+	if err != nil || statusCode != 200 || attempts["GET /flaky"] != 3 {
+		t.Fatalf("Expected the GET to succeed after 3 attempts but got %d after %d attempts: %v", statusCode, attempts["GET /flaky"], err)
+	}
+	if len(*delays) != 2 || (*delays)[0] < 500*time.Millisecond || (*delays)[0] > time.Second || (*delays)[1] < time.Second || (*delays)[1] > 2*time.Second {
+		t.Fatalf("Expected 2 exponentially growing delays but got %v", *delays)
+	}
+
+	// but others are not
+	_, statusCode, err = m.Post(nil, server.URL+"/flaky", nil)
+	if err != nil || statusCode != http.StatusBadGateway || attempts["POST /flaky"] != 1 {
+		t.Fatalf("Expected the POST to fail without being retried but got %d after %d attempts: %v", statusCode, attempts["POST /flaky"], err)
+	}
+
+	// any request is retried after a 429, honoring Retry-After
+	*delays = []time.Duration{}
+	_, statusCode, err = m.Post(nil, server.URL+"/throttled", nil)
+	if err != nil || statusCode != 200 || len(*delays) != 1 || (*delays)[0] != 2*time.Second {
+		t.Fatalf("Expected the POST to be retried after 2s but got %d after %v: %v", statusCode, *delays, err)
+	}
+
+	// retries are limited
+	_, statusCode, _ = m.Delete(nil, server.URL+"/down", nil)
+	if statusCode != http.StatusServiceUnavailable || attempts["DELETE /down"] != 4 {
+		t.Fatalf("Expected the DELETE to give up after 4 attempts but got %d after %d attempts", statusCode, attempts["DELETE /down"])
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	m, _ := newTestManager(RetryPolicy{Timeout: 20 * time.Millisecond})
+
+	if _, _, err := m.Get(nil, server.URL, nil); err == nil {
+		t.Fatalf("Expected the request to time out")
+	}
+	if _, _, err := m.Get(nil, "://invalid", nil); err == nil {
+		t.Fatalf("Expected an error creating a request with an invalid URL")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("120"); d != 2*time.Minute {
+		t.Errorf("Expected 2m but got %s", d)
+	}
+	if d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("Expected about an hour but got %s", d)
+	}
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Errorf("Expected no delay but got %s", d)
+	}
+}
@@ -0,0 +1,183 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// maxTraceBody is the largest body printed in a trace, longer bodies are
+	// truncated
+	maxTraceBody = 4096
+)
+
+// sensitiveFields are the JSON fields and query parameters whose values are
+// never printed. Names are compared in lower case without dashes and
+// underscores.
+var sensitiveFields = map[string]bool{
+	"authorization":   true,
+	"cookie":          true,
+	"iv":              true,
+	"key":             true,
+	"keyinternallogs": true,
+	"keylogs":         true,
+	"otp":             true,
+	"signature":       true,
+	"sslpkfile":       true,
+}
+
+// sensitiveFragments redact any field whose name contains one of them, such
+// as sessionToken or private_key.
+var sensitiveFragments = []string{"password", "private", "secret", "token"}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	if sensitiveFields[name] {
+		return true
+	}
+	for _, fragment := range sensitiveFragments {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVarsURL reports whether the URL reads or writes environment variables,
+// where every value is a secret regardless of its name.
+func isVarsURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "services" && parts[i+2] == "env" {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the values of sensitive query parameters and any password
+// in the user info.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if isSensitive(name) {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactHeaders returns the headers as sorted "Name: value" lines with
+// credentials hidden.
+func redactHeaders(headers map[string][]string) []string {
+	var lines []string
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		if isSensitive(name) || strings.HasSuffix(strings.ToLower(name), "authorization") {
+			value = redacted
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, value))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// redactBody returns a printable form of a request or response body. JSON
+// bodies have their sensitive values hidden, and for environment variable
+// requests every value is hidden. Other bodies are never printed.
+func redactBody(rawURL string, body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return fmt.Sprintf("(%d bytes of non JSON content not shown)", len(body))
+	}
+	b, err := json.Marshal(redactValue(data, isVarsURL(rawURL)))
+	if err != nil {
+		return fmt.Sprintf("(%d bytes not shown)", len(body))
+	}
+	if len(b) > maxTraceBody {
+		return fmt.Sprintf("%s... (truncated)", b[:maxTraceBody])
+	}
+	return string(b)
+}
+
+func redactValue(value interface{}, all bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			if isSensitive(name) {
+				v[name] = redacted
+			} else {
+				v[name] = redactValue(child, all)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child, all)
+		}
+		return v
+	case string:
+		if all {
+			return redacted
+		}
+	}
+	return value
+}
+
+// traceRequest prints a redacted summary of a request before it is sent.
+func (m *TLSHTTPManager) traceRequest(method, url string, headers map[string][]string, body []byte, size int64) {
+	if m.trace == nil {
+		return
+	}
+	fmt.Fprintf(m.trace, "--> %s %s (%d bytes)\n", method, redactURL(url), size)
+	for _, line := range redactHeaders(headers) {
+		fmt.Fprintf(m.trace, "    %s\n", line)
+	}
+	if b := redactBody(url, body); b != "" {
+		fmt.Fprintf(m.trace, "    %s\n", b)
+	}
+}
+
+// traceResponse prints a redacted summary of the response to a single attempt
+// of a request.
+func (m *TLSHTTPManager) traceResponse(method, url string, statusCode int, body []byte, latency time.Duration, err error) {
+	if m.trace == nil {
+		return
+	}
+	latency = latency.Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(m.trace, "<-- %s %s failed after %s: %s\n", method, redactURL(url), latency, err)
+		return
+	}
+	fmt.Fprintf(m.trace, "<-- %d %s %s (%d bytes in %s)\n", statusCode, method, redactURL(url), len(body), latency)
+	if b := redactBody(url, body); b != "" {
+		fmt.Fprintf(m.trace, "    %s\n", b)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/lib/network"
 	"github.com/daticahealth/cli/lib/prompts"
 	"github.com/daticahealth/cli/models"
 	"github.com/docker/distribution/registry/client/auth"
@@ -26,7 +27,6 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	dockerClient "github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
-	"github.com/docker/go-connections/tlsconfig"
 	"github.com/docker/notary"
 	notaryClient "github.com/docker/notary/client"
 	"github.com/docker/notary/client/changelist"
@@ -296,7 +296,7 @@ func (d *SImages) CheckChangelist(repo notaryClient.Repository, ip prompts.IProm
 // GetNotaryRepository returns a pointer to the notary repository for an image
 func (d *SImages) GetNotaryRepository(pod, imageName string, user *models.User) notaryClient.Repository {
 	notaryServer := getServer(pod, notaryServers)
-	transport, err := getTransport(imageName, notaryServer, user, readWrite)
+	transport, err := getTransport(d.Settings.Network, imageName, notaryServer, user, readWrite)
 	if err != nil {
 		logrus.Fatalln(err)
 	}
@@ -491,19 +491,17 @@ const (
 	admin
 )
 
-func getTransport(gun, notaryServer string, user *models.User, permission httpAccess) (http.RoundTripper, error) {
-	tlsConfig, err := tlsconfig.Client(tlsconfig.Options{
-		CAFile:             "",
-		InsecureSkipVerify: false,
-		CertFile:           "",
-		KeyFile:            "",
-	})
+func getTransport(nc *models.NetworkConfig, gun, notaryServer string, user *models.User, permission httpAccess) (http.RoundTripper, error) {
+	tlsConfig, err := network.TLSConfig(nc)
 	if err != nil {
 		return nil, fmt.Errorf("unable to configure TLS: %s", err.Error())
 	}
+	// SKIP_VERIFY is meant for testing the API, image trust data is always
+	// verified. A CA bundle still applies.
+	tlsConfig.InsecureSkipVerify = false
 
 	base := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: network.Proxy(nc),
 		Dial: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
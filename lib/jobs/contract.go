@@ -1,6 +1,10 @@
 package jobs
 
-import "github.com/daticahealth/cli/models"
+import (
+	"context"
+
+	"github.com/daticahealth/cli/models"
+)
 
 // IJobs
 type IJobs interface {
@@ -17,6 +21,7 @@ type IJobs interface {
 	PollTillFinished(jobID, svcID string) (string, error)
 	List(svcID string, page, pageSize int) (*[]models.Job, error)
 	WaitToAppear(jobID, svcID string) error
+	Watch(ctx context.Context, jobID, svcID string, opts WatchOptions) (string, error)
 }
 
 // SJobs is a concrete implementation of IJobs
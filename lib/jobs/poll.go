@@ -1,12 +1,10 @@
 package jobs
 
 import (
-	"fmt"
+	"context"
 	"time"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/daticahealth/cli/config"
-	"github.com/daticahealth/cli/models"
 )
 
 func contains(v string, a []string) bool {
@@ -22,55 +20,15 @@ func (j *SJobs) PollTillFinished(jobID, svcID string) (string, error) {
 	return j.PollForStatus([]string{"finished"}, jobID, svcID)
 }
 
+// WaitToAppear waits for a job that is not visible yet, such as a snapshot
+// backup, to show up. It gives up after config.JobAppearTimeout.
 func (j *SJobs) WaitToAppear(jobID, svcID string) error {
-	for {
-		headers := j.Settings.HTTPManager.GetHeaders(j.Settings.SessionToken, j.Settings.Version, j.Settings.Pod, j.Settings.UsersID)
-		_, statusCode, err := j.Settings.HTTPManager.Get(nil, fmt.Sprintf("%s%s/environments/%s/services/%s/jobs/%s", j.Settings.PaasHost, j.Settings.PaasHostVersion, j.Settings.EnvironmentID, svcID, jobID), headers)
-		if err != nil {
-			return err
-		}
-		if statusCode >= 200 && statusCode < 300 {
-			return nil
-		}
-		time.Sleep(time.Second * 2)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.JobAppearTimeout*time.Minute)
+	defer cancel()
+	_, err := j.Watch(ctx, jobID, svcID, WatchOptions{UntilAppears: true})
+	return err
 }
 
 func (j *SJobs) PollForStatus(statuses []string, jobID, svcID string) (string, error) {
-	var job models.Job
-	failedAttempts := 0
-poll:
-	for {
-		failed := false
-		headers := j.Settings.HTTPManager.GetHeaders(j.Settings.SessionToken, j.Settings.Version, j.Settings.Pod, j.Settings.UsersID)
-		resp, statusCode, err := j.Settings.HTTPManager.Get(nil, fmt.Sprintf("%s%s/environments/%s/services/%s/jobs/%s", j.Settings.PaasHost, j.Settings.PaasHostVersion, j.Settings.EnvironmentID, svcID, jobID), headers)
-		if err != nil {
-			failed = true
-		}
-		err = j.Settings.HTTPManager.ConvertResp(resp, statusCode, &job)
-		if err != nil {
-			failed = true
-		}
-		if failed {
-			failedAttempts++
-		}
-		s := job.Status
-		switch {
-		case contains(s, statuses):
-			break poll
-		case contains(s, []string{"scheduled", "queued", "started", "running", "stopped", "waiting"}):
-			if failedAttempts >= 3 {
-				return "", fmt.Errorf("Error - ended in status '%s'.", job.Status)
-			}
-			// all because logrus treats print, println, and printf the same
-			logrus.StandardLogger().Out.Write([]byte("."))
-			time.Sleep(config.JobPollTime * time.Second)
-		default:
-			return "", fmt.Errorf("Error - ended in status '%s'.", job.Status)
-		}
-	}
-	if !contains(job.Status, statuses) {
-		return "", fmt.Errorf("Error - ended in status '%s'.", job.Status)
-	}
-	return job.Status, nil
+	return j.Watch(context.Background(), jobID, svcID, WatchOptions{Statuses: statuses})
 }
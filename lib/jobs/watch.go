@@ -0,0 +1,189 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/prompts"
+	"github.com/daticahealth/cli/models"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// pendingStatuses are the statuses of jobs that have not ended yet
+var pendingStatuses = []string{"scheduled", "queued", "started", "running", "stopped", "waiting"}
+
+// maxFailedPolls is how many polls in a row may fail before Watch gives up
+const maxFailedPolls = 3
+
+// Event is a change in the status of a watched job.
+type Event struct {
+	Time     time.Time
+	JobID    string
+	Previous string // empty for the first status seen
+	Status   string
+	Elapsed  time.Duration // since the watch started
+}
+
+// WatchOptions configure Watch. The zero value waits for the job to finish
+// using the job timeout and poll interval of the settings.
+type WatchOptions struct {
+	// Statuses end the watch successfully. Defaults to finished.
+	Statuses []string
+	// UntilAppears ends the watch as soon as the job can be retrieved, a 404
+	// is polled again until then.
+	UntilAppears bool
+	// Timeout limits the whole watch. Defaults to the --job-timeout option,
+	// no limit when both are zero.
+	Timeout time.Duration
+	// PollInterval is the time between the first polls. It grows while the
+	// status does not change. Defaults to the --poll-interval option.
+	PollInterval time.Duration
+	// OnEvent receives every status change. Defaults to PrintEvent.
+	OnEvent func(Event)
+	// ConfirmStop is asked whether the job should be stopped when the watch
+	// is interrupted with Ctrl-C. Defaults to a y/n prompt on a terminal.
+	ConfirmStop func(jobID string) bool
+}
+
+// PrintEvent prints a status change with its time.
+func PrintEvent(e Event) {
+	if e.Previous == "" {
+		logrus.Printf("[%s] Job %s is %s", e.Time.Format("15:04:05"), e.JobID, e.Status)
+		return
+	}
+	logrus.Printf("[%s] Job %s changed from %s to %s after %s", e.Time.Format("15:04:05"), e.JobID, e.Previous, e.Status, e.Elapsed.Round(time.Second))
+}
+
+// Watch polls a job until it reaches one of the given statuses, ends in any
+// other status, ctx is done, or the timeout passes. The poll interval backs
+// off exponentially up to config.JobMaxPollTime while the status stays the
+// same. Pressing Ctrl-C stops watching and offers to stop the job.
+func (j *SJobs) Watch(ctx context.Context, jobID, svcID string, opts WatchOptions) (string, error) {
+	statuses := opts.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{"finished"}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = j.Settings.JobTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = j.Settings.JobPollInterval
+	}
+	if interval <= 0 {
+		interval = config.JobPollTime * time.Second
+	}
+	maxInterval := config.JobMaxPollTime * time.Second
+	if interval > maxInterval {
+		maxInterval = interval
+	}
+	onEvent := opts.OnEvent
+	if onEvent == nil {
+		onEvent = PrintEvent
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	start := time.Now()
+	status := ""
+	failedPolls := 0
+	delay := interval
+	for {
+		job, statusCode, err := j.poll(jobID, svcID)
+		switch {
+		case err == nil:
+			failedPolls = 0
+			if opts.UntilAppears {
+				return job.Status, nil
+			}
+			if job.Status != status {
+				now := time.Now()
+				onEvent(Event{Time: now, JobID: jobID, Previous: status, Status: job.Status, Elapsed: now.Sub(start)})
+				status = job.Status
+				delay = interval
+			}
+			if contains(status, statuses) {
+				return status, nil
+			}
+			if !contains(status, pendingStatuses) {
+				return status, fmt.Errorf("Error - ended in status '%s'.", status)
+			}
+		case opts.UntilAppears && statusCode == 404:
+			// the job is not visible yet
+		default:
+			failedPolls++
+			if failedPolls >= maxFailedPolls {
+				return status, fmt.Errorf("Could not retrieve the status of job %s: %s", jobID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return status, fmt.Errorf("Timed out after %s waiting for job %s (last status '%s')", time.Since(start).Round(time.Second), jobID, status)
+			}
+			return status, ctx.Err()
+		case <-interrupts:
+			signal.Stop(interrupts)
+			return status, j.interrupted(jobID, svcID, opts.ConfirmStop)
+		case <-time.After(delay):
+		}
+		if delay = delay * 3 / 2; delay > maxInterval {
+			delay = maxInterval
+		}
+	}
+}
+
+// IsPending returns whether a job in the given status has not ended yet.
+func IsPending(status string) bool {
+	return contains(status, pendingStatuses)
+}
+
+// poll retrieves the job and returns the HTTP status code so a job that
+// does not exist yet can be told apart from other errors.
+func (j *SJobs) poll(jobID, svcID string) (*models.Job, int, error) {
+	headers := j.Settings.HTTPManager.GetHeaders(j.Settings.SessionToken, j.Settings.Version, j.Settings.Pod, j.Settings.UsersID)
+	resp, statusCode, err := j.Settings.HTTPManager.Get(nil, fmt.Sprintf("%s%s/environments/%s/services/%s/jobs/%s", j.Settings.PaasHost, j.Settings.PaasHostVersion, j.Settings.EnvironmentID, svcID, jobID), headers)
+	if err != nil {
+		return nil, statusCode, err
+	}
+	var job models.Job
+	if err = j.Settings.HTTPManager.ConvertResp(resp, statusCode, &job); err != nil {
+		return nil, statusCode, err
+	}
+	return &job, statusCode, nil
+}
+
+func (j *SJobs) interrupted(jobID, svcID string, confirmStop func(string) bool) error {
+	logrus.Println()
+	if confirmStop == nil {
+		confirmStop = promptStop
+	}
+	if !confirmStop(jobID) {
+		return fmt.Errorf("Stopped waiting for job %s. It keeps running in the background", jobID)
+	}
+	if err := j.Delete(jobID, svcID); err != nil {
+		return fmt.Errorf("Could not stop job %s: %s", jobID, err)
+	}
+	return fmt.Errorf("Stopped job %s", jobID)
+}
+
+func promptStop(jobID string) bool {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	return prompts.New().YesNo("", fmt.Sprintf("Would you like to stop job %s? (y/n) ", jobID)) == nil
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daticahealth/cli/test"
+)
+
+const jobID = "job1"
+
+// serveStatuses answers each poll of the job with the next status, repeating
+// the last one. An empty status answers with a 404.
+func serveStatuses(mux *http.ServeMux, statuses ...string) {
+	polls := 0
+	mux.HandleFunc("/environments/"+test.EnvID+"/services/"+test.SvcID+"/jobs/"+jobID,
+		func(w http.ResponseWriter, r *http.Request) {
+			status := statuses[len(statuses)-1]
+			if polls < len(statuses) {
+				status = statuses[polls]
+			}
+			polls++
+			if r.Method == "DELETE" {
+				fmt.Fprint(w, `{}`)
+				return
+			}
+			if status == "" {
+				w.WriteHeader(404)
+				fmt.Fprint(w, `{"title":"Not Found","description":"Job not found","code":404}`)
+				return
+			}
+			fmt.Fprintf(w, `{"id":"%s","type":"backup","status":"%s"}`, jobID, status)
+		},
+	)
+}
+
+func TestWatch(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	serveStatuses(mux, "queued", "queued", "started", "finished")
+
+	var events []string
+	status, err := New(test.GetSettings(baseURL.String())).Watch(context.Background(), jobID, test.SvcID, WatchOptions{
+		PollInterval: time.Millisecond,
+		OnEvent: func(e Event) {
+			test.AssertEquals(t, jobID, e.JobID)
+			events = append(events, e.Previous+">"+e.Status)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test.AssertEquals(t, "finished", status)
+	test.AssertEquals(t, ">queued,queued>started,started>finished", strings.Join(events, ","))
+}
+
+func TestWatchFailed(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	serveStatuses(mux, "started", "failed")
+
+	status, err := New(test.GetSettings(baseURL.String())).Watch(context.Background(), jobID, test.SvcID, WatchOptions{PollInterval: time.Millisecond, OnEvent: func(Event) {}})
+	if err == nil {
+		t.Fatalf("Expected an error for a failed job")
+	}
+	test.AssertEquals(t, "failed", status)
+}
+
+func TestWatchTimeout(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	serveStatuses(mux, "running")
+
+	settings := test.GetSettings(baseURL.String())
+	settings.JobTimeout = 50 * time.Millisecond
+	_, err := New(settings).Watch(context.Background(), jobID, test.SvcID, WatchOptions{PollInterval: time.Millisecond, OnEvent: func(Event) {}})
+	if err == nil || !strings.Contains(err.Error(), "Timed out") {
+		t.Fatalf("Expected a timeout but got %v", err)
+	}
+}
+
+func TestWatchUntilAppears(t *testing.T) {
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	serveStatuses(mux, "", "", "", "", "queued")
+
+	status, err := New(test.GetSettings(baseURL.String())).Watch(context.Background(), jobID, test.SvcID, WatchOptions{UntilAppears: true, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test.AssertEquals(t, "queued", status)
+}
+
+func TestWatchInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Ctrl-C cannot be sent to the test process")
+	}
+	mux, server, baseURL := test.Setup()
+	defer test.Teardown(server)
+	serveStatuses(mux, "running")
+	stopped := false
+
+	_, err := New(test.GetSettings(baseURL.String())).Watch(context.Background(), jobID, test.SvcID, WatchOptions{
+		Statuses:     []string{"finished"},
+		PollInterval: time.Millisecond,
+		OnEvent: func(Event) {
+			p, _ := os.FindProcess(os.Getpid())
+			p.Signal(os.Interrupt)
+		},
+		ConfirmStop: func(id string) bool {
+			test.AssertEquals(t, jobID, id)
+			stopped = true
+			return true
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Stopped job") {
+		t.Fatalf("Expected the job to be stopped but got %v", err)
+	}
+	if !stopped {
+		t.Errorf("Expected to be asked whether to stop the job")
+	}
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/daticahealth/cli/models"
+)
+
+const dialTimeout = 30 * time.Second
+
+// Dial opens a connection to the host of the given URL, using TLS for https
+// and wss URLs. When a proxy applies, the connection is tunneled through it
+// with an HTTP CONNECT request. This is used by clients that cannot be handed
+// an http.Transport, such as the console websocket.
+func Dial(nc *models.NetworkConfig, target *url.URL) (net.Conn, error) {
+	tlsConfig, err := TLSConfig(nc)
+	if err != nil {
+		return nil, err
+	}
+	addr := hostPort(target)
+	tlsConfig.ServerName = target.Hostname()
+	secure := target.Scheme == "https" || target.Scheme == "wss"
+
+	proxy, err := ProxyForURL(nc, target)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	if proxy == nil {
+		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		conn, err = dialProxy(nc, proxy)
+		if err != nil {
+			return nil, err
+		}
+		if err = connect(conn, proxy, addr); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if !secure {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func dialProxy(nc *models.NetworkConfig, proxy *url.URL) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if proxy.Scheme != "https" {
+		return dialer.Dial("tcp", hostPort(proxy))
+	}
+	tlsConfig, err := TLSConfig(nc)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = proxy.Hostname()
+	return tls.DialWithDialer(dialer, "tcp", hostPort(proxy), tlsConfig)
+}
+
+// connect asks the proxy to open a tunnel to addr over conn.
+func connect(conn net.Conn, proxy *url.URL, addr string) error {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	// the server does not send anything after the CONNECT response until the
+	// TLS handshake starts so nothing is lost in the buffered reader
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("The proxy %s refused to connect to %s: %s", proxy.Host, addr, resp.Status)
+	}
+	return nil
+}
+
+// bypassProxy reports whether host matches an entry of the comma separated
+// noProxy list. Entries match the host itself and all of its subdomains, an
+// entry of * matches every host, and loopback addresses are never proxied.
+func bypassProxy(noProxy, host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.ToLower(hostname)
+	if hostname == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(hostname); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			if entry != strings.ToLower(host) {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if hostname == entry || strings.HasSuffix(hostname, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "http", "ws":
+		return net.JoinHostPort(u.Hostname(), "80")
+	}
+	return net.JoinHostPort(u.Hostname(), "443")
+}
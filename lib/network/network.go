@@ -0,0 +1,155 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/models"
+)
+
+// FromEnv builds a network configuration from the DATICA_PROXY,
+// DATICA_NO_PROXY, DATICA_CA_BUNDLE, DATICA_CLIENT_CERT, DATICA_CLIENT_KEY,
+// and SKIP_VERIFY env variables.
+func FromEnv() *models.NetworkConfig {
+	skip, _ := strconv.ParseBool(os.Getenv(config.SkipVerifyEnvVar))
+	return &models.NetworkConfig{
+		ProxyURL:   os.Getenv(config.ProxyEnvVar),
+		NoProxy:    os.Getenv(config.NoProxyEnvVar),
+		CABundle:   os.Getenv(config.CABundleEnvVar),
+		ClientCert: os.Getenv(config.ClientCertEnvVar),
+		ClientKey:  os.Getenv(config.ClientKeyEnvVar),
+		SkipVerify: skip,
+	}
+}
+
+// Validate checks that the proxy URL can be parsed and that the CA bundle and
+// client certificate can be loaded so a bad configuration is reported before
+// any connection is attempted.
+func Validate(nc *models.NetworkConfig) error {
+	if nc == nil {
+		return nil
+	}
+	if _, err := parseProxyURL(nc.ProxyURL); err != nil {
+		return err
+	}
+	_, err := TLSConfig(nc)
+	return err
+}
+
+// TLSConfig returns the TLS configuration for connections to Datica and its
+// proxies. The CA bundle is added to the system roots rather than replacing
+// them so public endpoints keep working behind a TLS intercepting proxy.
+func TLSConfig(nc *models.NetworkConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if nc == nil {
+		return tlsConfig, nil
+	}
+	tlsConfig.InsecureSkipVerify = nc.SkipVerify
+	if nc.CABundle != "" {
+		pem, err := ioutil.ReadFile(nc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read the CA bundle %s: %s", nc.CABundle, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No PEM encoded certificates were found in the CA bundle %s", nc.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if nc.ClientCert != "" || nc.ClientKey != "" {
+		if nc.ClientCert == "" || nc.ClientKey == "" {
+			return nil, fmt.Errorf("A client certificate requires both a certificate file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(nc.ClientCert, nc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load the client certificate %s: %s", nc.ClientCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Transport returns an http.Transport that uses the configured proxy and TLS
+// settings.
+func Transport(nc *models.NetworkConfig) (*http.Transport, error) {
+	tlsConfig, err := TLSConfig(nc)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:           Proxy(nc),
+		TLSClientConfig: tlsConfig,
+	}, nil
+}
+
+// Client returns an http.Client for requests that do not go through the
+// Datica API client, such as file transfers to presigned URLs.
+func Client(nc *models.NetworkConfig) (*http.Client, error) {
+	tr, err := Transport(nc)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// Proxy returns the proxy function for an http.Transport. Without a
+// configured proxy URL the standard HTTPS_PROXY, HTTP_PROXY, and NO_PROXY env
+// variables are honored.
+func Proxy(nc *models.NetworkConfig) func(*http.Request) (*url.URL, error) {
+	if nc == nil || nc.ProxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		return ProxyForURL(nc, req.URL)
+	}
+}
+
+// ProxyForURL returns the proxy that should be used to reach the given URL or
+// nil if the URL should be reached directly.
+func ProxyForURL(nc *models.NetworkConfig, target *url.URL) (*url.URL, error) {
+	if nc == nil || nc.ProxyURL == "" {
+		// ProxyFromEnvironment only understands http and https
+		u := *target
+		switch u.Scheme {
+		case "wss":
+			u.Scheme = "https"
+		case "ws":
+			u.Scheme = "http"
+		}
+		return http.ProxyFromEnvironment(&http.Request{URL: &u})
+	}
+	if bypassProxy(nc.NoProxy, target.Host) {
+		return nil, nil
+	}
+	return parseProxyURL(nc.ProxyURL)
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		// allow host:port without a scheme like curl does
+		u, err = url.Parse("http://" + proxy)
+	}
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy URL \"%s\"", proxy)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid proxy URL \"%s\". Only http and https proxies are supported", proxy)
+	}
+	return u, nil
+}
@@ -0,0 +1,159 @@
+package network
+
+import (
+	"bufio"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/daticahealth/cli/models"
+)
+
+var bypassTests = []struct {
+	noProxy  string
+	host     string
+	expected bool
+}{
+	{"", "api.datica.com", false},
+	{"", "localhost:8080", true},
+	{"", "127.0.0.1", true},
+	{"*", "api.datica.com", true},
+	{"datica.com", "api.datica.com", true},
+	{".datica.com", "api.datica.com:443", true},
+	{"datica.com", "datica.com", true},
+	{"datica.com", "notdatica.com", false},
+	{"example.com, api.datica.com", "api.datica.com", true},
+	{"api.datica.com:8443", "api.datica.com:443", false},
+	{"api.datica.com:8443", "api.datica.com:8443", true},
+}
+
+func TestBypassProxy(t *testing.T) {
+	for _, data := range bypassTests {
+		t.Logf("Data: %+v", data)
+		if actual := bypassProxy(data.noProxy, data.host); actual != data.expected {
+			t.Errorf("Expected %t but got %t", data.expected, actual)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bundle := writeTemp(t, []byte("not a certificate"))
+	defer os.Remove(bundle)
+	configs := []*models.NetworkConfig{
+		{ProxyURL: "ftp://proxy.example.com"},
+		{CABundle: bundle},
+		{CABundle: bundle + ".missing"},
+		{ClientCert: bundle},
+	}
+	for _, nc := range configs {
+		if err := Validate(nc); err == nil {
+			t.Errorf("Expected an error for %+v", nc)
+		}
+	}
+	if err := Validate(&models.NetworkConfig{ProxyURL: "proxy.example.com:3128", NoProxy: "datica.com"}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+// TestProxyAndCABundle sends requests to a server with a private certificate
+// through a CONNECT proxy, the same setup as an intercepting corporate proxy.
+func TestProxyAndCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	bundle := writeTemp(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	defer os.Remove(bundle)
+
+	tunnels := 0
+	proxy := newConnectProxy(t, server.Listener.Addr().String(), &tunnels)
+	defer proxy.Close()
+
+	// the test certificate is valid for example.com, and the proxy sends every
+	// tunnel to the test server
+	target, _ := url.Parse("https://example.com/")
+	nc := &models.NetworkConfig{ProxyURL: "http://" + proxy.Addr().String()}
+
+	if _, err := Dial(nc, target); err == nil {
+		t.Fatal("Expected the private certificate to be rejected without the CA bundle")
+	}
+
+	nc.CABundle = bundle
+	client, err := Client(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("Expected hello but got %s", body)
+	}
+
+	conn, err := Dial(nc, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if tunnels != 3 {
+		t.Errorf("Expected 3 tunnels through the proxy but got %d", tunnels)
+	}
+}
+
+// newConnectProxy starts a proxy that answers CONNECT requests by tunneling
+// to addr regardless of the requested host.
+func newConnectProxy(t *testing.T, addr string, tunnels *int) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			req, err := http.ReadRequest(bufio.NewReader(conn))
+			if err != nil || req.Method != "CONNECT" {
+				conn.Close()
+				continue
+			}
+			*tunnels++
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+			go func() {
+				io.Copy(upstream, conn)
+				upstream.Close()
+			}()
+			go func() {
+				io.Copy(conn, upstream)
+				conn.Close()
+			}()
+		}
+	}()
+	return l
+}
+
+func writeTemp(t *testing.T, b []byte) string {
+	f, err := ioutil.TempFile("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(b)
+	f.Close()
+	return f.Name()
+}
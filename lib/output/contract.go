@@ -0,0 +1,37 @@
+package output
+
+import (
+	"io"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/daticahealth/cli/models"
+)
+
+// The output formats supported by the global --output option
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// IOutput is the shared renderer used by list and show commands. When a
+// structured format is selected, commands hand their models to Render instead
+// of printing tables.
+type IOutput interface {
+	Structured() bool
+	Render(data interface{}) error
+}
+
+// SOutput is a concrete implementation of IOutput
+type SOutput struct {
+	Format string
+	Writer io.Writer
+}
+
+// New returns an instance of IOutput
+func New(settings *models.Settings) IOutput {
+	return &SOutput{
+		Format: settings.OutputFormat,
+		Writer: logrus.StandardLogger().Out,
+	}
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ValidateFormat ensures the given format is one of the supported output
+// formats. An empty format is treated as plain text.
+func ValidateFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatText, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("Invalid output format \"%s\". Supported formats are %s, %s, and %s", format, FormatText, FormatJSON, FormatYAML)
+}
+
+// Structured returns whether or not the selected format is a machine readable
+// format such as JSON or YAML.
+func (o *SOutput) Structured() bool {
+	format := strings.ToLower(o.Format)
+	return format == FormatJSON || format == FormatYAML
+}
+
+// Render writes the given data in the selected format. YAML output is
+// generated from the JSON representation so that keys match the json tags on
+// the models and are emitted in a stable, sorted order.
+func (o *SOutput) Render(data interface{}) error {
+	b, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(o.Format) == FormatYAML {
+		var generic interface{}
+		if err = json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		b, err = yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		_, err = o.Writer.Write(b)
+		return err
+	}
+	_, err = fmt.Fprintln(o.Writer, string(b))
+	return err
+}
@@ -0,0 +1,142 @@
+package sshkeys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthSockEnvVar is the env variable ssh-agent sets to the path of its socket
+const AuthSockEnvVar = "SSH_AUTH_SOCK"
+
+// ssh-agent protocol messages, see
+// https://tools.ietf.org/html/draft-miller-ssh-agent. The identities answer
+// (12), sign request (13), and sign response (14) are marshaled through the
+// sshtype struct tags.
+const (
+	agentFailure           = 5
+	agentRequestIdentities = 11
+
+	agentRSASHA256 = 2
+)
+
+// maxAgentReply guards against reading a bogus length from the socket
+const maxAgentReply = 256 * 1024
+
+// agent is a minimal ssh-agent client that can list keys and sign data.
+type agent struct {
+	conn io.ReadWriter
+}
+
+// dialAgent connects to the ssh-agent listening on SSH_AUTH_SOCK.
+func dialAgent() (*agent, io.Closer, error) {
+	sock := os.Getenv(AuthSockEnvVar)
+	if sock == "" {
+		return nil, nil, fmt.Errorf("%s is not set", AuthSockEnvVar)
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &agent{conn: conn}, conn, nil
+}
+
+func (a *agent) call(req []byte) ([]byte, error) {
+	msg := make([]byte, 4+len(req))
+	binary.BigEndian.PutUint32(msg, uint32(len(req)))
+	copy(msg[4:], req)
+	if _, err := a.conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var length [4]byte
+	if _, err := io.ReadFull(a.conn, length[:]); err != nil {
+		return nil, err
+	}
+	l := binary.BigEndian.Uint32(length[:])
+	if l == 0 || l > maxAgentReply {
+		return nil, fmt.Errorf("Invalid ssh-agent reply length %d", l)
+	}
+	reply := make([]byte, l)
+	if _, err := io.ReadFull(a.conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] == agentFailure {
+		return nil, errors.New("The ssh-agent refused the request")
+	}
+	return reply, nil
+}
+
+// hasKey reports whether the agent holds the private key for pub.
+func (a *agent) hasKey(pub ssh.PublicKey) (bool, error) {
+	reply, err := a.call([]byte{agentRequestIdentities})
+	if err != nil {
+		return false, err
+	}
+	var answer struct {
+		NumKeys uint32 `sshtype:"12"`
+		Keys    []byte `ssh:"rest"`
+	}
+	if err = ssh.Unmarshal(reply, &answer); err != nil {
+		return false, err
+	}
+	want := pub.Marshal()
+	rest := answer.Keys
+	for i := uint32(0); i < answer.NumKeys; i++ {
+		var identity struct {
+			Blob    []byte
+			Comment string
+			Rest    []byte `ssh:"rest"`
+		}
+		if err = ssh.Unmarshal(rest, &identity); err != nil {
+			return false, err
+		}
+		if bytes.Equal(identity.Blob, want) {
+			return true, nil
+		}
+		rest = identity.Rest
+	}
+	return false, nil
+}
+
+// sign asks the agent to sign data with the private key for pub and returns
+// the signature blob. RSA keys are signed with rsa-sha2-256.
+func (a *agent) sign(pub ssh.PublicKey, data []byte) ([]byte, error) {
+	var flags uint32
+	format := pub.Type()
+	if format == ssh.KeyAlgoRSA {
+		flags = agentRSASHA256
+		format = "rsa-sha2-256"
+	}
+	reply, err := a.call(ssh.Marshal(struct {
+		KeyBlob []byte `sshtype:"13"`
+		Data    []byte
+		Flags   uint32
+	}{pub.Marshal(), data, flags}))
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		SigBlob []byte `sshtype:"14"`
+	}
+	if err = ssh.Unmarshal(reply, &resp); err != nil {
+		return nil, err
+	}
+	var sig struct {
+		Format string
+		Blob   []byte
+		Rest   []byte `ssh:"rest"`
+	}
+	if err = ssh.Unmarshal(resp.SigBlob, &sig); err != nil {
+		return nil, err
+	}
+	if sig.Format != format {
+		return nil, fmt.Errorf("The ssh-agent returned a %s signature instead of %s. Please upgrade your ssh-agent.", sig.Format, format)
+	}
+	return sig.Blob, nil
+}
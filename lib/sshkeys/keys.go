@@ -0,0 +1,161 @@
+package sshkeys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyAlgoED25519 is the ssh key type of Ed25519 keys. The vendored ssh package
+// only knows about RSA, DSA, and ECDSA keys.
+const KeyAlgoED25519 = "ssh-ed25519"
+
+// ErrEncrypted is returned when a private key is encrypted and no passphrase
+// prompt was given.
+var ErrEncrypted = errors.New("The private key is encrypted with a passphrase")
+
+// ed25519PublicKey implements ssh.PublicKey for Ed25519 keys.
+type ed25519PublicKey ed25519.PublicKey
+
+func (k ed25519PublicKey) Type() string {
+	return KeyAlgoED25519
+}
+
+func (k ed25519PublicKey) Marshal() []byte {
+	return ssh.Marshal(struct {
+		Name     string
+		KeyBytes []byte
+	}{KeyAlgoED25519, []byte(k)})
+}
+
+func (k ed25519PublicKey) Verify(data []byte, sig *ssh.Signature) error {
+	if sig.Format != KeyAlgoED25519 {
+		return fmt.Errorf("Signature type %s does not match key type %s", sig.Format, KeyAlgoED25519)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(k), data, sig.Blob) {
+		return errors.New("Invalid Ed25519 signature")
+	}
+	return nil
+}
+
+// ParsePublicKey parses a public key in the authorized_keys format, the
+// format of id_*.pub files.
+func ParsePublicKey(b []byte) (ssh.PublicKey, error) {
+	if key, _, _, _, err := ssh.ParseAuthorizedKey(b); err == nil {
+		return key, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i := 0; i < len(fields)-1; i++ {
+			if fields[i] != KeyAlgoED25519 {
+				continue
+			}
+			blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+			if err != nil {
+				continue
+			}
+			if key, err := ParsePublicKeyBlob(blob); err == nil {
+				return key, nil
+			}
+		}
+	}
+	return nil, errors.New("Invalid public key format")
+}
+
+// ParsePublicKeyBlob parses a public key in the ssh wire format.
+func ParsePublicKeyBlob(blob []byte) (ssh.PublicKey, error) {
+	var w struct {
+		Name     string
+		KeyBytes []byte
+		Rest     []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(blob, &w); err == nil && w.Name == KeyAlgoED25519 {
+		if len(w.KeyBytes) != ed25519.PublicKeySize {
+			return nil, errors.New("Invalid Ed25519 public key")
+		}
+		return ed25519PublicKey(w.KeyBytes), nil
+	}
+	return ssh.ParsePublicKey(blob)
+}
+
+// NewPublicKey converts an RSA, ECDSA, or Ed25519 public key to an
+// ssh.PublicKey.
+func NewPublicKey(key crypto.PublicKey) (ssh.PublicKey, error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return ed25519PublicKey(k), nil
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return ssh.NewPublicKey(k)
+	}
+	return nil, fmt.Errorf("Unsupported public key type %T", key)
+}
+
+// ParsePrivateKey parses a PEM encoded RSA, ECDSA, or Ed25519 private key.
+// PKCS#1, SEC 1, PKCS#8, and OpenSSH private keys are supported. passphrase is
+// only called for encrypted keys and may be nil.
+func ParsePrivateKey(b []byte, passphrase func() string) (crypto.Signer, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("Private key is not PEM-encoded")
+	}
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		return parseOpenSSHPrivateKey(block.Bytes, passphrase)
+	}
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if passphrase == nil {
+			return nil, ErrEncrypted
+		}
+		var err error
+		der, err = x509.DecryptPEMBlock(block, []byte(passphrase()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		return checkSigner(key)
+	}
+	return nil, fmt.Errorf("Unsupported private key type \"%s\"", block.Type)
+}
+
+// checkSigner makes sure a parsed private key is one of the supported types.
+func checkSigner(key interface{}) (crypto.Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("Unsupported private key type %T", key)
+}
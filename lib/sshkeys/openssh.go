@@ -0,0 +1,171 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dchest/bcrypt_pbkdf"
+	"golang.org/x/crypto/ssh"
+)
+
+const opensshMagic = "openssh-key-v1\x00"
+
+// parseOpenSSHPrivateKey parses the body of an "OPENSSH PRIVATE KEY" PEM
+// block, the default format of ssh-keygen since OpenSSH 7.8. Keys encrypted
+// with the bcrypt KDF and aes256-ctr or aes256-cbc are decrypted with the
+// passphrase.
+func parseOpenSSHPrivateKey(b []byte, passphrase func() string) (crypto.Signer, error) {
+	if !bytes.HasPrefix(b, []byte(opensshMagic)) {
+		return nil, errors.New("Invalid OpenSSH private key")
+	}
+	var w struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+		Rest         []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(b[len(opensshMagic):], &w); err != nil {
+		return nil, errors.New("Invalid OpenSSH private key")
+	}
+	if w.NumKeys != 1 {
+		return nil, errors.New("OpenSSH private key files with more than one key are not supported")
+	}
+
+	block := w.PrivKeyBlock
+	if w.CipherName != "none" || w.KdfName != "none" {
+		if passphrase == nil {
+			return nil, ErrEncrypted
+		}
+		var err error
+		if block, err = decryptOpenSSH(w.CipherName, w.KdfName, w.KdfOpts, block, passphrase()); err != nil {
+			return nil, err
+		}
+	}
+
+	var pk struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Rest    []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(block, &pk); err != nil || pk.Check1 != pk.Check2 {
+		return nil, errors.New("Incorrect passphrase for the OpenSSH private key")
+	}
+
+	switch pk.Keytype {
+	case ssh.KeyAlgoRSA:
+		var key struct {
+			N       *big.Int
+			E       *big.Int
+			D       *big.Int
+			Iqmp    *big.Int
+			P       *big.Int
+			Q       *big.Int
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(pk.Rest, &key); err != nil {
+			return nil, err
+		}
+		privKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: key.N, E: int(key.E.Int64())},
+			D:         key.D,
+			Primes:    []*big.Int{key.P, key.Q},
+		}
+		if err := privKey.Validate(); err != nil {
+			return nil, err
+		}
+		privKey.Precompute()
+		return privKey, nil
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		var key struct {
+			Curve   string
+			Pub     []byte
+			D       *big.Int
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(pk.Rest, &key); err != nil {
+			return nil, err
+		}
+		var curve elliptic.Curve
+		switch key.Curve {
+		case "nistp256":
+			curve = elliptic.P256()
+		case "nistp384":
+			curve = elliptic.P384()
+		case "nistp521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported curve %s", key.Curve)
+		}
+		x, y := elliptic.Unmarshal(curve, key.Pub)
+		if x == nil {
+			return nil, errors.New("Invalid ECDSA public key")
+		}
+		return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: key.D}, nil
+	case KeyAlgoED25519:
+		var key struct {
+			Pub     []byte
+			Priv    []byte
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(pk.Rest, &key); err != nil {
+			return nil, err
+		}
+		if len(key.Priv) != ed25519.PrivateKeySize {
+			return nil, errors.New("Invalid Ed25519 private key")
+		}
+		return ed25519.PrivateKey(key.Priv), nil
+	}
+	return nil, fmt.Errorf("Unsupported private key type %s", pk.Keytype)
+}
+
+func decryptOpenSSH(cipherName, kdfName, kdfOpts string, block []byte, passphrase string) ([]byte, error) {
+	if kdfName != "bcrypt" {
+		return nil, fmt.Errorf("Unsupported OpenSSH private key KDF %s", kdfName)
+	}
+	if cipherName != "aes256-ctr" && cipherName != "aes256-cbc" {
+		return nil, fmt.Errorf("Unsupported OpenSSH private key cipher %s", cipherName)
+	}
+	var opts struct {
+		Salt   []byte
+		Rounds uint32
+		Rest   []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal([]byte(kdfOpts), &opts); err != nil {
+		return nil, err
+	}
+	// aes256 needs a 32 byte key and a 16 byte iv
+	k, err := bcrypt_pbkdf.Key([]byte(passphrase), opts.Salt, int(opts.Rounds), 32+aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(k[:32])
+	if err != nil {
+		return nil, err
+	}
+	if len(block)%aes.BlockSize != 0 {
+		return nil, errors.New("Invalid OpenSSH private key")
+	}
+	decrypted := make([]byte, len(block))
+	if cipherName == "aes256-ctr" {
+		cipher.NewCTR(c, k[32:]).XORKeyStream(decrypted, block)
+	} else {
+		cipher.NewCBCDecrypter(c, k[32:]).CryptBlocks(decrypted, block)
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,150 @@
+package sshkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signer signs data with a private key read from disk or held by an
+// ssh-agent. Sign returns the blob of an ssh signature: a PKCS#1 v1.5 SHA-256
+// signature for RSA keys (rsa-sha2-256), the ssh encoded r and s for ECDSA keys
+// hashed with the curve's hash, and the raw signature of the data for Ed25519
+// keys.
+type Signer interface {
+	PublicKey() ssh.PublicKey
+	Sign(data []byte) ([]byte, error)
+}
+
+// NewSigner returns a Signer for the key at privateKeyPath. When the public
+// key is available at privateKeyPath + ".pub" and the ssh-agent at
+// SSH_AUTH_SOCK holds the matching private key, the agent signs and the
+// private key file is never read. Otherwise the private key file is parsed,
+// calling passphrase if it is encrypted.
+func NewSigner(privateKeyPath string, passphrase func() string) (Signer, error) {
+	if b, err := ioutil.ReadFile(privateKeyPath + ".pub"); err == nil {
+		if pub, err := ParsePublicKey(b); err == nil {
+			if s, err := newAgentSigner(pub); err == nil {
+				return s, nil
+			} else if os.Getenv(AuthSockEnvVar) != "" {
+				logrus.Debugf("Not signing with the ssh-agent: %s", err)
+			}
+		}
+	}
+	b, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(b, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := NewPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &keySigner{key: key, pub: pub}, nil
+}
+
+// LoadPublicKey returns the public key for the key at privateKeyPath, reading
+// privateKeyPath + ".pub" if it exists and deriving it from the private key
+// otherwise.
+func LoadPublicKey(privateKeyPath string, passphrase func() string) (ssh.PublicKey, error) {
+	if b, err := ioutil.ReadFile(privateKeyPath + ".pub"); err == nil {
+		return ParsePublicKey(b)
+	}
+	b, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(b, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewPublicKey(key.Public())
+}
+
+type agentSigner struct {
+	pub ssh.PublicKey
+}
+
+func newAgentSigner(pub ssh.PublicKey) (Signer, error) {
+	a, conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ok, err := a.hasKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("The ssh-agent does not hold the %s key", pub.Type())
+	}
+	return &agentSigner{pub: pub}, nil
+}
+
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *agentSigner) Sign(data []byte) ([]byte, error) {
+	a, conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return a.sign(s.pub, data)
+}
+
+type keySigner struct {
+	key crypto.Signer
+	pub ssh.PublicKey
+}
+
+func (s *keySigner) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *keySigner) Sign(data []byte) ([]byte, error) {
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		hashed := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashed[:])
+	case *ecdsa.PrivateKey:
+		var digest []byte
+		switch bitSize := k.Curve.Params().BitSize; {
+		case bitSize <= 256:
+			h := sha256.Sum256(data)
+			digest = h[:]
+		case bitSize <= 384:
+			h := sha512.Sum384(data)
+			digest = h[:]
+		default:
+			h := sha512.Sum512(data)
+			digest = h[:]
+		}
+		r, sig, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.Marshal(struct {
+			R *big.Int
+			S *big.Int
+		}{r, sig}), nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, data), nil
+	}
+	return nil, fmt.Errorf("Unsupported private key type %T", s.key)
+}
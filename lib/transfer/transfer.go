@@ -98,3 +98,63 @@ func (wct *WriteCloserTransfer) Transferred() ByteSize {
 func (wct *WriteCloserTransfer) Length() ByteSize {
 	return wct.length
 }
+
+// PartsTransfer monitors the combined progress of several readers, such as
+// the individual parts of a multipart upload. Bytes read by a part that is
+// later retried can be given back with Rewind.
+type PartsTransfer struct {
+	length      ByteSize
+	transferred int64
+}
+
+// NewPartsTransfer instantiates a new PartsTransfer. The transferred argument
+// is the number of bytes that were already transferred by a previous attempt.
+func NewPartsTransfer(length, transferred int) *PartsTransfer {
+	pt := new(PartsTransfer)
+	pt.length = ByteSize(length)
+	pt.transferred = int64(transferred)
+	return pt
+}
+
+// NewReader wraps the given reader so that reads are counted towards the
+// progress of this PartsTransfer.
+func (pt *PartsTransfer) NewReader(reader io.Reader) *PartReader {
+	return &PartReader{
+		parts:  pt,
+		reader: reader,
+	}
+}
+
+// Rewind removes the given number of bytes from the transferred total.
+func (pt *PartsTransfer) Rewind(n int) {
+	atomic.AddInt64(&pt.transferred, -int64(n))
+}
+
+func (pt *PartsTransfer) Transferred() ByteSize {
+	return ByteSize(atomic.LoadInt64(&pt.transferred))
+}
+
+func (pt *PartsTransfer) Length() ByteSize {
+	return pt.length
+}
+
+// PartReader counts the bytes read from a single part of a PartsTransfer
+type PartReader struct {
+	parts  *PartsTransfer
+	read   int
+	reader io.Reader
+}
+
+func (pr *PartReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.read += n
+	atomic.AddInt64(&pr.parts.transferred, int64(n))
+	return n, err
+}
+
+// Rewind removes all bytes read by this part from the overall progress. This
+// should be called before a failed part is retried.
+func (pr *PartReader) Rewind() {
+	pr.parts.Rewind(pr.read)
+	pr.read = 0
+}
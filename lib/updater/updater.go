@@ -48,7 +48,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -60,6 +59,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/bugsnag/osext"
 	"github.com/daticahealth/cli/config"
+	"github.com/daticahealth/cli/lib/network"
 )
 
 const (
@@ -242,8 +242,15 @@ func (u *Updater) fetchBin() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// fetch downloads url with the proxy and TLS settings from the environment.
+// Updates are checked before the command line is parsed so the global network
+// options do not apply here.
 func fetch(url string) (io.ReadCloser, error) {
-	resp, err := http.Get(url)
+	client, err := network.Client(network.FromEnv())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jault3/mow.cli"
 )
 
@@ -177,6 +179,7 @@ type LogHits struct {
 	Score  float64             `json:"_score"`
 	Fields map[string][]string `json:"fields"`
 	Source map[string]string   `json:"_source"`
+	Sort   []interface{}       `json:"sort,omitempty"`
 }
 
 // Login is used for making an authentication request
@@ -221,6 +224,13 @@ type MetricsData struct {
 	NetworkUsage *[]NetworkUsage `json:"network.usage"`
 }
 
+// MultipartUpload holds the information for an in progress multipart upload of
+// an import file
+type MultipartUpload struct {
+	UploadID string `json:"uploadId"`
+	Filename string `json:"filename"`
+}
+
 type NetworkUsage struct {
 	JobID     string  `json:"job"`
 	RXDropped float64 `json:"rx_dropped"`
@@ -382,14 +392,23 @@ type SettingsV1 struct {
 // `json:"-"` are never persisted to disk but used in memory for the current
 // command.
 type SettingsV2 struct {
-	AccountsHost    string      `json:"-"`
-	AuthHost        string      `json:"-"`
-	PaasHost        string      `json:"-"`
-	AuthHostVersion string      `json:"-"`
-	PaasHostVersion string      `json:"-"`
-	Version         string      `json:"-"`
-	HTTPManager     HTTPManager `json:"-"`
-	GivenEnvName    string      `json:"-"`
+	AccountsHost     string         `json:"-"`
+	AuthHost         string         `json:"-"`
+	PaasHost         string         `json:"-"`
+	AuthHostVersion  string         `json:"-"`
+	PaasHostVersion  string         `json:"-"`
+	Version          string         `json:"-"`
+	HTTPManager      HTTPManager    `json:"-"`
+	GivenEnvName     string         `json:"-"`
+	Profile          string         `json:"-"` // the name of the settings profile used for the current command
+	OutputFormat     string         `json:"-"` // the format list and show commands print their results in
+	Network          *NetworkConfig `json:"-"` // the proxy and TLS settings used by every connection
+	TokenStore       string         `json:"-"` // where the session is kept: empty for the settings file, vault, or helper
+	CredentialHelper string         `json:"-"` // the credential helper program used by the helper token store
+	HTTPTimeout      time.Duration  `json:"-"` // how long to wait for each request, zero for no limit
+	SessionError     error          `json:"-"` // why the session could not be loaded from its token store
+	JobTimeout       time.Duration  `json:"-"` // how long to wait for a job, zero for no limit
+	JobPollInterval  time.Duration  `json:"-"` // the time between the first polls for a job status
 
 	Email           string                     `json:"-"`
 	Password        string                     `json:"-"`
@@ -406,6 +425,47 @@ type SettingsV2 struct {
 	Format          string                     `json:"format"`
 }
 
+// NetworkConfig holds the proxy and TLS settings shared by every connection
+// the CLI makes. Empty values fall back to the system defaults.
+type NetworkConfig struct {
+	ProxyURL   string // proxy used for all requests, overriding HTTPS_PROXY and HTTP_PROXY
+	NoProxy    string // comma separated hosts and domains that bypass ProxyURL
+	CABundle   string // path to a PEM file of extra trusted certificate authorities
+	ClientCert string // path to a PEM client certificate presented to servers and proxies
+	ClientKey  string // path to the PEM private key for ClientCert
+	SkipVerify bool   // accept invalid server certificates
+}
+
+// SettingsV3 is the settings file format with named profiles. Each profile
+// has its own hosts, login, and environment associations so one machine can
+// be used with several accounts and Datica platforms.
+type SettingsV3 struct {
+	Format         string               `json:"format"`
+	DefaultProfile string               `json:"default_profile"`
+	Profiles       map[string]ProfileV3 `json:"profiles"`
+}
+
+// ProfileV3 holds the persisted settings of a single named profile. Empty
+// hosts fall back to the production Datica hosts.
+type ProfileV3 struct {
+	AccountsHost   string                     `json:"accounts_host,omitempty"`
+	AuthHost       string                     `json:"auth_host,omitempty"`
+	PaasHost       string                     `json:"paas_host,omitempty"`
+	Email          string                     `json:"email,omitempty"`
+	PrivateKeyPath string                     `json:"private_key_path"`
+	SessionToken   string                     `json:"token"`
+	UsersID        string                     `json:"user_id"`
+	Environments   map[string]AssociatedEnvV2 `json:"environments"`
+	Pods           *[]Pod                     `json:"pods"`
+	PodCheck       int64                      `json:"pod_check"`
+
+	// When TokenStore is set, SessionToken and UsersID are empty and the
+	// session is kept in the vault or credential helper under TokenRef
+	TokenStore       string `json:"token_store,omitempty"`
+	TokenRef         string `json:"token_ref,omitempty"`
+	CredentialHelper string `json:"credential_helper,omitempty"`
+}
+
 type Site struct {
 	ID              int                    `json:"id,omitempty"`
 	Name            string                 `json:"name"`
@@ -426,6 +486,12 @@ type TempURL struct {
 	URL string `json:"url"`
 }
 
+// UploadPart is a single successfully uploaded part of a multipart upload
+type UploadPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+}
+
 // User is an authenticated User
 type User struct {
 	Email        string `json:"email"`
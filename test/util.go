@@ -57,10 +57,14 @@ func AssertEquals(t *testing.T, expected, actual string) {
 }
 
 func GetSettings(baseURL string) *models.Settings {
+	httpManager, err := httpclient.NewTLSHTTPManager(false, httpclient.RetryPolicy{})
+	if err != nil {
+		panic(err)
+	}
 	return &models.Settings{
 		SessionToken:   "token",
 		PrivateKeyPath: "ssh_rsa",
-		HTTPManager:    httpclient.NewTLSHTTPManager(false),
+		HTTPManager:    httpManager,
 		PaasHost:       baseURL,
 		Environments: map[string]models.AssociatedEnvV2{
 			Alias: models.AssociatedEnvV2{